@@ -9,40 +9,177 @@ import (
 type LabTemplateSpec struct {
 	// Array of lab nodes and their configuration.
 	Nodes []LabInstanceNodes `json:"nodes"`
-	// Array of connections between lab nodes. (currently not supported)
-	Neighbors []string `json:"neighbors"`
+	// Deprecated: Connections between lab nodes in the format of the first releases, which were never supported and are ignored.
+	// They are kept, so existing lab templates can still be read, use links instead.
+	//+optional
+	Neighbors []string `json:"neighbors,omitempty"`
+	// Array of point-to-point connections between lab nodes.
+	// Every link is implemented as a dedicated layer 2 segment, which only connects the two interfaces of the link.
+	//+optional
+	Links []Link `json:"links,omitempty"`
+	// Controls, whether changes of the node types are rendered into the nodes of the lab template.
+	// With Manual, the nodes are rendered again the next time the lab template is changed.
+	//+kubebuilder:default=Automatic
+	//+optional
+	RolloutPolicy RolloutPolicy `json:"rolloutPolicy,omitempty"`
+	// Parameters, which every lab instance of the lab template can set, e.g. to give every group of students its own addresses.
+	// They are available in the node specs of the node types as .Params, e.g. {{ .Params.subnet }}.
+	//+listType=map
+	//+listMapKey=name
+	//+optional
+	Parameters []Parameter `json:"parameters,omitempty"`
+}
+
+// Parameter is a value of a lab template, which is set by every lab instance.
+type Parameter struct {
+	// The name of the parameter in the node specs, it has to be a valid identifier of a Go template.
+	//+kubebuilder:validation:Pattern=`^[A-Za-z_][A-Za-z0-9_]*$`
+	Name string `json:"name"`
+	// The type of the value. Values of type int and bool are available as numbers and booleans in the node specs.
+	//+kubebuilder:default=string
+	//+optional
+	Type ParameterType `json:"type,omitempty"`
+	// Explains the parameter to the users of the lab template.
+	//+optional
+	Description string `json:"description,omitempty"`
+	// The value of the parameter, if a lab instance doesn't set it.
+	//+optional
+	Default string `json:"default,omitempty"`
+	// Whether every lab instance has to set the parameter.
+	// The default of a required parameter is only used to render the node specs into the status of the lab template.
+	//+optional
+	Required bool `json:"required,omitempty"`
+}
+
+// ParameterType is the type of the value of a parameter.
+// +kubebuilder:validation:Enum=string;int;bool;cidr
+type ParameterType string
+
+const (
+	StringParameterType ParameterType = "string"
+	IntParameterType    ParameterType = "int"
+	BoolParameterType   ParameterType = "bool"
+	// A prefix in CIDR notation, e.g. 10.0.0.0/24.
+	CIDRParameterType ParameterType = "cidr"
+)
+
+// RolloutPolicy controls, how changes of referenced resources are rolled out.
+// +kubebuilder:validation:Enum=Automatic;Manual
+type RolloutPolicy string
+
+const (
+	// Changes are rolled out as soon as the referenced resource changes.
+	AutomaticRolloutPolicy RolloutPolicy = "Automatic"
+	// Changes are only rolled out, when the resource itself is changed or deleted.
+	ManualRolloutPolicy RolloutPolicy = "Manual"
+)
+
+// Link is a point-to-point connection between two interfaces of lab nodes.
+type Link struct {
+	// Name of the link, defaults to the names of its endpoints.
+	//+optional
+	Name string `json:"name,omitempty"`
+	// The two interfaces, which are connected by the link.
+	//+kubebuilder:validation:MinItems=2
+	//+kubebuilder:validation:MaxItems=2
+	Endpoints []LinkEndpoint `json:"endpoints"`
+	// Impairment of the link, e.g. to emulate a bad connection.
+	//+optional
+	Impairment *Impairment `json:"impairment,omitempty"`
+}
+
+// Impairment degrades the packets sent over a link. It is applied with tc netem on both interfaces of the link.
+type Impairment struct {
+	// Delay added to every packet, e.g. 100ms.
+	//+kubebuilder:validation:Pattern=`^[0-9]+(\.[0-9]+)?(us|ms|s)$`
+	Delay string `json:"delay,omitempty"`
+	// Random variation of the delay, e.g. 10ms. Requires a delay.
+	//+kubebuilder:validation:Pattern=`^[0-9]+(\.[0-9]+)?(us|ms|s)$`
+	Jitter string `json:"jitter,omitempty"`
+	// Percentage of dropped packets, e.g. 0.5.
+	//+kubebuilder:validation:Pattern=`^[0-9]+(\.[0-9]+)?$`
+	Loss string `json:"loss,omitempty"`
+	// Maximum rate of the link, e.g. 10mbit.
+	//+kubebuilder:validation:Pattern=`^[0-9]+(\.[0-9]+)?(bit|kbit|mbit|gbit|tbit|bps|kbps|mbps|gbps|tbps)$`
+	Rate string `json:"rate,omitempty"`
+	// Percentage of corrupted packets, e.g. 0.1.
+	//+kubebuilder:validation:Pattern=`^[0-9]+(\.[0-9]+)?$`
+	Corruption string `json:"corruption,omitempty"`
+}
+
+// LinkEndpoint references an interface of a lab node.
+type LinkEndpoint struct {
+	// The name of the lab node.
+	Node string `json:"node"`
+	// The name of the interface inside the lab node, e.g. eth1.
+	// Defaults to the next free interface name of the lab node in links of a lab template.
+	//+optional
+	Interface string `json:"interface,omitempty"`
 }
 
 // Configuration for a lab node.
 type LabInstanceNodes struct {
 	// The name of the lab node.
+	// It is converted into a lowercase DNS label of at most 24 characters, as it is part of the names of the resources of the lab node.
 	Name string `json:"name"`
 	// The type of the lab node.
 	NodeTypeRef NodeTypeRef `json:"nodeTypeRef"`
-	// Array of interface configurations for the lab node. (currently not supported)
+	// Array of interface configurations for the lab node.
+	// The interfaces are attached to the lab node in the given order, followed by interfaces that are only referenced by a link.
 	Interfaces []NodeInterface `json:"interfaces,omitempty"`
 	// The configuration for the lab node.
 	Config string `json:"config,omitempty"`
 	// Array of ports which should be publicly exposed for the lab node.
-	Ports            []Port `json:"ports,omitempty"`
+	Ports []Port `json:"ports,omitempty"`
+	// Secrets in the namespace of the lab instance, which are available to the lab node, e.g. passwords, SSH keys or license files.
+	// Their names are available in the node spec as .Secrets, e.g. {{ .Secrets.admin }}, their values are never rendered into the node spec.
+	//+listType=map
+	//+listMapKey=name
+	//+optional
+	Secrets []SecretReference `json:"secrets,omitempty"`
+	// Deprecated: The rendered node spec is stored in the status of the lab template, this field is ignored.
 	RenderedNodeSpec string `json:"renderedNodeSpec,omitempty"`
 }
 
+// SecretReference makes a Secret available to a lab node.
+type SecretReference struct {
+	// The name of the reference in the node spec, it has to be a valid identifier of a Go template.
+	//+kubebuilder:validation:Pattern=`^[A-Za-z_][A-Za-z0-9_]*$`
+	Name string `json:"name"`
+	// The name of the Secret in the namespace of the lab instance.
+	// Without it, every lab instance has to set the Secret, unless it is generated.
+	//+optional
+	SecretName string `json:"secretName,omitempty"`
+	// Generates a Secret of type kubernetes.io/basic-auth with a random password for every lab instance, which doesn't set the Secret.
+	// Lab nodes with references of the same name share the generated Secret.
+	//+optional
+	Generate bool `json:"generate,omitempty"`
+	// The directory in the first container of a pod, which the Secret is mounted at, e.g. /run/secrets/admin.
+	// A VM gets the Secret as a disk, whose serial is the name of the reference in lowercase, which the guest has to mount itself.
+	//+optional
+	MountPath string `json:"mountPath,omitempty"`
+}
+
 // Port of a lab node which should be publicly exposed.
 type Port struct {
 	// Arbitrary name for the port.
 	Name string `json:"name"`
 	// Choose either TCP or UDP.
-	Protocol corev1.Protocol `json:"protocol"`
+	//+kubebuilder:default=TCP
+	//+optional
+	Protocol corev1.Protocol `json:"protocol,omitempty"`
 	// The port number to expose.
 	Port int32 `json:"port"`
 }
 
-// Interface configuration for the lab node (currently not supported)
+// Interface configuration for the lab node
 type NodeInterface struct {
-	// IPv4 address of the interface.
+	// The name of the interface inside the lab node, e.g. eth1.
+	// Defaults to the next free interface name of the lab node.
+	Name string `json:"name,omitempty"`
+	// IPv4 address of the interface in CIDR notation, e.g. 10.0.0.1/24.
 	IPv4 string `json:"ipv4,omitempty"`
-	// IPv6 address of the interface.
+	// IPv6 address of the interface in CIDR notation, e.g. 2001:db8::1/64.
 	IPv6 string `json:"ipv6,omitempty"`
 }
 
@@ -53,15 +190,68 @@ type NodeTypeRef struct {
 	// Image to use for the NodeType. Is available as variable in the NodeType and functionality depends on its usage.
 	Image string `json:"image,omitempty"`
 	// Version of the NodeType. Is available as variable in the NodeType and functionality depends on its usage.
+	//+kubebuilder:default=latest
+	//+optional
 	Version string `json:"version,omitempty"`
 }
 
+// LabTemplateStatus contains the node specs, which are rendered from the node types of the lab template.
 type LabTemplateStatus struct {
+	// The generation of the lab template, which was last rendered by the operator.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Conditions of the lab template, the Rendered condition is true, if the node specs of all nodes are rendered.
+	//+listType=map
+	//+listMapKey=type
+	//+optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// The rendered node spec of every lab node.
+	Nodes []RenderedNode `json:"nodes,omitempty"`
+	// Number of lab nodes, which run as pods.
+	NumPods int32 `json:"numPods,omitempty"`
+	// Number of lab nodes, which run as VMs.
+	NumVMs int32 `json:"numVMs,omitempty"`
+	// Sum of the CPU and memory requests of the rendered lab nodes.
+	Requests corev1.ResourceList `json:"requests,omitempty"`
+	// Lab instances, which use the lab template, as namespace/name.
+	UsedBy []string `json:"usedBy,omitempty"`
+}
+
+// Condition types of a lab template and its nodes.
+const (
+	// The node spec is rendered from the node type, or for the lab template, the node specs of all nodes are rendered.
+	RenderedCondition = "Rendered"
+)
+
+// RenderedNode is the node spec of a lab node, which is rendered from its node type.
+type RenderedNode struct {
+	// The name of the lab node.
+	Name string `json:"name"`
+	// Whether the lab node runs as pod or vm, empty if its node type doesn't exist.
+	Kind string `json:"kind,omitempty"`
+	// The node spec of the node type, which the lab node was rendered from.
+	// Every lab instance renders it again with the lab instance in its template context.
+	NodeSpec string `json:"nodeSpec,omitempty"`
+	// How the config of the lab node is delivered, as declared by the node type, which the lab node was rendered from.
+	ConfigDelivery *ConfigDelivery `json:"configDelivery,omitempty"`
+	// The node spec rendered from the node type without a lab instance, empty if rendering failed.
+	RenderedNodeSpec string `json:"renderedNodeSpec,omitempty"`
+	// The CPU and memory requests of the rendered node spec.
+	Requests corev1.ResourceList `json:"requests,omitempty"`
+	// Conditions of the lab node, the Rendered condition explains why rendering failed.
+	//+listType=map
+	//+listMapKey=type
+	//+optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 //+kubebuilder:object:root=true
+//+kubebuilder:storageversion
 //+kubebuilder:resource:scope=Cluster
 //+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="RENDERED",type=string,JSONPath=`.status.conditions[?(@.type=="Rendered")].status`
+//+kubebuilder:printcolumn:name="PODS",type=integer,JSONPath=`.status.numPods`
+//+kubebuilder:printcolumn:name="VMS",type=integer,JSONPath=`.status.numVMs`
+//+kubebuilder:printcolumn:name="USED_BY",type=string,JSONPath=`.status.usedBy`,priority=1
 
 // Defines the lab topology, its nodes and their configuration.
 type LabTemplate struct {
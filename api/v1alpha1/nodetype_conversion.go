@@ -0,0 +1,4 @@
+package v1alpha1
+
+// Hub marks this type as the conversion hub, the v1beta1 version is converted from and to it.
+func (*NodeType) Hub() {}
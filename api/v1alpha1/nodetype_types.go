@@ -12,16 +12,69 @@ type NodeTypeSpec struct {
 	// NodeSpec is the PodSpec or VirtualMachineSpec configuration for the node with the possibility to use go templating syntax to include LabTemplate variables (see [User Guide](https://lab-topology-builder.github.io/LTB-K8s-Backend/user-guide/#example-node-type))
 	// See [PodSpec](https://kubernetes.io/docs/reference/generated/kubernetes-api/v1.26/#podspec-v1-core) and [VirtualMachineSpec](https://kubevirt.io/api-reference/master/definitions.html#_v1_virtualmachinespec)
 	NodeSpec string `json:"nodeSpec,omitempty"`
+	// Delivers the config of the lab nodes as a file, instead of including it in the node spec with {{ .Config }}.
+	// Without it, the config only reaches the lab node, if the node spec references it.
+	//+optional
+	ConfigDelivery *ConfigDelivery `json:"configDelivery,omitempty"`
 }
 
-// NodeTypeStatus defines the observed state of NodeType
+// ConfigDelivery declares, how the config of a lab node is delivered into the lab node.
+type ConfigDelivery struct {
+	// ConfigMap mounts a ConfigMap with the config into the first container of a pod.
+	// CloudInitNoCloud and CloudInitConfigDrive pass the config as cloud-init user data from a Secret to a VM.
+	Type ConfigDeliveryType `json:"type"`
+	// The directory in the first container of a pod, which the ConfigMap is mounted at, e.g. /etc/frr.
+	// It only contains the file with the config. Required for ConfigMap.
+	//+optional
+	MountPath string `json:"mountPath,omitempty"`
+	// The name of the file with the config in the mount path.
+	//+kubebuilder:default=config
+	//+optional
+	FileName string `json:"fileName,omitempty"`
+}
+
+// ConfigDeliveryType is the way the config of a lab node is delivered.
+// +kubebuilder:validation:Enum=ConfigMap;CloudInitNoCloud;CloudInitConfigDrive
+type ConfigDeliveryType string
+
+const (
+	// The config is mounted as a file from a ConfigMap into a pod.
+	ConfigMapConfigDelivery ConfigDeliveryType = "ConfigMap"
+	// The config is the user data of a cloud-init NoCloud disk of a VM.
+	CloudInitNoCloudConfigDelivery ConfigDeliveryType = "CloudInitNoCloud"
+	// The config is the user data of a cloud-init ConfigDrive disk of a VM.
+	CloudInitConfigDriveConfigDelivery ConfigDeliveryType = "CloudInitConfigDrive"
+)
+
+// NodeTypeStatus shows whether the node spec of the NodeType is valid and which lab templates use it.
 type NodeTypeStatus struct {
-	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
+	// The generation of the node type, which was last validated by the operator.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Conditions of the node type, the Valid condition explains why the node spec doesn't render or decode.
+	//+listType=map
+	//+listMapKey=type
+	//+optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// Names of the lab templates, which reference the node type.
+	UsedBy []string `json:"usedBy,omitempty"`
+	// SHA-256 hash of the current spec of the node type.
+	SpecHash string `json:"specHash,omitempty"`
 }
 
+// Condition types of a node type.
+const (
+	// The node spec renders with test data and the parameters of the lab templates using it and decodes into a spec of the kind of the node type.
+	// It is Unknown for a node spec with parameters, until a lab template declares their types.
+	ValidCondition = "Valid"
+)
+
 //+kubebuilder:object:root=true
+//+kubebuilder:storageversion
 //+kubebuilder:resource:scope=Cluster
 //+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="KIND",type=string,JSONPath=`.spec.kind`
+//+kubebuilder:printcolumn:name="VALID",type=string,JSONPath=`.status.conditions[?(@.type=="Valid")].status`
+//+kubebuilder:printcolumn:name="USED_BY",type=string,JSONPath=`.status.usedBy`
 
 // NodeType defines a type of node that can be used in a lab template
 type NodeType struct {
@@ -0,0 +1,78 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PacketCaptureSpec selects the interface of a lab node, on which packets are captured.
+type PacketCaptureSpec struct {
+	// Reference to the name of the LabInstance, which contains the lab node.
+	LabInstanceRef string `json:"labInstanceRef"`
+	// The name of the lab node.
+	Node string `json:"node"`
+	// The name of the interface inside the lab node, e.g. eth1.
+	Interface string `json:"interface"`
+	// Filter expression in pcap-filter syntax, e.g. "icmp or arp".
+	//+optional
+	Filter string `json:"filter,omitempty"`
+	// Stops the capture after the given number of packets, otherwise the capture runs until the PacketCapture is deleted.
+	//+kubebuilder:validation:Minimum=0
+	//+optional
+	PacketCount int32 `json:"packetCount,omitempty"`
+}
+
+// PacketCapturePhase is the phase of a packet capture.
+type PacketCapturePhase string
+
+const (
+	PacketCapturePending   PacketCapturePhase = "Pending"
+	PacketCaptureRunning   PacketCapturePhase = "Running"
+	PacketCaptureCompleted PacketCapturePhase = "Completed"
+	PacketCaptureFailed    PacketCapturePhase = "Failed"
+)
+
+// PacketCaptureStatus shows where the capture runs and how to access it.
+type PacketCaptureStatus struct {
+	// The phase of the capture.
+	Phase PacketCapturePhase `json:"phase,omitempty"`
+	// Explains the phase of the capture, e.g. why it failed.
+	Message string `json:"message,omitempty"`
+	// Name of the pod of the lab node.
+	PodName string `json:"podName,omitempty"`
+	// Name of the ephemeral container, which runs tcpdump inside the pod.
+	ContainerName string `json:"containerName,omitempty"`
+	// Path of the pcap file inside the ephemeral container and the link-control container.
+	File string `json:"file,omitempty"`
+	// Command to stream the capture into Wireshark, while it is running.
+	StreamCommand string `json:"streamCommand,omitempty"`
+	// Command to download the pcap file from the link-control container, also after the capture completed.
+	DownloadCommand string `json:"downloadCommand,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="LABINSTANCE",type=string,JSONPath=`.spec.labInstanceRef`
+//+kubebuilder:printcolumn:name="NODE",type=string,JSONPath=`.spec.node`
+//+kubebuilder:printcolumn:name="INTERFACE",type=string,JSONPath=`.spec.interface`
+//+kubebuilder:printcolumn:name="PHASE",type=string,JSONPath=`.status.phase`
+
+// A packet capture records the packets of an interface of a lab node, they can be downloaded as pcap file or streamed into Wireshark.
+type PacketCapture struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   PacketCaptureSpec   `json:"spec,omitempty"`
+	Status PacketCaptureStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+type PacketCaptureList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []PacketCapture `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&PacketCapture{}, &PacketCaptureList{})
+}
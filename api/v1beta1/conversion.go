@@ -0,0 +1,63 @@
+package v1beta1
+
+import (
+	"github.com/Lab-Topology-Builder/LTB-K8s-Backend/api/v1alpha1"
+)
+
+// convertSlice converts every element of a slice, a nil slice stays nil, so an object converted back and forth is unchanged.
+func convertSlice[S any, D any](src []S, convert func(S) D) []D {
+	if src == nil {
+		return nil
+	}
+	dst := make([]D, 0, len(src))
+	for _, item := range src {
+		dst = append(dst, convert(item))
+	}
+	return dst
+}
+
+func linkEndpointToV1alpha1(endpoint LinkEndpoint) v1alpha1.LinkEndpoint {
+	return v1alpha1.LinkEndpoint(endpoint)
+}
+
+func linkEndpointFromV1alpha1(endpoint v1alpha1.LinkEndpoint) LinkEndpoint {
+	return LinkEndpoint(endpoint)
+}
+
+func impairmentToV1alpha1(impairment *Impairment) *v1alpha1.Impairment {
+	if impairment == nil {
+		return nil
+	}
+	dst := v1alpha1.Impairment(*impairment)
+	return &dst
+}
+
+func impairmentFromV1alpha1(impairment *v1alpha1.Impairment) *Impairment {
+	if impairment == nil {
+		return nil
+	}
+	dst := Impairment(*impairment)
+	return &dst
+}
+
+func configDeliveryToV1alpha1(configDelivery *ConfigDelivery) *v1alpha1.ConfigDelivery {
+	if configDelivery == nil {
+		return nil
+	}
+	return &v1alpha1.ConfigDelivery{
+		Type:      v1alpha1.ConfigDeliveryType(configDelivery.Type),
+		MountPath: configDelivery.MountPath,
+		FileName:  configDelivery.FileName,
+	}
+}
+
+func configDeliveryFromV1alpha1(configDelivery *v1alpha1.ConfigDelivery) *ConfigDelivery {
+	if configDelivery == nil {
+		return nil
+	}
+	return &ConfigDelivery{
+		Type:      ConfigDeliveryType(configDelivery.Type),
+		MountPath: configDelivery.MountPath,
+		FileName:  configDelivery.FileName,
+	}
+}
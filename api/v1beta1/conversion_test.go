@@ -0,0 +1,274 @@
+package v1beta1_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/conversion"
+	"sigs.k8s.io/yaml"
+
+	"github.com/Lab-Topology-Builder/LTB-K8s-Backend/api/v1alpha1"
+	"github.com/Lab-Topology-Builder/LTB-K8s-Backend/api/v1beta1"
+)
+
+var _ = Describe("Conversion", func() {
+	var (
+		nodeType    *v1alpha1.NodeType
+		labTemplate *v1alpha1.LabTemplate
+		labInstance *v1alpha1.LabInstance
+	)
+
+	BeforeEach(func() {
+		nodeType = &v1alpha1.NodeType{
+			ObjectMeta: metav1.ObjectMeta{Name: "genericpod", Labels: map[string]string{"app": "ltb"}},
+			Spec: v1alpha1.NodeTypeSpec{
+				Kind:           "pod",
+				NodeSpec:       "containers: []",
+				ConfigDelivery: &v1alpha1.ConfigDelivery{Type: v1alpha1.ConfigMapConfigDelivery, MountPath: "/etc/frr", FileName: "frr.conf"},
+			},
+			Status: v1alpha1.NodeTypeStatus{
+				Conditions: []metav1.Condition{{Type: v1alpha1.ValidCondition, Status: metav1.ConditionTrue, Reason: "Valid"}},
+				UsedBy:     []string{"labtemplate-sample"},
+				SpecHash:   "0123456789abcdef",
+			},
+		}
+		labTemplate = &v1alpha1.LabTemplate{
+			ObjectMeta: metav1.ObjectMeta{Name: "labtemplate-sample", Generation: 2},
+			Spec: v1alpha1.LabTemplateSpec{
+				Nodes: []v1alpha1.LabInstanceNodes{
+					{
+						Name:        "node-1",
+						NodeTypeRef: v1alpha1.NodeTypeRef{Type: "genericpod", Image: "ubuntu", Version: "22.04"},
+						Interfaces:  []v1alpha1.NodeInterface{{Name: "eth1", IPv4: "10.0.0.1/24"}},
+						Ports:       []v1alpha1.Port{{Name: "ssh", Protocol: corev1.ProtocolTCP, Port: 22}},
+						Config:      "[]",
+						Secrets:     []v1alpha1.SecretReference{{Name: "admin", Generate: true, MountPath: "/run/secrets/admin"}},
+					},
+					{
+						Name:        "node-2",
+						NodeTypeRef: v1alpha1.NodeTypeRef{Type: "genericpod", Version: "latest"},
+					},
+				},
+				Links: []v1alpha1.Link{
+					{
+						Name:       "node-1-eth1-node-2-eth1",
+						Endpoints:  []v1alpha1.LinkEndpoint{{Node: "node-1", Interface: "eth1"}, {Node: "node-2", Interface: "eth1"}},
+						Impairment: &v1alpha1.Impairment{Delay: "10ms", Loss: "1"},
+					},
+				},
+				RolloutPolicy: v1alpha1.ManualRolloutPolicy,
+				Parameters:    []v1alpha1.Parameter{{Name: "subnet", Type: v1alpha1.CIDRParameterType, Default: "10.0.0.0/24", Required: true}},
+			},
+			Status: v1alpha1.LabTemplateStatus{
+				ObservedGeneration: 2,
+				Conditions:         []metav1.Condition{{Type: v1alpha1.RenderedCondition, Status: metav1.ConditionTrue, Reason: "Rendered"}},
+				Nodes: []v1alpha1.RenderedNode{{
+					Name:             "node-1",
+					Kind:             "pod",
+					NodeSpec:         "containers: []",
+					ConfigDelivery:   &v1alpha1.ConfigDelivery{Type: v1alpha1.ConfigMapConfigDelivery, MountPath: "/etc/frr", FileName: "config"},
+					RenderedNodeSpec: "containers: []",
+					Requests:         corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")},
+				}},
+				NumPods:  1,
+				Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")},
+				UsedBy:   []string{"default/labinstance-sample"},
+			},
+		}
+		labInstance = &v1alpha1.LabInstance{
+			ObjectMeta: metav1.ObjectMeta{Name: "labinstance-sample", Namespace: "default"},
+			Spec: v1alpha1.LabInstanceSpec{
+				LabTemplateReference: "labtemplate-sample",
+				DNSAddress:           "example.com",
+				NetworkBackend:       v1alpha1.VXLANNetworkBackend,
+				LinkImpairments:      []v1alpha1.LinkImpairment{{Endpoint: v1alpha1.LinkEndpoint{Node: "node-1", Interface: "eth1"}, Impairment: v1alpha1.Impairment{Rate: "10mbit"}}},
+				DownLinks:            []v1alpha1.LinkEndpoint{{Node: "node-2", Interface: "eth1"}},
+				RolloutPolicy:        v1alpha1.AutomaticRolloutPolicy,
+				Parameters:           []v1alpha1.ParameterValue{{Name: "subnet", Value: "10.1.0.0/24"}},
+				NodeOverrides: []v1alpha1.NodeOverride{{
+					Node:      "node-1",
+					Image:     "debian",
+					Resources: &corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")}},
+				}},
+				Secrets: []v1alpha1.SecretBinding{{Name: "admin", SecretName: "admin-credentials"}},
+			},
+			Status: v1alpha1.LabInstanceStatus{
+				Status:         "Running",
+				NumPodsRunning: "2/2",
+				NumVMsRunning:  "0/0",
+				Nodes: []v1alpha1.NodeStatus{{
+					Name:         "node-1",
+					Kind:         "pod",
+					Ready:        true,
+					IPs:          []string{"10.244.0.10"},
+					RemoteAccess: []v1alpha1.RemoteAccessEndpoint{{Name: "ssh", Host: "192.0.2.1", Port: 22, Protocol: corev1.ProtocolTCP}},
+				}},
+				Network: v1alpha1.LabInstanceNetworkStatus{Name: "labinstance-sample", Subnet: "10.10.0.0/24", Segments: map[string]int32{"node-1-eth1-node-2-eth1": 100}},
+				Links: []v1alpha1.LinkStatus{{
+					Name:      "node-1-eth1-node-2-eth1",
+					Endpoints: labTemplate.Spec.Links[0].Endpoints,
+					State:     v1alpha1.LinkStateDown,
+					Interfaces: []v1alpha1.LinkInterfaceStatus{
+						{LinkEndpoint: labTemplate.Spec.Links[0].Endpoints[0], State: v1alpha1.LinkStateDown, Impaired: true},
+						{LinkEndpoint: labTemplate.Spec.Links[0].Endpoints[1], State: v1alpha1.LinkStateDown},
+					},
+				}},
+			},
+		}
+	})
+
+	Describe("NodeType", func() {
+		It("should convert the kind", func() {
+			converted := &v1beta1.NodeType{}
+			Expect(converted.ConvertFrom(nodeType)).To(Succeed())
+			Expect(converted.Spec.Kind).To(Equal(v1beta1.PodNodeKind))
+			Expect(converted.Spec.ConfigDelivery.Type).To(Equal(v1beta1.ConfigMapConfigDelivery))
+			Expect(converted.Labels).To(Equal(nodeType.Labels))
+		})
+		It("should round-trip a v1alpha1 node type", func() {
+			converted := &v1beta1.NodeType{}
+			Expect(converted.ConvertFrom(nodeType)).To(Succeed())
+			roundTripped := &v1alpha1.NodeType{}
+			Expect(converted.ConvertTo(roundTripped)).To(Succeed())
+			Expect(roundTripped).To(Equal(nodeType))
+		})
+	})
+
+	Describe("LabTemplate", func() {
+		It("should convert the links", func() {
+			converted := &v1beta1.LabTemplate{}
+			Expect(converted.ConvertFrom(labTemplate)).To(Succeed())
+			Expect(converted.Spec.Nodes).To(HaveLen(2))
+			Expect(converted.Spec.Links).To(HaveLen(1))
+			Expect(converted.Spec.Links[0].Endpoints[1]).To(Equal(v1beta1.LinkEndpoint{Node: "node-2", Interface: "eth1"}))
+			Expect(converted.Spec.Links[0].Impairment.Delay).To(Equal("10ms"))
+			Expect(converted.Status.Nodes[0].RenderedNodeSpec).To(Equal("containers: []"))
+			Expect(converted.Status.Nodes[0].Kind).To(Equal(v1beta1.PodNodeKind))
+			Expect(converted.Annotations).ToNot(HaveKey(v1beta1.RenderedNodeSpecsAnnotation))
+		})
+		It("should round-trip a v1alpha1 lab template", func() {
+			converted := &v1beta1.LabTemplate{}
+			Expect(converted.ConvertFrom(labTemplate)).To(Succeed())
+			roundTripped := &v1alpha1.LabTemplate{}
+			Expect(converted.ConvertTo(roundTripped)).To(Succeed())
+			Expect(roundTripped).To(Equal(labTemplate))
+		})
+		It("should keep the deprecated rendered node specs in an annotation", func() {
+			labTemplate.Annotations = map[string]string{"owner": "ltb"}
+			labTemplate.Spec.Nodes[1].RenderedNodeSpec = "containers: []"
+			converted := &v1beta1.LabTemplate{}
+			Expect(converted.ConvertFrom(labTemplate)).To(Succeed())
+			Expect(converted.Annotations).To(HaveKeyWithValue(v1beta1.RenderedNodeSpecsAnnotation, `{"node-2":"containers: []"}`))
+			Expect(labTemplate.Annotations).ToNot(HaveKey(v1beta1.RenderedNodeSpecsAnnotation))
+			roundTripped := &v1alpha1.LabTemplate{}
+			Expect(converted.ConvertTo(roundTripped)).To(Succeed())
+			Expect(roundTripped).To(Equal(labTemplate))
+		})
+		It("should fail with an invalid annotation", func() {
+			converted := &v1beta1.LabTemplate{}
+			converted.Annotations = map[string]string{v1beta1.RenderedNodeSpecsAnnotation: "node-2"}
+			Expect(converted.ConvertTo(&v1alpha1.LabTemplate{})).ToNot(Succeed())
+			converted.Annotations = map[string]string{v1beta1.NeighborsAnnotation: "node-2"}
+			Expect(converted.ConvertTo(&v1alpha1.LabTemplate{})).ToNot(Succeed())
+		})
+		It("should decode and round-trip a lab template with the deprecated neighbors of the first releases", func() {
+			oldLabTemplate := &v1alpha1.LabTemplate{}
+			Expect(yaml.UnmarshalStrict([]byte(oldLabTemplateSample), oldLabTemplate)).To(Succeed())
+			Expect(oldLabTemplate.Spec.Nodes).To(HaveLen(3))
+			Expect(oldLabTemplate.Spec.Neighbors).To(Equal([]string{"sample-node-1:1,sample-node-2:1", "sample-node-2:2-sample-node-3:1"}))
+			Expect(oldLabTemplate.Spec.Links).To(BeEmpty())
+			converted := &v1beta1.LabTemplate{}
+			Expect(converted.ConvertFrom(oldLabTemplate)).To(Succeed())
+			Expect(converted.Spec.Links).To(BeEmpty())
+			Expect(converted.Annotations).To(HaveKeyWithValue(v1beta1.NeighborsAnnotation, `["sample-node-1:1,sample-node-2:1","sample-node-2:2-sample-node-3:1"]`))
+			roundTripped := &v1alpha1.LabTemplate{}
+			Expect(converted.ConvertTo(roundTripped)).To(Succeed())
+			Expect(roundTripped.ObjectMeta).To(Equal(oldLabTemplate.ObjectMeta))
+			Expect(roundTripped.Spec).To(Equal(oldLabTemplate.Spec))
+		})
+	})
+
+	Describe("LabInstance", func() {
+		It("should round-trip a v1alpha1 lab instance", func() {
+			converted := &v1beta1.LabInstance{}
+			Expect(converted.ConvertFrom(labInstance)).To(Succeed())
+			Expect(converted.Status.Nodes[0].Kind).To(Equal(v1beta1.PodNodeKind))
+			roundTripped := &v1alpha1.LabInstance{}
+			Expect(converted.ConvertTo(roundTripped)).To(Succeed())
+			Expect(roundTripped).To(Equal(labInstance))
+		})
+		It("should round-trip a v1beta1 lab instance", func() {
+			converted := &v1beta1.LabInstance{}
+			Expect(converted.ConvertFrom(labInstance)).To(Succeed())
+			hub := &v1alpha1.LabInstance{}
+			Expect(converted.ConvertTo(hub)).To(Succeed())
+			roundTripped := &v1beta1.LabInstance{}
+			Expect(roundTripped.ConvertFrom(hub)).To(Succeed())
+			Expect(roundTripped).To(Equal(converted))
+		})
+	})
+
+	Describe("Scheme", func() {
+		It("should make every kind of both versions convertible", func() {
+			scheme := runtime.NewScheme()
+			Expect(v1alpha1.AddToScheme(scheme)).To(Succeed())
+			Expect(v1beta1.AddToScheme(scheme)).To(Succeed())
+			for _, object := range []runtime.Object{&v1alpha1.NodeType{}, &v1alpha1.LabTemplate{}, &v1alpha1.LabInstance{}} {
+				convertible, err := conversion.IsConvertible(scheme, object)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(convertible).To(BeTrue())
+			}
+		})
+	})
+})
+
+// The sample lab template of the first releases, whose neighbors are strings.
+const oldLabTemplateSample = `apiVersion: ltb-backend.ltb/v1alpha1
+kind: LabTemplate
+metadata:
+  labels:
+    app.kubernetes.io/name: labtemplate
+    app.kubernetes.io/instance: labtemplate-sample
+    app.kubernetes.io/part-of: operator
+    app.kubernetes.io/managed-by: kustomize
+    app.kubernetes.io/created-by: operator
+  name: labtemplate-sample
+spec:
+  nodes:
+  - name: "sample-node-1"
+    nodeTypeRef:
+      type: "nodetypeubuntuvm"
+      image: "ubuntu"
+      version: "22.04"
+    config: "I2Nsb3VkLWNvbmZpZwpwYXNzd29yZDogdWJ1bnR1CmNocGFzc3dkOiB7IGV4cGlyZTogRmFsc2UgfQpzc2hfcHdhdXRoOiBUcnVlCnBhY2thZ2VzOgogLSBxZW11LWd1ZXN0LWFnZW50CiAtIGNtYXRyaXgKcnVuY21kOgogLSBbIHN5c3RlbWN0bCwgc3RhcnQsIHFlbXUtZ3Vlc3QtYWdlbnQgXQo="
+    ports:
+    - name: "ssh"
+      port: 22
+      protocol: "TCP"
+  - name: "sample-node-2"
+    nodeTypeRef:
+      type: "genericpod"
+      image: "ghcr.io/insrapperswil/network-ninja"
+      version: "latest"
+    ports:
+    - name: "ssh"
+      port: 22
+      protocol: "TCP"
+    config: '["/bin/bash", "-c", "apt update && apt install -y openssh-server && service ssh start && sleep 365d"]'
+  - name: "sample-node-3"
+    nodeTypeRef:
+      type: "genericpod"
+      image: "ubuntu"
+      version: "22.04"
+    ports:
+    - name: "ssh"
+      port: 22
+      protocol: "TCP"
+    config: '["/bin/bash", "-c", "apt update && apt install -y openssh-server && service ssh start && sleep 365d"]'
+  neighbors:
+  - "sample-node-1:1,sample-node-2:1"
+  - "sample-node-2:2-sample-node-3:1"
+`
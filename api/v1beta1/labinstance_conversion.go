@@ -0,0 +1,133 @@
+package v1beta1
+
+import (
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+
+	"github.com/Lab-Topology-Builder/LTB-K8s-Backend/api/v1alpha1"
+)
+
+// ConvertTo converts this LabInstance to the hub version v1alpha1.
+func (src *LabInstance) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v1alpha1.LabInstance)
+	src.ObjectMeta.DeepCopyInto(&dst.ObjectMeta)
+	dst.Spec = v1alpha1.LabInstanceSpec{
+		LabTemplateReference: src.Spec.LabTemplateReference,
+		DNSAddress:           src.Spec.DNSAddress,
+		NetworkBackend:       v1alpha1.NetworkBackend(src.Spec.NetworkBackend),
+		LinkImpairments: convertSlice(src.Spec.LinkImpairments, func(linkImpairment LinkImpairment) v1alpha1.LinkImpairment {
+			return v1alpha1.LinkImpairment{
+				Endpoint:   linkEndpointToV1alpha1(linkImpairment.Endpoint),
+				Impairment: v1alpha1.Impairment(linkImpairment.Impairment),
+			}
+		}),
+		DownLinks:     convertSlice(src.Spec.DownLinks, linkEndpointToV1alpha1),
+		RolloutPolicy: v1alpha1.RolloutPolicy(src.Spec.RolloutPolicy),
+		Parameters: convertSlice(src.Spec.Parameters, func(parameter ParameterValue) v1alpha1.ParameterValue {
+			return v1alpha1.ParameterValue(parameter)
+		}),
+		NodeOverrides: convertSlice(src.Spec.NodeOverrides, func(nodeOverride NodeOverride) v1alpha1.NodeOverride {
+			return v1alpha1.NodeOverride(nodeOverride)
+		}),
+		Secrets: convertSlice(src.Spec.Secrets, func(secret SecretBinding) v1alpha1.SecretBinding {
+			return v1alpha1.SecretBinding(secret)
+		}),
+	}
+	dst.Status = v1alpha1.LabInstanceStatus{
+		Status:             src.Status.Status,
+		NumPodsRunning:     src.Status.NumPodsRunning,
+		NumVMsRunning:      src.Status.NumVMsRunning,
+		ObservedGeneration: src.Status.ObservedGeneration,
+		Conditions:         src.Status.Conditions,
+		Nodes: convertSlice(src.Status.Nodes, func(node NodeStatus) v1alpha1.NodeStatus {
+			return v1alpha1.NodeStatus{
+				Name:           node.Name,
+				Kind:           string(node.Kind),
+				Phase:          node.Phase,
+				Ready:          node.Ready,
+				IPs:            node.IPs,
+				HostNode:       node.HostNode,
+				LastError:      node.LastError,
+				WebTerminalURL: node.WebTerminalURL,
+				RemoteAccess: convertSlice(node.RemoteAccess, func(endpoint RemoteAccessEndpoint) v1alpha1.RemoteAccessEndpoint {
+					return v1alpha1.RemoteAccessEndpoint(endpoint)
+				}),
+			}
+		}),
+		Network: v1alpha1.LabInstanceNetworkStatus(src.Status.Network),
+		Links: convertSlice(src.Status.Links, func(link LinkStatus) v1alpha1.LinkStatus {
+			return v1alpha1.LinkStatus{
+				Name:      link.Name,
+				Endpoints: convertSlice(link.Endpoints, linkEndpointToV1alpha1),
+				State:     v1alpha1.LinkState(link.State),
+				Message:   link.Message,
+				Interfaces: convertSlice(link.Interfaces, func(linkInterface LinkInterfaceStatus) v1alpha1.LinkInterfaceStatus {
+					return v1alpha1.LinkInterfaceStatus{LinkEndpoint: linkEndpointToV1alpha1(linkInterface.LinkEndpoint), State: v1alpha1.LinkState(linkInterface.State), Impaired: linkInterface.Impaired}
+				}),
+			}
+		}),
+	}
+	return nil
+}
+
+// ConvertFrom converts the hub version v1alpha1 to this LabInstance.
+func (dst *LabInstance) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*v1alpha1.LabInstance)
+	src.ObjectMeta.DeepCopyInto(&dst.ObjectMeta)
+	dst.Spec = LabInstanceSpec{
+		LabTemplateReference: src.Spec.LabTemplateReference,
+		DNSAddress:           src.Spec.DNSAddress,
+		NetworkBackend:       NetworkBackend(src.Spec.NetworkBackend),
+		LinkImpairments: convertSlice(src.Spec.LinkImpairments, func(linkImpairment v1alpha1.LinkImpairment) LinkImpairment {
+			return LinkImpairment{
+				Endpoint:   linkEndpointFromV1alpha1(linkImpairment.Endpoint),
+				Impairment: Impairment(linkImpairment.Impairment),
+			}
+		}),
+		DownLinks:     convertSlice(src.Spec.DownLinks, linkEndpointFromV1alpha1),
+		RolloutPolicy: RolloutPolicy(src.Spec.RolloutPolicy),
+		Parameters: convertSlice(src.Spec.Parameters, func(parameter v1alpha1.ParameterValue) ParameterValue {
+			return ParameterValue(parameter)
+		}),
+		NodeOverrides: convertSlice(src.Spec.NodeOverrides, func(nodeOverride v1alpha1.NodeOverride) NodeOverride {
+			return NodeOverride(nodeOverride)
+		}),
+		Secrets: convertSlice(src.Spec.Secrets, func(secret v1alpha1.SecretBinding) SecretBinding {
+			return SecretBinding(secret)
+		}),
+	}
+	dst.Status = LabInstanceStatus{
+		Status:             src.Status.Status,
+		NumPodsRunning:     src.Status.NumPodsRunning,
+		NumVMsRunning:      src.Status.NumVMsRunning,
+		ObservedGeneration: src.Status.ObservedGeneration,
+		Conditions:         src.Status.Conditions,
+		Nodes: convertSlice(src.Status.Nodes, func(node v1alpha1.NodeStatus) NodeStatus {
+			return NodeStatus{
+				Name:           node.Name,
+				Kind:           NodeKind(node.Kind),
+				Phase:          node.Phase,
+				Ready:          node.Ready,
+				IPs:            node.IPs,
+				HostNode:       node.HostNode,
+				LastError:      node.LastError,
+				WebTerminalURL: node.WebTerminalURL,
+				RemoteAccess: convertSlice(node.RemoteAccess, func(endpoint v1alpha1.RemoteAccessEndpoint) RemoteAccessEndpoint {
+					return RemoteAccessEndpoint(endpoint)
+				}),
+			}
+		}),
+		Network: LabInstanceNetworkStatus(src.Status.Network),
+		Links: convertSlice(src.Status.Links, func(link v1alpha1.LinkStatus) LinkStatus {
+			return LinkStatus{
+				Name:      link.Name,
+				Endpoints: convertSlice(link.Endpoints, linkEndpointFromV1alpha1),
+				State:     LinkState(link.State),
+				Message:   link.Message,
+				Interfaces: convertSlice(link.Interfaces, func(linkInterface v1alpha1.LinkInterfaceStatus) LinkInterfaceStatus {
+					return LinkInterfaceStatus{LinkEndpoint: linkEndpointFromV1alpha1(linkInterface.LinkEndpoint), State: LinkState(linkInterface.State), Impaired: linkInterface.Impaired}
+				}),
+			}
+		}),
+	}
+	return nil
+}
@@ -0,0 +1,248 @@
+package v1beta1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// LabInstanceSpec define which LabTemplate should be used for the lab instance and the DNS address.
+type LabInstanceSpec struct {
+	// Reference to the name of a LabTemplate to use for the lab instance.
+	LabTemplateReference string `json:"labTemplateReference"`
+	// The DNS address, which will be used to expose the lab instance.
+	// It should point to the Kubernetes node where the lab instance is running.
+	// Defaults to the default DNS address of the operator.
+	//+optional
+	DNSAddress string `json:"dnsAddress,omitempty"`
+	// The network backend, which implements the links between the lab nodes.
+	// Defaults to bridge, which only connects lab nodes running on the same Kubernetes node.
+	//+optional
+	NetworkBackend NetworkBackend `json:"networkBackend,omitempty"`
+	// Impairments of links of the lab template, which replace the impairments defined in the lab template.
+	// They are applied to the running lab without redeploying it.
+	//+optional
+	LinkImpairments []LinkImpairment `json:"linkImpairments,omitempty"`
+	// Links of the lab template, which are administratively down, referenced by one of their interfaces.
+	// The interfaces of these links are set down in the running lab without redeploying it.
+	//+optional
+	DownLinks []LinkEndpoint `json:"downLinks,omitempty"`
+	// Controls, whether changes of the lab template and its node types are applied to the running pods and VMs of the lab instance.
+	// With Manual, a pod or VM keeps its old spec until it is deleted.
+	//+kubebuilder:default=Automatic
+	//+optional
+	RolloutPolicy RolloutPolicy `json:"rolloutPolicy,omitempty"`
+	// Values of the parameters declared by the lab template.
+	//+listType=map
+	//+listMapKey=name
+	//+optional
+	Parameters []ParameterValue `json:"parameters,omitempty"`
+	// Changes of lab nodes of the lab template, which only apply to this lab instance.
+	//+optional
+	NodeOverrides []NodeOverride `json:"nodeOverrides,omitempty"`
+	// Secrets in the namespace of the lab instance, which replace the Secrets of the secret references of the lab nodes with the same name.
+	//+listType=map
+	//+listMapKey=name
+	//+optional
+	Secrets []SecretBinding `json:"secrets,omitempty"`
+}
+
+// SecretBinding sets the Secret of the secret references of the lab nodes with the given name.
+type SecretBinding struct {
+	// The name of the secret references.
+	Name string `json:"name"`
+	// The name of the Secret in the namespace of the lab instance.
+	SecretName string `json:"secretName"`
+}
+
+// ParameterValue sets a parameter of the lab template.
+type ParameterValue struct {
+	// The name of the parameter.
+	Name string `json:"name"`
+	// The value of the parameter, it has to match the type of the parameter.
+	Value string `json:"value"`
+}
+
+// NodeOverride replaces fields of a lab node of the lab template for a lab instance.
+// Fields, which are not set, keep the value of the lab template.
+type NodeOverride struct {
+	// The name of the lab node.
+	Node string `json:"node"`
+	// Replaces the image of the node type reference of the lab node.
+	//+optional
+	Image string `json:"image,omitempty"`
+	// Replaces the version of the node type reference of the lab node.
+	//+optional
+	Version string `json:"version,omitempty"`
+	// Replaces the configuration of the lab node.
+	//+optional
+	Config string `json:"config,omitempty"`
+	// Replaces the resources of the first container of a pod, or the resources of the domain of a VM.
+	//+optional
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+}
+
+// LinkImpairment replaces the impairment of a link of the lab template.
+type LinkImpairment struct {
+	// One of the two interfaces of the link.
+	Endpoint LinkEndpoint `json:"endpoint"`
+	// The impairment of the link, an empty impairment removes the impairment of the lab template.
+	Impairment Impairment `json:"impairment"`
+}
+
+// NetworkBackend selects the CNI plugins, which implement the links of a lab instance.
+// +kubebuilder:validation:Enum=bridge;vxlan;ovn-k8s;ovs
+type NetworkBackend string
+
+const (
+	// A linux bridge per link, only works if all lab nodes run on the same Kubernetes node.
+	BridgeNetworkBackend NetworkBackend = "bridge"
+	// A VLAN per link on a linux bridge, which is connected to the other Kubernetes nodes with a VXLAN interface.
+	VXLANNetworkBackend NetworkBackend = "vxlan"
+	// An OVN-Kubernetes secondary network with layer 2 topology per link.
+	OVNK8sNetworkBackend NetworkBackend = "ovn-k8s"
+	// A VLAN per link on an Open vSwitch bridge, which is connected to the other Kubernetes nodes, using OVS-CNI.
+	OVSNetworkBackend NetworkBackend = "ovs"
+)
+
+// LabInstanceStatus shows the state of the lab instance, its nodes and links.
+type LabInstanceStatus struct {
+	// Summary of the state of the lab instance, e.g. Running.
+	Status string `json:"status,omitempty"`
+	// Number of running pods and the total number of pods, e.g. 2/3.
+	NumPodsRunning string `json:"numPodsRunning,omitempty"`
+	// Number of running VMs and the total number of VMs, e.g. 1/1.
+	NumVMsRunning string `json:"numVMsRunning,omitempty"`
+	// The generation of the lab instance, which was last observed by the operator.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Conditions of the lab instance, see the condition types for their meaning.
+	//+listType=map
+	//+listMapKey=type
+	//+optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// Status of every lab node.
+	Nodes []NodeStatus `json:"nodes,omitempty"`
+	// Network resources allocated for the lab instance.
+	Network LabInstanceNetworkStatus `json:"network,omitempty"`
+	// State of the links of the lab template.
+	Links []LinkStatus `json:"links,omitempty"`
+}
+
+// Condition types of a lab instance.
+const (
+	// The subnet, the network attachment definitions and the link configuration of the lab instance exist.
+	NetworkReadyCondition = "NetworkReady"
+	// All lab nodes are running and ready.
+	NodesReadyCondition = "NodesReady"
+	// The web terminal and the services and ingresses for the remote access to the lab nodes exist.
+	RemoteAccessReadyCondition = "RemoteAccessReady"
+	// The lab instance or one of its lab nodes failed and needs attention.
+	DegradedCondition = "Degraded"
+	// The lab instance is deleted and its resources are torn down, the reason shows the current step.
+	TerminatingCondition = "Terminating"
+)
+
+// NodeStatus records the state of a lab node.
+type NodeStatus struct {
+	// The name of the lab node.
+	Name string `json:"name"`
+	// Whether the lab node runs as pod or vm.
+	Kind NodeKind `json:"kind,omitempty"`
+	// The phase of the pod or the printable status of the VM.
+	Phase string `json:"phase,omitempty"`
+	// Whether the lab node is ready.
+	Ready bool `json:"ready"`
+	// The IP addresses of the lab node.
+	IPs []string `json:"ips,omitempty"`
+	// The Kubernetes node, on which the lab node runs.
+	HostNode string `json:"hostNode,omitempty"`
+	// The last error of the lab node, e.g. why its containers don't start.
+	LastError string `json:"lastError,omitempty"`
+	// URL of the web terminal of the lab node.
+	WebTerminalURL string `json:"webTerminalURL,omitempty"`
+	// Endpoints of the publicly exposed ports of the lab node, they are listed once the load balancer assigned an address.
+	RemoteAccess []RemoteAccessEndpoint `json:"remoteAccess,omitempty"`
+}
+
+// RemoteAccessEndpoint is the address of a publicly exposed port of a lab node.
+type RemoteAccessEndpoint struct {
+	// The name of the port.
+	Name string `json:"name,omitempty"`
+	// The external IP address or hostname of the load balancer.
+	Host string `json:"host"`
+	// The port number.
+	Port int32 `json:"port"`
+	// The protocol of the port, either TCP or UDP.
+	Protocol corev1.Protocol `json:"protocol,omitempty"`
+}
+
+// LinkState is the administrative state of a link.
+type LinkState string
+
+const (
+	LinkStateUp   LinkState = "Up"
+	LinkStateDown LinkState = "Down"
+)
+
+// LinkStatus records the state of a link of the lab template.
+type LinkStatus struct {
+	// Name of the link.
+	Name string `json:"name,omitempty"`
+	// The two interfaces, which are connected by the link.
+	Endpoints []LinkEndpoint `json:"endpoints"`
+	// The state of the link, which is enforced on the interfaces of the pods.
+	State LinkState `json:"state"`
+	// Explains why the state of the link differs from the requested state.
+	Message string `json:"message,omitempty"`
+	// What is applied to each interface of the link, as only the link sidecars of pods apply the state and the impairment of their interfaces.
+	//+optional
+	Interfaces []LinkInterfaceStatus `json:"interfaces,omitempty"`
+}
+
+// LinkInterfaceStatus records, what is applied to one interface of a link.
+type LinkInterfaceStatus struct {
+	LinkEndpoint `json:",inline"`
+	// The state of the interface, the interfaces of VMs are always up.
+	State LinkState `json:"state"`
+	// Whether the impairment of the link is applied to the interface, the interfaces of VMs are never impaired.
+	//+optional
+	Impaired bool `json:"impaired,omitempty"`
+}
+
+// LabInstanceNetworkStatus records the network resources allocated for a lab instance.
+type LabInstanceNetworkStatus struct {
+	// Name of the network shared by all links of the lab instance.
+	// The bridges of the links are derived from it and are therefore unique to the lab instance.
+	Name string `json:"name,omitempty"`
+	// Subnet allocated from the lab network pool of the operator.
+	// Interfaces without static addresses get their addresses from this subnet.
+	Subnet string `json:"subnet,omitempty"`
+	// IDs of the segments of the links, if the network backend isolates them by ID, e.g. with VLAN tags.
+	Segments map[string]int32 `json:"segments,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="STATUS",type=string,JSONPath=`.status.status`
+//+kubebuilder:printcolumn:name="PODS_RUNNING",type=string,JSONPath=`.status.numPodsRunning`
+//+kubebuilder:printcolumn:name="VMS_RUNNING",type=string,JSONPath=`.status.numVMsRunning`
+//+kubebuilder:printcolumn:name="SUBNET",type=string,JSONPath=`.status.network.subnet`,priority=1
+
+// A lab instance is created as a specific instance of a deployed lab, using the configuration from the corresponding lab template.
+type LabInstance struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   LabInstanceSpec   `json:"spec,omitempty"`
+	Status LabInstanceStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+type LabInstanceList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []LabInstance `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&LabInstance{}, &LabInstanceList{})
+}
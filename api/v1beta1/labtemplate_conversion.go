@@ -0,0 +1,187 @@
+package v1beta1
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+
+	"github.com/Lab-Topology-Builder/LTB-K8s-Backend/api/v1alpha1"
+)
+
+// Annotation of a v1beta1 lab template, which keeps the deprecated rendered node specs of the nodes of the v1alpha1 lab template by node name.
+// v1beta1 only has the rendered node specs in the status, the annotation is removed again, when the lab template is converted back.
+const RenderedNodeSpecsAnnotation = "ltb-backend.ltb/v1alpha1-rendered-node-specs"
+
+// Annotation of a v1beta1 lab template, which keeps the deprecated neighbors of the v1alpha1 lab template.
+// v1beta1 doesn't have neighbors, the annotation is removed again, when the lab template is converted back.
+const NeighborsAnnotation = "ltb-backend.ltb/v1alpha1-neighbors"
+
+// ConvertTo converts this LabTemplate to the hub version v1alpha1.
+// The deprecated rendered node specs and neighbors are restored from their annotations.
+func (src *LabTemplate) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v1alpha1.LabTemplate)
+	src.ObjectMeta.DeepCopyInto(&dst.ObjectMeta)
+	renderedNodeSpecs := map[string]string{}
+	if annotation, ok := dst.Annotations[RenderedNodeSpecsAnnotation]; ok {
+		if err := json.Unmarshal([]byte(annotation), &renderedNodeSpecs); err != nil {
+			return fmt.Errorf("invalid annotation %s: %w", RenderedNodeSpecsAnnotation, err)
+		}
+		delete(dst.Annotations, RenderedNodeSpecsAnnotation)
+	}
+	var neighbors []string
+	if annotation, ok := dst.Annotations[NeighborsAnnotation]; ok {
+		if err := json.Unmarshal([]byte(annotation), &neighbors); err != nil {
+			return fmt.Errorf("invalid annotation %s: %w", NeighborsAnnotation, err)
+		}
+		delete(dst.Annotations, NeighborsAnnotation)
+	}
+	if len(dst.Annotations) == 0 {
+		dst.Annotations = nil
+	}
+	dst.Spec = v1alpha1.LabTemplateSpec{
+		Nodes: convertSlice(src.Spec.Nodes, func(node LabNode) v1alpha1.LabInstanceNodes {
+			return v1alpha1.LabInstanceNodes{
+				Name:        node.Name,
+				NodeTypeRef: v1alpha1.NodeTypeRef(node.NodeTypeRef),
+				Interfaces: convertSlice(node.Interfaces, func(nodeInterface NodeInterface) v1alpha1.NodeInterface {
+					return v1alpha1.NodeInterface(nodeInterface)
+				}),
+				Config: node.Config,
+				Ports: convertSlice(node.Ports, func(port Port) v1alpha1.Port {
+					return v1alpha1.Port(port)
+				}),
+				Secrets: convertSlice(node.Secrets, func(secret SecretReference) v1alpha1.SecretReference {
+					return v1alpha1.SecretReference(secret)
+				}),
+				RenderedNodeSpec: renderedNodeSpecs[node.Name],
+			}
+		}),
+		Neighbors: neighbors,
+		Links: convertSlice(src.Spec.Links, func(link Link) v1alpha1.Link {
+			return v1alpha1.Link{
+				Name:       link.Name,
+				Endpoints:  convertSlice(link.Endpoints, linkEndpointToV1alpha1),
+				Impairment: impairmentToV1alpha1(link.Impairment),
+			}
+		}),
+		RolloutPolicy: v1alpha1.RolloutPolicy(src.Spec.RolloutPolicy),
+		Parameters: convertSlice(src.Spec.Parameters, func(parameter Parameter) v1alpha1.Parameter {
+			return v1alpha1.Parameter{
+				Name:        parameter.Name,
+				Type:        v1alpha1.ParameterType(parameter.Type),
+				Description: parameter.Description,
+				Default:     parameter.Default,
+				Required:    parameter.Required,
+			}
+		}),
+	}
+	dst.Status = v1alpha1.LabTemplateStatus{
+		ObservedGeneration: src.Status.ObservedGeneration,
+		Conditions:         src.Status.Conditions,
+		Nodes: convertSlice(src.Status.Nodes, func(node RenderedNode) v1alpha1.RenderedNode {
+			return v1alpha1.RenderedNode{
+				Name:             node.Name,
+				Kind:             string(node.Kind),
+				NodeSpec:         node.NodeSpec,
+				ConfigDelivery:   configDeliveryToV1alpha1(node.ConfigDelivery),
+				RenderedNodeSpec: node.RenderedNodeSpec,
+				Requests:         node.Requests,
+				Conditions:       node.Conditions,
+			}
+		}),
+		NumPods:  src.Status.NumPods,
+		NumVMs:   src.Status.NumVMs,
+		Requests: src.Status.Requests,
+		UsedBy:   src.Status.UsedBy,
+	}
+	return nil
+}
+
+// ConvertFrom converts the hub version v1alpha1 to this LabTemplate.
+// The deprecated rendered node specs in the spec and the deprecated neighbors are kept in annotations.
+func (dst *LabTemplate) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*v1alpha1.LabTemplate)
+	src.ObjectMeta.DeepCopyInto(&dst.ObjectMeta)
+	renderedNodeSpecs := map[string]string{}
+	for _, node := range src.Spec.Nodes {
+		if node.RenderedNodeSpec != "" {
+			renderedNodeSpecs[node.Name] = node.RenderedNodeSpec
+		}
+	}
+	if len(renderedNodeSpecs) > 0 {
+		annotation, err := json.Marshal(renderedNodeSpecs)
+		if err != nil {
+			return err
+		}
+		if dst.Annotations == nil {
+			dst.Annotations = map[string]string{}
+		}
+		dst.Annotations[RenderedNodeSpecsAnnotation] = string(annotation)
+	}
+	if len(src.Spec.Neighbors) > 0 {
+		annotation, err := json.Marshal(src.Spec.Neighbors)
+		if err != nil {
+			return err
+		}
+		if dst.Annotations == nil {
+			dst.Annotations = map[string]string{}
+		}
+		dst.Annotations[NeighborsAnnotation] = string(annotation)
+	}
+	dst.Spec = LabTemplateSpec{
+		Nodes: convertSlice(src.Spec.Nodes, func(node v1alpha1.LabInstanceNodes) LabNode {
+			return LabNode{
+				Name:        node.Name,
+				NodeTypeRef: NodeTypeRef(node.NodeTypeRef),
+				Interfaces: convertSlice(node.Interfaces, func(nodeInterface v1alpha1.NodeInterface) NodeInterface {
+					return NodeInterface(nodeInterface)
+				}),
+				Config: node.Config,
+				Ports: convertSlice(node.Ports, func(port v1alpha1.Port) Port {
+					return Port(port)
+				}),
+				Secrets: convertSlice(node.Secrets, func(secret v1alpha1.SecretReference) SecretReference {
+					return SecretReference(secret)
+				}),
+			}
+		}),
+		Links: convertSlice(src.Spec.Links, func(link v1alpha1.Link) Link {
+			return Link{
+				Name:       link.Name,
+				Endpoints:  convertSlice(link.Endpoints, linkEndpointFromV1alpha1),
+				Impairment: impairmentFromV1alpha1(link.Impairment),
+			}
+		}),
+		RolloutPolicy: RolloutPolicy(src.Spec.RolloutPolicy),
+		Parameters: convertSlice(src.Spec.Parameters, func(parameter v1alpha1.Parameter) Parameter {
+			return Parameter{
+				Name:        parameter.Name,
+				Type:        ParameterType(parameter.Type),
+				Description: parameter.Description,
+				Default:     parameter.Default,
+				Required:    parameter.Required,
+			}
+		}),
+	}
+	dst.Status = LabTemplateStatus{
+		ObservedGeneration: src.Status.ObservedGeneration,
+		Conditions:         src.Status.Conditions,
+		Nodes: convertSlice(src.Status.Nodes, func(node v1alpha1.RenderedNode) RenderedNode {
+			return RenderedNode{
+				Name:             node.Name,
+				Kind:             NodeKind(node.Kind),
+				NodeSpec:         node.NodeSpec,
+				ConfigDelivery:   configDeliveryFromV1alpha1(node.ConfigDelivery),
+				RenderedNodeSpec: node.RenderedNodeSpec,
+				Requests:         node.Requests,
+				Conditions:       node.Conditions,
+			}
+		}),
+		NumPods:  src.Status.NumPods,
+		NumVMs:   src.Status.NumVMs,
+		Requests: src.Status.Requests,
+		UsedBy:   src.Status.UsedBy,
+	}
+	return nil
+}
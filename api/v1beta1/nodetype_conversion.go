@@ -0,0 +1,34 @@
+package v1beta1
+
+import (
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+
+	"github.com/Lab-Topology-Builder/LTB-K8s-Backend/api/v1alpha1"
+)
+
+// ConvertTo converts this NodeType to the hub version v1alpha1.
+func (src *NodeType) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v1alpha1.NodeType)
+	src.ObjectMeta.DeepCopyInto(&dst.ObjectMeta)
+	dst.Spec = v1alpha1.NodeTypeSpec{
+		Kind:           string(src.Spec.Kind),
+		NodeSpec:       src.Spec.NodeSpec,
+		ConfigDelivery: configDeliveryToV1alpha1(src.Spec.ConfigDelivery),
+	}
+	dst.Status = v1alpha1.NodeTypeStatus(src.Status)
+	return nil
+}
+
+// ConvertFrom converts the hub version v1alpha1 to this NodeType.
+// Kinds other than pod and vm are kept, they are rejected by the validating webhook anyway.
+func (dst *NodeType) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*v1alpha1.NodeType)
+	src.ObjectMeta.DeepCopyInto(&dst.ObjectMeta)
+	dst.Spec = NodeTypeSpec{
+		Kind:           NodeKind(src.Spec.Kind),
+		NodeSpec:       src.Spec.NodeSpec,
+		ConfigDelivery: configDeliveryFromV1alpha1(src.Spec.ConfigDelivery),
+	}
+	dst.Status = NodeTypeStatus(src.Status)
+	return nil
+}
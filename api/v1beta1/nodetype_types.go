@@ -0,0 +1,106 @@
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// NodeKind is the kind of the resource, which runs the lab nodes of a NodeType.
+// +kubebuilder:validation:Enum=pod;vm
+type NodeKind string
+
+const (
+	// The lab nodes run as pods.
+	PodNodeKind NodeKind = "pod"
+	// The lab nodes run as KubeVirt virtual machines.
+	VMNodeKind NodeKind = "vm"
+)
+
+// NodeTypeSpec defines the Kind and NodeSpec for a NodeType
+type NodeTypeSpec struct {
+	// Kind specifies if the nodes are either a pod or a vm
+	Kind NodeKind `json:"kind"`
+	// NodeSpec is the PodSpec or VirtualMachineSpec configuration for the node with the possibility to use go templating syntax to include LabTemplate variables (see [User Guide](https://lab-topology-builder.github.io/LTB-K8s-Backend/user-guide/#example-node-type))
+	// See [PodSpec](https://kubernetes.io/docs/reference/generated/kubernetes-api/v1.26/#podspec-v1-core) and [VirtualMachineSpec](https://kubevirt.io/api-reference/master/definitions.html#_v1_virtualmachinespec)
+	NodeSpec string `json:"nodeSpec"`
+	// Delivers the config of the lab nodes as a file, instead of including it in the node spec with {{ .Config }}.
+	// Without it, the config only reaches the lab node, if the node spec references it.
+	//+optional
+	ConfigDelivery *ConfigDelivery `json:"configDelivery,omitempty"`
+}
+
+// ConfigDelivery declares, how the config of a lab node is delivered into the lab node.
+type ConfigDelivery struct {
+	// ConfigMap mounts a ConfigMap with the config into the first container of a pod.
+	// CloudInitNoCloud and CloudInitConfigDrive pass the config as cloud-init user data from a Secret to a VM.
+	Type ConfigDeliveryType `json:"type"`
+	// The directory in the first container of a pod, which the ConfigMap is mounted at, e.g. /etc/frr.
+	// It only contains the file with the config. Required for ConfigMap.
+	//+optional
+	MountPath string `json:"mountPath,omitempty"`
+	// The name of the file with the config in the mount path.
+	//+kubebuilder:default=config
+	//+optional
+	FileName string `json:"fileName,omitempty"`
+}
+
+// ConfigDeliveryType is the way the config of a lab node is delivered.
+// +kubebuilder:validation:Enum=ConfigMap;CloudInitNoCloud;CloudInitConfigDrive
+type ConfigDeliveryType string
+
+const (
+	// The config is mounted as a file from a ConfigMap into a pod.
+	ConfigMapConfigDelivery ConfigDeliveryType = "ConfigMap"
+	// The config is the user data of a cloud-init NoCloud disk of a VM.
+	CloudInitNoCloudConfigDelivery ConfigDeliveryType = "CloudInitNoCloud"
+	// The config is the user data of a cloud-init ConfigDrive disk of a VM.
+	CloudInitConfigDriveConfigDelivery ConfigDeliveryType = "CloudInitConfigDrive"
+)
+
+// NodeTypeStatus shows whether the node spec of the NodeType is valid and which lab templates use it.
+type NodeTypeStatus struct {
+	// The generation of the node type, which was last validated by the operator.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Conditions of the node type, the Valid condition explains why the node spec doesn't render or decode.
+	//+listType=map
+	//+listMapKey=type
+	//+optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// Names of the lab templates, which reference the node type.
+	UsedBy []string `json:"usedBy,omitempty"`
+	// SHA-256 hash of the current spec of the node type.
+	SpecHash string `json:"specHash,omitempty"`
+}
+
+// Condition types of a node type.
+const (
+	// The node spec renders with test data and decodes into a spec of the kind of the node type.
+	ValidCondition = "Valid"
+)
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:scope=Cluster
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="KIND",type=string,JSONPath=`.spec.kind`
+//+kubebuilder:printcolumn:name="VALID",type=string,JSONPath=`.status.conditions[?(@.type=="Valid")].status`
+//+kubebuilder:printcolumn:name="USED_BY",type=string,JSONPath=`.status.usedBy`
+
+// NodeType defines a type of node that can be used in a lab template
+type NodeType struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   NodeTypeSpec   `json:"spec,omitempty"`
+	Status NodeTypeStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+type NodeTypeList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []NodeType `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&NodeType{}, &NodeTypeList{})
+}
@@ -0,0 +1,41 @@
+package controllers
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+)
+
+// Reasons of the events, which are recorded for the custom resources.
+const (
+	CreatedReason         = "Created"
+	UpdatedReason         = "Updated"
+	DeletedReason         = "Deleted"
+	RecreatingReason      = "Recreating"
+	ResourceMissingReason = "ResourceMissing"
+	ReadyReason           = "Ready"
+	NotReadyReason        = "NotReady"
+	NodesFailedReason     = "NodesFailed"
+	RenderedReason        = "Rendered"
+	RenderFailedReason    = "RenderFailed"
+	NodeTypeMissingReason = "NodeTypeMissing"
+	UpdateFailedReason    = "UpdateFailed"
+	ValidReason           = "Valid"
+	InvalidReason         = "Invalid"
+	NotValidatedReason    = "NotValidated"
+	StoppingVMsReason     = "StoppingVMs"
+	DeletingNodesReason   = "DeletingNodes"
+	DeletingAccessReason  = "DeletingRemoteAccess"
+	DeletingNetworkReason = "DeletingNetwork"
+	TeardownFailedReason  = "TeardownFailed"
+	TornDownReason        = "TornDown"
+)
+
+// recordEvent records an event for a custom resource, no event is recorded if the recorder is nil.
+func recordEvent(recorder record.EventRecorder, object runtime.Object, eventType string, reason string, messageFmt string, args ...interface{}) {
+	if recorder == nil {
+		return
+	}
+	recorder.Event(object, eventType, reason, fmt.Sprintf(messageFmt, args...))
+}
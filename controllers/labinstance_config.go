@@ -0,0 +1,239 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"reflect"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/yaml"
+
+	ltbv1alpha1 "github.com/Lab-Topology-Builder/LTB-K8s-Backend/api/v1alpha1"
+)
+
+// Name of the volume, and for VMs the disk, which delivers the config of a lab node.
+const nodeConfigVolumeName = "ltb-config"
+
+// Name of the file with the config, if the node type doesn't declare one.
+const defaultConfigFileName = "config"
+
+// Key of the cloud-init user data in the Secret of a lab node, as expected by KubeVirt.
+const userDataKey = "userdata"
+
+// NodeConfigName returns the name of the ConfigMap or Secret, which holds the config of a lab node.
+func NodeConfigName(labInstance *ltbv1alpha1.LabInstance, nodeName string) string {
+	return labInstance.Name + "-" + nodeName + "-config"
+}
+
+// GetNodeConfig returns the config of a lab node, which is replaced by the override of the lab instance, if it sets one.
+func GetNodeConfig(labInstance *ltbv1alpha1.LabInstance, node *ltbv1alpha1.LabInstanceNodes) string {
+	if nodeOverride := GetNodeOverride(labInstance, node.Name); nodeOverride != nil && nodeOverride.Config != "" {
+		return nodeOverride.Config
+	}
+	return node.Config
+}
+
+// configFileName returns the name of the file with the config in the mount path.
+func configFileName(configDelivery *ltbv1alpha1.ConfigDelivery) string {
+	if configDelivery.FileName == "" {
+		return defaultConfigFileName
+	}
+	return configDelivery.FileName
+}
+
+// ValidateConfigDelivery checks, that the config delivery of a node type suits its kind.
+// ConfigMaps are only mounted into pods, while cloud-init is only supported by VMs.
+func ValidateConfigDelivery(nodetype *ltbv1alpha1.NodeType) error {
+	configDelivery := nodetype.Spec.ConfigDelivery
+	if configDelivery == nil {
+		return nil
+	}
+	switch configDelivery.Type {
+	case ltbv1alpha1.ConfigMapConfigDelivery:
+		if nodetype.Spec.Kind != "pod" {
+			return errors.NewBadRequest(fmt.Sprintf("Config delivery %s is only supported for pods", configDelivery.Type))
+		}
+		if !path.IsAbs(configDelivery.MountPath) {
+			return errors.NewBadRequest(fmt.Sprintf("Config delivery %s needs an absolute mount path, got %q", configDelivery.Type, configDelivery.MountPath))
+		}
+		if strings.Contains(configDelivery.FileName, "/") || configDelivery.FileName == "." || configDelivery.FileName == ".." {
+			return errors.NewBadRequest(fmt.Sprintf("Invalid config file name %q, it must not contain a path", configDelivery.FileName))
+		}
+	case ltbv1alpha1.CloudInitNoCloudConfigDelivery, ltbv1alpha1.CloudInitConfigDriveConfigDelivery:
+		if nodetype.Spec.Kind != "vm" {
+			return errors.NewBadRequest(fmt.Sprintf("Config delivery %s is only supported for VMs", configDelivery.Type))
+		}
+	default:
+		return errors.NewBadRequest(fmt.Sprintf("Invalid config delivery %q, must be ConfigMap, CloudInitNoCloud or CloudInitConfigDrive", configDelivery.Type))
+	}
+	return nil
+}
+
+// CreateNodeConfig returns the ConfigMap or, for cloud-init, the Secret with the config of a lab node.
+// It returns nil, if the config isn't delivered as a file or the lab node has no config.
+func CreateNodeConfig(labInstance *ltbv1alpha1.LabInstance, node *ltbv1alpha1.LabInstanceNodes, configDelivery *ltbv1alpha1.ConfigDelivery) client.Object {
+	config := GetNodeConfig(labInstance, node)
+	if configDelivery == nil || config == "" {
+		return nil
+	}
+	metadata := metav1.ObjectMeta{
+		Name:      NodeConfigName(labInstance, node.Name),
+		Namespace: labInstance.Namespace,
+	}
+	if configDelivery.Type == ltbv1alpha1.ConfigMapConfigDelivery {
+		return &corev1.ConfigMap{
+			ObjectMeta: metadata,
+			Data:       map[string]string{configFileName(configDelivery): config},
+		}
+	}
+	return &corev1.Secret{
+		ObjectMeta: metadata,
+		Data:       map[string][]byte{userDataKey: []byte(config)},
+	}
+}
+
+// ReconcileNodeConfig creates the ConfigMap or Secret with the config of a lab node and keeps it up to date.
+// Pods see changes of the mounted file after a short delay, VMs read their cloud-init user data when they boot.
+func (r *LabInstanceReconciler) ReconcileNodeConfig(ctx context.Context, labInstance *ltbv1alpha1.LabInstance, node *ltbv1alpha1.LabInstanceNodes, configDelivery *ltbv1alpha1.ConfigDelivery) ReturnToReconciler {
+	log := log.FromContext(ctx)
+	retValue := ReturnToReconciler{shouldReturn: true, result: ctrl.Result{}, err: nil}
+	if labInstance == nil {
+		retValue.err = errors.NewBadRequest("labInstance is nil")
+		return retValue
+	}
+	if node == nil {
+		retValue.err = errors.NewBadRequest("node is nil")
+		return retValue
+	}
+	config := CreateNodeConfig(labInstance, node, configDelivery)
+	if config == nil {
+		retValue.shouldReturn = false
+		return retValue
+	}
+	kind := reflect.TypeOf(config).Elem().Name()
+	foundConfig := config.DeepCopyObject().(client.Object)
+	err := r.Get(ctx, client.ObjectKeyFromObject(config), foundConfig)
+	if errors.IsNotFound(err) {
+		ctrl.SetControllerReference(labInstance, config, r.Scheme)
+		r.ReportMissingResource(ctx, labInstance, config)
+		log.Info("Creating the config of a node", "Kind", kind, "Namespace", config.GetNamespace(), "Name", config.GetName())
+		err = r.Create(ctx, config)
+		if err != nil {
+			retValue.err = err
+			log.Error(err, "Failed to create the config of a node", "Kind", kind)
+			return retValue
+		}
+		recordEvent(r.Recorder, labInstance, corev1.EventTypeNormal, CreatedReason, "Created %s %s", kind, config.GetName())
+		retValue.result = ctrl.Result{Requeue: true}
+		return retValue
+	}
+	if err != nil {
+		retValue.err = err
+		log.Error(err, "Failed to get the config of a node", "Kind", kind)
+		return retValue
+	}
+	if mergeNodeConfig(foundConfig, config) {
+		log.Info("Updating the config of a node", "Kind", kind, "Namespace", foundConfig.GetNamespace(), "Name", foundConfig.GetName())
+		err = r.Update(ctx, foundConfig)
+		if err != nil {
+			retValue.err = err
+			log.Error(err, "Failed to update the config of a node", "Kind", kind)
+			return retValue
+		}
+		recordEvent(r.Recorder, labInstance, corev1.EventTypeNormal, UpdatedReason, "Updated %s %s", kind, foundConfig.GetName())
+	}
+	retValue.shouldReturn = false
+	return retValue
+}
+
+// mergeNodeConfig copies the desired data into an existing ConfigMap or Secret and reports whether it changed.
+func mergeNodeConfig(config client.Object, desired client.Object) bool {
+	switch actual := config.(type) {
+	case *corev1.ConfigMap:
+		desiredConfigMap := desired.(*corev1.ConfigMap)
+		if !reflect.DeepEqual(actual.Data, desiredConfigMap.Data) {
+			actual.Data = desiredConfigMap.Data
+			return true
+		}
+	case *corev1.Secret:
+		desiredSecret := desired.(*corev1.Secret)
+		if !reflect.DeepEqual(actual.Data, desiredSecret.Data) {
+			actual.Data = desiredSecret.Data
+			return true
+		}
+	}
+	return false
+}
+
+// AddNodeConfigVolume adds the volume with the config of a lab node to its rendered node spec, so the node spec stays free of the config.
+// The ConfigMap is mounted into the first container of a pod, and the Secret is attached as cloud-init disk to a VM.
+func AddNodeConfigVolume(labInstance *ltbv1alpha1.LabInstance, node *ltbv1alpha1.LabInstanceNodes, renderedNode *ltbv1alpha1.RenderedNode, renderedNodeSpec string) (string, error) {
+	configDelivery := renderedNode.ConfigDelivery
+	if configDelivery == nil || GetNodeConfig(labInstance, node) == "" {
+		return renderedNodeSpec, nil
+	}
+	configName := NodeConfigName(labInstance, node.Name)
+	var nodeSpec interface{}
+	switch configDelivery.Type {
+	case ltbv1alpha1.ConfigMapConfigDelivery:
+		podSpec := &corev1.PodSpec{}
+		if err := yaml.Unmarshal([]byte(renderedNodeSpec), podSpec); err != nil {
+			return "", fmt.Errorf("failed to decode the pod spec: %w", err)
+		}
+		if len(podSpec.Containers) == 0 {
+			return "", fmt.Errorf("the pod spec has no container to mount the config into")
+		}
+		podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+			Name:         nodeConfigVolumeName,
+			VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: configName}}},
+		})
+		podSpec.Containers[0].VolumeMounts = append(podSpec.Containers[0].VolumeMounts, corev1.VolumeMount{
+			Name:      nodeConfigVolumeName,
+			MountPath: configDelivery.MountPath,
+			ReadOnly:  true,
+		})
+		nodeSpec = podSpec
+	case ltbv1alpha1.CloudInitNoCloudConfigDelivery, ltbv1alpha1.CloudInitConfigDriveConfigDelivery:
+		vmSpec := &kubevirtv1.VirtualMachineSpec{}
+		if err := yaml.Unmarshal([]byte(renderedNodeSpec), vmSpec); err != nil {
+			return "", fmt.Errorf("failed to decode the VM spec: %w", err)
+		}
+		if vmSpec.Template == nil {
+			return "", fmt.Errorf("the VM spec has no template to attach the config to")
+		}
+		userData := &corev1.LocalObjectReference{Name: configName}
+		volume := kubevirtv1.Volume{Name: nodeConfigVolumeName}
+		if configDelivery.Type == ltbv1alpha1.CloudInitNoCloudConfigDelivery {
+			volume.CloudInitNoCloud = &kubevirtv1.CloudInitNoCloudSource{UserDataSecretRef: userData}
+		} else {
+			volume.CloudInitConfigDrive = &kubevirtv1.CloudInitConfigDriveSource{UserDataSecretRef: userData}
+		}
+		// A VM can only have one cloud-init disk
+		for _, existingVolume := range vmSpec.Template.Spec.Volumes {
+			if existingVolume.CloudInitNoCloud != nil || existingVolume.CloudInitConfigDrive != nil {
+				return "", fmt.Errorf("the VM spec already has the cloud-init volume %s", existingVolume.Name)
+			}
+		}
+		vmSpec.Template.Spec.Volumes = append(vmSpec.Template.Spec.Volumes, volume)
+		vmSpec.Template.Spec.Domain.Devices.Disks = append(vmSpec.Template.Spec.Domain.Devices.Disks, kubevirtv1.Disk{
+			Name:       nodeConfigVolumeName,
+			DiskDevice: kubevirtv1.DiskDevice{Disk: &kubevirtv1.DiskTarget{Bus: kubevirtv1.DiskBusVirtio}},
+		})
+		nodeSpec = vmSpec
+	default:
+		return "", fmt.Errorf("invalid config delivery %q", configDelivery.Type)
+	}
+	nodeSpecBytes, err := yaml.Marshal(nodeSpec)
+	if err != nil {
+		return "", err
+	}
+	return string(nodeSpecBytes), nil
+}
@@ -0,0 +1,142 @@
+package controllers
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	ltbv1alpha1 "github.com/Lab-Topology-Builder/LTB-K8s-Backend/api/v1alpha1"
+)
+
+var _ = Describe("LabInstance Config", func() {
+	var (
+		labInstance   *ltbv1alpha1.LabInstance
+		podNode       *ltbv1alpha1.LabInstanceNodes
+		vmNode        *ltbv1alpha1.LabInstanceNodes
+		configMapNode *ltbv1alpha1.RenderedNode
+		cloudInitNode *ltbv1alpha1.RenderedNode
+	)
+
+	BeforeEach(func() {
+		labInstance = testLabInstance.DeepCopy()
+		podNode = testPodNode.DeepCopy()
+		podNode.Config = "hostname router1"
+		vmNode = testVMNode.DeepCopy()
+		vmNode.Config = "#cloud-config\npassword: ubuntu"
+		configMapNode = &ltbv1alpha1.RenderedNode{
+			Name:           podNode.Name,
+			Kind:           "pod",
+			ConfigDelivery: &ltbv1alpha1.ConfigDelivery{Type: ltbv1alpha1.ConfigMapConfigDelivery, MountPath: "/etc/frr", FileName: "frr.conf"},
+		}
+		cloudInitNode = &ltbv1alpha1.RenderedNode{
+			Name:           vmNode.Name,
+			Kind:           "vm",
+			ConfigDelivery: &ltbv1alpha1.ConfigDelivery{Type: ltbv1alpha1.CloudInitNoCloudConfigDelivery},
+		}
+	})
+
+	Describe("ValidateConfigDelivery", func() {
+		It("should accept config deliveries, which suit the kind", func() {
+			nodeType := testPodNodeType.DeepCopy()
+			nodeType.Spec.ConfigDelivery = configMapNode.ConfigDelivery
+			Expect(ValidateConfigDelivery(nodeType)).To(Succeed())
+			nodeType = testNodeVMType.DeepCopy()
+			nodeType.Spec.ConfigDelivery = &ltbv1alpha1.ConfigDelivery{Type: ltbv1alpha1.CloudInitConfigDriveConfigDelivery}
+			Expect(ValidateConfigDelivery(nodeType)).To(Succeed())
+		})
+		It("should reject config deliveries of the other kind and invalid paths", func() {
+			nodeType := testNodeVMType.DeepCopy()
+			nodeType.Spec.ConfigDelivery = configMapNode.ConfigDelivery
+			Expect(ValidateConfigDelivery(nodeType)).To(MatchError(ContainSubstring("only supported for pods")))
+			nodeType = testPodNodeType.DeepCopy()
+			nodeType.Spec.ConfigDelivery = cloudInitNode.ConfigDelivery
+			Expect(ValidateConfigDelivery(nodeType)).To(MatchError(ContainSubstring("only supported for VMs")))
+			nodeType.Spec.ConfigDelivery = &ltbv1alpha1.ConfigDelivery{Type: ltbv1alpha1.ConfigMapConfigDelivery, MountPath: "etc/frr"}
+			Expect(ValidateConfigDelivery(nodeType)).To(MatchError(ContainSubstring("absolute mount path")))
+			nodeType.Spec.ConfigDelivery = &ltbv1alpha1.ConfigDelivery{Type: ltbv1alpha1.ConfigMapConfigDelivery, MountPath: "/etc", FileName: "frr/frr.conf"}
+			Expect(ValidateConfigDelivery(nodeType)).To(MatchError(ContainSubstring("must not contain a path")))
+		})
+	})
+
+	Describe("CreateNodeConfig", func() {
+		It("should put the config into a ConfigMap or a Secret", func() {
+			configMap := CreateNodeConfig(labInstance, podNode, configMapNode.ConfigDelivery).(*corev1.ConfigMap)
+			Expect(configMap.Name).To(Equal(labInstance.Name + "-" + podNode.Name + "-config"))
+			Expect(configMap.Namespace).To(Equal(labInstance.Namespace))
+			Expect(configMap.Data).To(Equal(map[string]string{"frr.conf": "hostname router1"}))
+			secret := CreateNodeConfig(labInstance, vmNode, cloudInitNode.ConfigDelivery).(*corev1.Secret)
+			Expect(secret.Data).To(HaveKeyWithValue(userDataKey, []byte(vmNode.Config)))
+		})
+		It("should use the config of the node override", func() {
+			labInstance.Spec.NodeOverrides = []ltbv1alpha1.NodeOverride{{Node: podNode.Name, Config: "hostname router2"}}
+			configMap := CreateNodeConfig(labInstance, podNode, configMapNode.ConfigDelivery).(*corev1.ConfigMap)
+			Expect(configMap.Data).To(HaveKeyWithValue("frr.conf", "hostname router2"))
+		})
+		It("should return nil without a config or a config delivery", func() {
+			Expect(CreateNodeConfig(labInstance, podNode, nil)).To(BeNil())
+			podNode.Config = ""
+			Expect(CreateNodeConfig(labInstance, podNode, configMapNode.ConfigDelivery)).To(BeNil())
+		})
+	})
+
+	Describe("AddNodeConfigVolume", func() {
+		It("should mount the ConfigMap into the first container of a pod", func() {
+			nodeSpec, err := AddNodeConfigVolume(labInstance, podNode, configMapNode, podNode.RenderedNodeSpec)
+			Expect(err).NotTo(HaveOccurred())
+			podSpec := &corev1.PodSpec{}
+			Expect(yaml.Unmarshal([]byte(nodeSpec), podSpec)).To(Succeed())
+			Expect(podSpec.Volumes).To(ConsistOf(corev1.Volume{
+				Name:         nodeConfigVolumeName,
+				VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: NodeConfigName(labInstance, podNode.Name)}}},
+			}))
+			Expect(podSpec.Containers[0].VolumeMounts).To(ConsistOf(corev1.VolumeMount{Name: nodeConfigVolumeName, MountPath: "/etc/frr", ReadOnly: true}))
+			Expect(podSpec.Containers[0].Command).To(HaveLen(3))
+		})
+		It("should attach the Secret as cloud-init disk to a VM", func() {
+			nodeSpec, err := AddNodeConfigVolume(labInstance, vmNode, cloudInitNode, vmNode.RenderedNodeSpec)
+			Expect(err).NotTo(HaveOccurred())
+			vmSpec := &kubevirtv1.VirtualMachineSpec{}
+			Expect(yaml.Unmarshal([]byte(nodeSpec), vmSpec)).To(Succeed())
+			Expect(vmSpec.Template.Spec.Volumes).To(HaveLen(2))
+			Expect(vmSpec.Template.Spec.Volumes[1].CloudInitNoCloud.UserDataSecretRef.Name).To(Equal(NodeConfigName(labInstance, vmNode.Name)))
+			Expect(vmSpec.Template.Spec.Domain.Devices.Disks[1].Name).To(Equal(nodeConfigVolumeName))
+			_, err = AddNodeConfigVolume(labInstance, vmNode, cloudInitNode, nodeSpec)
+			Expect(err).To(MatchError(ContainSubstring("already has the cloud-init volume")))
+		})
+		It("should keep the node spec without a config", func() {
+			podNode.Config = ""
+			nodeSpec, err := AddNodeConfigVolume(labInstance, podNode, configMapNode, podNode.RenderedNodeSpec)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(nodeSpec).To(Equal(podNode.RenderedNodeSpec))
+		})
+	})
+
+	Describe("ReconcileNodeConfig", func() {
+		It("should create the ConfigMap and update it, when the config changes", func() {
+			ctx := context.Background()
+			r := &LabInstanceReconciler{Client: fake.NewClientBuilder().Build(), Scheme: scheme.Scheme, Recorder: record.NewFakeRecorder(10)}
+			returnValue := r.ReconcileNodeConfig(ctx, labInstance, podNode, configMapNode.ConfigDelivery)
+			Expect(returnValue.err).NotTo(HaveOccurred())
+			Expect(returnValue.result).To(Equal(ctrl.Result{Requeue: true}))
+			configMap := &corev1.ConfigMap{}
+			key := types.NamespacedName{Namespace: labInstance.Namespace, Name: NodeConfigName(labInstance, podNode.Name)}
+			Expect(r.Get(ctx, key, configMap)).To(Succeed())
+			Expect(configMap.OwnerReferences).To(HaveLen(1))
+
+			podNode.Config = "hostname router2"
+			returnValue = r.ReconcileNodeConfig(ctx, labInstance, podNode, configMapNode.ConfigDelivery)
+			Expect(returnValue.shouldReturn).To(BeFalse())
+			Expect(r.Get(ctx, key, configMap)).To(Succeed())
+			Expect(configMap.Data).To(HaveKeyWithValue("frr.conf", "hostname router2"))
+		})
+	})
+})
@@ -4,33 +4,69 @@ package controllers
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"net"
 	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/tools/record"
 	kubevirtv1 "kubevirt.io/api/core/v1"
 
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	network "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
 
 	ltbv1alpha1 "github.com/Lab-Topology-Builder/LTB-K8s-Backend/api/v1alpha1"
+	"github.com/Lab-Topology-Builder/LTB-K8s-Backend/labnet"
+	util "github.com/Lab-Topology-Builder/LTB-K8s-Backend/util"
 )
 
+var invalidNameCharacters = regexp.MustCompile("[^a-z0-9-]+")
+
+// Interval to check again, while a resource is deleted to be recreated.
+const resourceRecreateInterval = 5 * time.Second
+
+// Field index of the lab template, which is referenced by a lab instance.
+const labTemplateRefField = ".spec.labTemplateReference"
+
 type LabInstanceReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+	// Allocates a subnet for each lab instance from the lab network pool, no subnets are allocated if nil.
+	SubnetAllocator *util.SubnetAllocator
+	// Allocates the segment IDs for network backends, which isolate the links by ID.
+	SegmentAllocator *util.SegmentAllocator
+	// Settings of the network backends, which depend on the infrastructure of the cluster.
+	NetworkConfig labnet.Config
+	// Records events of the lab instances, no events are recorded if nil.
+	Recorder record.EventRecorder
 }
 
 type ReturnToReconciler struct {
@@ -45,17 +81,21 @@ type ReturnToReconciler struct {
 //+kubebuilder:rbac:groups=kubevirt.io,resources=virtualmachines,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=kubevirt.io,resources=virtualmachines/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=kubevirt.io,resources=virtualmachines/finalizers,verbs=update
+//+kubebuilder:rbac:groups=kubevirt.io,resources=virtualmachineinstances,verbs=get;list;watch;delete
 //+kubebuilder:rbac:groups=subresources.kubevirt.io,resources=virtualmachineinstances/console,verbs=get;list;create;update;delete
 //+kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups="",resources=pods/log,verbs=get;list
 //+kubebuilder:rbac:groups="",resources=pods/exec,verbs=create;update;delete
 //+kubebuilder:rbac:groups="",resources=pods/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups="",resources=services,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups="",resources=serviceaccounts,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups="rbac.authorization.k8s.io",resources=roles,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups="rbac.authorization.k8s.io",resources=rolebindings,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=k8s.cni.cncf.io,resources=network-attachment-definitions,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 func (r *LabInstanceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := log.FromContext(ctx)
@@ -65,22 +105,57 @@ func (r *LabInstanceReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			log.Info("LabInstance resource not found. Ignoring since object must be deleted", "IsNotFound", err)
+			if r.SubnetAllocator != nil {
+				r.SubnetAllocator.Release(req.NamespacedName.String())
+			}
+			if r.SegmentAllocator != nil {
+				r.SegmentAllocator.Release(req.NamespacedName.String())
+			}
 			return ctrl.Result{Requeue: false}, client.IgnoreNotFound(err)
 		}
 		log.Error(err, "Failed to get LabInstance")
 		return ctrl.Result{}, err
 	}
 
+	if !labInstance.DeletionTimestamp.IsZero() {
+		return r.Teardown(ctx, labInstance)
+	}
+	if controllerutil.AddFinalizer(labInstance, labInstanceFinalizer) {
+		err = r.Update(ctx, labInstance)
+		if err != nil {
+			log.Error(err, "Failed to add finalizer to LabInstance")
+			return ctrl.Result{}, err
+		}
+	}
+
 	labTemplate := &ltbv1alpha1.LabTemplate{}
 	retValue := r.GetLabTemplate(ctx, labInstance, labTemplate)
 	if retValue.shouldReturn {
-		return retValue.result, retValue.err
+		return r.ReturnWithCondition(ctx, labInstance, ltbv1alpha1.NodesReadyCondition, retValue)
+	}
+
+	// Reconcile Secrets
+	retValue = r.ReconcileSecrets(ctx, labInstance, labTemplate)
+	if retValue.shouldReturn {
+		return r.ReturnWithCondition(ctx, labInstance, ltbv1alpha1.NodesReadyCondition, retValue)
+	}
+
+	// Reconcile Subnet
+	retValue = r.ReconcileSubnet(ctx, labInstance)
+	if retValue.shouldReturn {
+		return r.ReturnWithCondition(ctx, labInstance, ltbv1alpha1.NetworkReadyCondition, retValue)
 	}
 
 	// Reconcile Network
-	retValue = r.ReconcileNetwork(ctx, labInstance)
+	retValue = r.ReconcileNetwork(ctx, labInstance, labTemplate)
+	if retValue.shouldReturn {
+		return r.ReturnWithCondition(ctx, labInstance, ltbv1alpha1.NetworkReadyCondition, retValue)
+	}
+
+	// Reconcile Link Configuration
+	retValue = r.ReconcileLinkConfig(ctx, labInstance, labTemplate)
 	if retValue.shouldReturn {
-		return retValue.result, retValue.err
+		return r.ReturnWithCondition(ctx, labInstance, ltbv1alpha1.NetworkReadyCondition, retValue)
 	}
 
 	// Reconcile TTYD Service Account
@@ -88,7 +163,7 @@ func (r *LabInstanceReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	sa.Name = labInstance.Name + "-ttyd-svcacc"
 	retValue = r.ReconcileResource(labInstance, sa, nil, "")
 	if retValue.shouldReturn {
-		return retValue.result, retValue.err
+		return r.ReturnWithCondition(ctx, labInstance, ltbv1alpha1.RemoteAccessReadyCondition, retValue)
 	}
 
 	// Reconcile TTYD Role
@@ -96,7 +171,7 @@ func (r *LabInstanceReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	role.Name = labInstance.Name + "-ttyd-role"
 	retValue = r.ReconcileResource(labInstance, role, nil, "")
 	if retValue.shouldReturn {
-		return retValue.result, retValue.err
+		return r.ReturnWithCondition(ctx, labInstance, ltbv1alpha1.RemoteAccessReadyCondition, retValue)
 	}
 
 	// Reconcile TTYD Role Binding
@@ -104,7 +179,7 @@ func (r *LabInstanceReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	roleBinding.Name = labInstance.Name + "-ttyd-rolebind"
 	retValue = r.ReconcileResource(labInstance, roleBinding, nil, "")
 	if retValue.shouldReturn {
-		return retValue.result, retValue.err
+		return r.ReturnWithCondition(ctx, labInstance, ltbv1alpha1.RemoteAccessReadyCondition, retValue)
 	}
 
 	// Reconcile TTYD Service
@@ -112,7 +187,7 @@ func (r *LabInstanceReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	ttydService.Name = labInstance.Name + "-ttyd-service"
 	retValue = r.ReconcileResource(labInstance, ttydService, nil, "")
 	if retValue.shouldReturn {
-		return retValue.result, retValue.err
+		return r.ReturnWithCondition(ctx, labInstance, ltbv1alpha1.RemoteAccessReadyCondition, retValue)
 	}
 
 	// Reconcile TTYD Pod
@@ -120,34 +195,59 @@ func (r *LabInstanceReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	ttydPod.Name = labInstance.Name + "-ttyd-pod"
 	retValue = r.ReconcileResource(labInstance, ttydPod, nil, "")
 	if retValue.shouldReturn {
-		return retValue.result, retValue.err
+		return r.ReturnWithCondition(ctx, labInstance, ltbv1alpha1.RemoteAccessReadyCondition, retValue)
 	}
 
 	nodes := labTemplate.Spec.Nodes
 	pods := []*corev1.Pod{}
 	vms := []*kubevirtv1.VirtualMachine{}
+	nodeKinds := map[string]string{}
+	nodeStatuses := []ltbv1alpha1.NodeStatus{}
 	for _, node := range nodes {
 		nodeType := &ltbv1alpha1.NodeType{}
 		retValue = r.GetNodeType(ctx, &node.NodeTypeRef, nodeType)
 		if retValue.shouldReturn {
-			return retValue.result, retValue.err
+			SetNodeError(labInstance, node.Name, "", retValue.err)
+			return r.ReturnWithCondition(ctx, labInstance, ltbv1alpha1.NodesReadyCondition, retValue)
+		}
+		nodeKinds[node.Name] = nodeType.Spec.Kind
+		node.RenderedNodeSpec, retValue = GetRenderedNodeSpec(labTemplate, labInstance, &node)
+		if retValue.shouldReturn {
+			SetNodeError(labInstance, node.Name, nodeType.Spec.Kind, retValue.err)
+			return r.ReturnWithCondition(ctx, labInstance, ltbv1alpha1.NodesReadyCondition, retValue)
+		}
+		retValue = r.ReconcileNodeConfig(ctx, labInstance, &node, GetRenderedNode(labTemplate, node.Name).ConfigDelivery)
+		if retValue.shouldReturn {
+			SetNodeError(labInstance, node.Name, nodeType.Spec.Kind, retValue.err)
+			return r.ReturnWithCondition(ctx, labInstance, ltbv1alpha1.NodesReadyCondition, retValue)
 		}
+		node.Interfaces = GetNodeInterfaces(labTemplate, &node)
+		var nodeStatus ltbv1alpha1.NodeStatus
 		if nodeType.Spec.Kind == "vm" {
 			virtualMachine := &kubevirtv1.VirtualMachine{}
 			virtualMachine.Name = labInstance.Name + "-" + node.Name
 			retValue := r.ReconcileResource(labInstance, virtualMachine, &node, "")
 			if retValue.shouldReturn {
-				return retValue.result, retValue.err
+				SetNodeError(labInstance, node.Name, nodeType.Spec.Kind, retValue.err)
+				return r.ReturnWithCondition(ctx, labInstance, ltbv1alpha1.NodesReadyCondition, retValue)
 			}
 			vms = append(vms, virtualMachine)
+			virtualMachineInstance := &kubevirtv1.VirtualMachineInstance{}
+			err = r.Get(ctx, types.NamespacedName{Namespace: virtualMachine.Namespace, Name: virtualMachine.Name}, virtualMachineInstance)
+			if err != nil {
+				virtualMachineInstance = nil
+			}
+			nodeStatus = GetVMNodeStatus(node.Name, virtualMachine, virtualMachineInstance)
 		} else {
 			pod := &corev1.Pod{}
 			pod.Name = labInstance.Name + "-" + node.Name
 			retValue := r.ReconcileResource(labInstance, pod, &node, "")
 			if retValue.shouldReturn {
-				return retValue.result, retValue.err
+				SetNodeError(labInstance, node.Name, nodeType.Spec.Kind, retValue.err)
+				return r.ReturnWithCondition(ctx, labInstance, ltbv1alpha1.NodesReadyCondition, retValue)
 			}
 			pods = append(pods, pod)
+			nodeStatus = GetPodNodeStatus(node.Name, pod)
 		}
 
 		// Reconcile Remote Access Service
@@ -156,8 +256,9 @@ func (r *LabInstanceReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 			service.Name = labInstance.Name + "-" + node.Name + "-remote-access"
 			retValue = r.ReconcileResource(labInstance, service, &node, "")
 			if retValue.shouldReturn {
-				return retValue.result, retValue.err
+				return r.ReturnWithCondition(ctx, labInstance, ltbv1alpha1.RemoteAccessReadyCondition, retValue)
 			}
+			nodeStatus.RemoteAccess = GetRemoteAccessEndpoints(service)
 		}
 
 		// Reconcile Ingress
@@ -165,9 +266,16 @@ func (r *LabInstanceReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		ingress.Name = labInstance.Name + "-" + node.Name
 		retValue = r.ReconcileResource(labInstance, ingress, &node, nodeType.Spec.Kind)
 		if retValue.shouldReturn {
-			return retValue.result, retValue.err
+			return r.ReturnWithCondition(ctx, labInstance, ltbv1alpha1.RemoteAccessReadyCondition, retValue)
 		}
+		nodeStatus.WebTerminalURL = GetWebTerminalURL(ingress)
+		nodeStatuses = append(nodeStatuses, nodeStatus)
+	}
 
+	// Delete the resources of removed nodes, links and interfaces
+	retValue = r.PruneResources(ctx, labInstance, labTemplate, DesiredResources(labInstance, labTemplate, nodeKinds))
+	if retValue.shouldReturn {
+		return r.ReturnWithCondition(ctx, labInstance, ltbv1alpha1.NodesReadyCondition, retValue)
 	}
 
 	err = UpdateLabInstanceStatus(pods, vms, labInstance)
@@ -175,6 +283,11 @@ func (r *LabInstanceReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		log.Error(err, "Failed set new status for LabInstance")
 		return ctrl.Result{}, err
 	}
+	labInstance.Status.Links = GetLinkStatus(labInstance, labTemplate, nodeKinds)
+	labInstance.Status.Nodes = nodeStatuses
+	previousConditions := append([]metav1.Condition{}, labInstance.Status.Conditions...)
+	UpdateLabInstanceConditions(labInstance)
+	r.RecordConditionEvents(labInstance, previousConditions)
 
 	err = r.Status().Update(ctx, labInstance)
 	if err != nil {
@@ -185,65 +298,481 @@ func (r *LabInstanceReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	return ctrl.Result{}, nil
 }
 
-func (r *LabInstanceReconciler) ReconcileNetwork(ctx context.Context, labInstance *ltbv1alpha1.LabInstance) ReturnToReconciler {
+// GetWebTerminalURL returns the URL of the web terminal of a lab node, which is routed by its ingress.
+func GetWebTerminalURL(ingress *networkingv1.Ingress) string {
+	if len(ingress.Spec.Rules) == 0 || ingress.Spec.Rules[0].Host == "" {
+		return ""
+	}
+	return "https://" + ingress.Spec.Rules[0].Host + "/"
+}
+
+// GetRemoteAccessEndpoints returns the endpoints of the publicly exposed ports of a lab node, one per port and address of the load balancer.
+func GetRemoteAccessEndpoints(service *corev1.Service) []ltbv1alpha1.RemoteAccessEndpoint {
+	endpoints := []ltbv1alpha1.RemoteAccessEndpoint{}
+	for _, loadBalancerIngress := range service.Status.LoadBalancer.Ingress {
+		host := loadBalancerIngress.IP
+		if host == "" {
+			host = loadBalancerIngress.Hostname
+		}
+		if host == "" {
+			continue
+		}
+		for _, port := range service.Spec.Ports {
+			endpoints = append(endpoints, ltbv1alpha1.RemoteAccessEndpoint{Name: port.Name, Host: host, Port: port.Port, Protocol: port.Protocol})
+		}
+	}
+	return endpoints
+}
+
+// ReturnWithCondition records in the given condition, that a step of the reconciliation didn't complete, and returns to the reconciler.
+// An error additionally marks the lab instance as degraded. Failing to update the status is only logged, the result of the step is returned anyway.
+func (r *LabInstanceReconciler) ReturnWithCondition(ctx context.Context, labInstance *ltbv1alpha1.LabInstance, conditionType string, retValue ReturnToReconciler) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+	if retValue.err != nil {
+		SetCondition(labInstance, conditionType, metav1.ConditionFalse, "Failed", retValue.err.Error())
+		SetCondition(labInstance, ltbv1alpha1.DegradedCondition, metav1.ConditionTrue, conditionType+"Failed", retValue.err.Error())
+		recordEvent(r.Recorder, labInstance, corev1.EventTypeWarning, conditionType+"Failed", "%s", retValue.err.Error())
+	} else {
+		SetCondition(labInstance, conditionType, metav1.ConditionFalse, "Reconciling", "Waiting for resources to be created")
+	}
+	labInstance.Status.ObservedGeneration = labInstance.Generation
+	err := r.Status().Update(ctx, labInstance)
+	if err != nil {
+		log.Error(err, "Failed to update LabInstance status")
+	}
+	return retValue.result, retValue.err
+}
+
+// RecordConditionEvents records an event for the readiness transitions and the new failures of the nodes of a lab instance, compared to the previous conditions.
+// Nodes, which aren't ready yet after the lab instance was created, are no transition.
+func (r *LabInstanceReconciler) RecordConditionEvents(labInstance *ltbv1alpha1.LabInstance, previousConditions []metav1.Condition) {
+	previousNodesReady := meta.FindStatusCondition(previousConditions, ltbv1alpha1.NodesReadyCondition)
+	nodesReady := meta.FindStatusCondition(labInstance.Status.Conditions, ltbv1alpha1.NodesReadyCondition)
+	if nodesReady != nil && (previousNodesReady == nil || previousNodesReady.Status != nodesReady.Status) {
+		if nodesReady.Status == metav1.ConditionTrue {
+			recordEvent(r.Recorder, labInstance, corev1.EventTypeNormal, ReadyReason, "All lab nodes are ready")
+		} else if previousNodesReady != nil && previousNodesReady.Status == metav1.ConditionTrue {
+			recordEvent(r.Recorder, labInstance, corev1.EventTypeWarning, NotReadyReason, "%s", nodesReady.Message)
+		}
+	}
+	previousDegraded := meta.FindStatusCondition(previousConditions, ltbv1alpha1.DegradedCondition)
+	degraded := meta.FindStatusCondition(labInstance.Status.Conditions, ltbv1alpha1.DegradedCondition)
+	if degraded != nil && degraded.Status == metav1.ConditionTrue && degraded.Reason == NodesFailedReason && (previousDegraded == nil || previousDegraded.Reason != degraded.Reason || previousDegraded.Message != degraded.Message) {
+		recordEvent(r.Recorder, labInstance, corev1.EventTypeWarning, NodesFailedReason, "%s", degraded.Message)
+	}
+}
+
+// SetCondition sets a condition of a lab instance for its current generation.
+func SetCondition(labInstance *ltbv1alpha1.LabInstance, conditionType string, status metav1.ConditionStatus, reason string, message string) {
+	meta.SetStatusCondition(&labInstance.Status.Conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: labInstance.Generation,
+	})
+}
+
+// UpdateLabInstanceConditions sets the conditions of a lab instance, whose resources all exist, from the status of its nodes.
+func UpdateLabInstanceConditions(labInstance *ltbv1alpha1.LabInstance) {
+	SetCondition(labInstance, ltbv1alpha1.NetworkReadyCondition, metav1.ConditionTrue, "Reconciled", "")
+	SetCondition(labInstance, ltbv1alpha1.RemoteAccessReadyCondition, metav1.ConditionTrue, "Reconciled", "")
+	notReady := []string{}
+	failed := []string{}
+	for _, nodeStatus := range labInstance.Status.Nodes {
+		if !nodeStatus.Ready {
+			notReady = append(notReady, nodeStatus.Name)
+		}
+		if nodeStatus.LastError != "" {
+			failed = append(failed, nodeStatus.Name+": "+nodeStatus.LastError)
+		}
+	}
+	if len(notReady) > 0 {
+		SetCondition(labInstance, ltbv1alpha1.NodesReadyCondition, metav1.ConditionFalse, "NodesNotReady", "Not ready: "+strings.Join(notReady, ", "))
+	} else {
+		SetCondition(labInstance, ltbv1alpha1.NodesReadyCondition, metav1.ConditionTrue, "NodesReady", "")
+	}
+	if len(failed) > 0 {
+		SetCondition(labInstance, ltbv1alpha1.DegradedCondition, metav1.ConditionTrue, NodesFailedReason, strings.Join(failed, "; "))
+	} else {
+		SetCondition(labInstance, ltbv1alpha1.DegradedCondition, metav1.ConditionFalse, "Reconciled", "")
+	}
+	labInstance.Status.ObservedGeneration = labInstance.Generation
+}
+
+// SetNodeError records the error of a lab node in the status of the lab instance, nil errors are ignored.
+func SetNodeError(labInstance *ltbv1alpha1.LabInstance, nodeName string, kind string, err error) {
+	if err == nil {
+		return
+	}
+	for i := range labInstance.Status.Nodes {
+		if labInstance.Status.Nodes[i].Name == nodeName {
+			labInstance.Status.Nodes[i].LastError = err.Error()
+			return
+		}
+	}
+	labInstance.Status.Nodes = append(labInstance.Status.Nodes, ltbv1alpha1.NodeStatus{Name: nodeName, Kind: kind, LastError: err.Error()})
+}
+
+// GetPodNodeStatus returns the status of a lab node, which runs as pod.
+func GetPodNodeStatus(nodeName string, pod *corev1.Pod) ltbv1alpha1.NodeStatus {
+	nodeStatus := ltbv1alpha1.NodeStatus{
+		Name:     nodeName,
+		Kind:     "pod",
+		Phase:    string(pod.Status.Phase),
+		HostNode: pod.Spec.NodeName,
+	}
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady && condition.Status == corev1.ConditionTrue {
+			nodeStatus.Ready = true
+		}
+	}
+	for _, podIP := range pod.Status.PodIPs {
+		nodeStatus.IPs = append(nodeStatus.IPs, podIP.IP)
+	}
+	if pod.Status.Phase == corev1.PodFailed {
+		nodeStatus.LastError = strings.TrimSpace(pod.Status.Reason + " " + pod.Status.Message)
+	}
+	for _, containerStatus := range pod.Status.ContainerStatuses {
+		if waiting := containerStatus.State.Waiting; waiting != nil && waiting.Reason != "" && waiting.Reason != "ContainerCreating" && waiting.Reason != "PodInitializing" {
+			nodeStatus.LastError = strings.TrimSpace(fmt.Sprintf("Container %s: %s %s", containerStatus.Name, waiting.Reason, waiting.Message))
+		}
+	}
+	return nodeStatus
+}
+
+// GetVMNodeStatus returns the status of a lab node, which runs as VM. The addresses and the host are only known, while the VM instance exists.
+func GetVMNodeStatus(nodeName string, vm *kubevirtv1.VirtualMachine, vmi *kubevirtv1.VirtualMachineInstance) ltbv1alpha1.NodeStatus {
+	nodeStatus := ltbv1alpha1.NodeStatus{
+		Name:  nodeName,
+		Kind:  "vm",
+		Phase: string(vm.Status.PrintableStatus),
+		Ready: vm.Status.Ready,
+	}
+	switch vm.Status.PrintableStatus {
+	case kubevirtv1.VirtualMachineStatusCrashLoopBackOff, kubevirtv1.VirtualMachineStatusUnschedulable, kubevirtv1.VirtualMachineStatusErrImagePull,
+		kubevirtv1.VirtualMachineStatusImagePullBackOff, kubevirtv1.VirtualMachineStatusPvcNotFound, kubevirtv1.VirtualMachineStatusDataVolumeError:
+		nodeStatus.LastError = string(vm.Status.PrintableStatus)
+	}
+	if vmi != nil {
+		nodeStatus.HostNode = vmi.Status.NodeName
+		for _, vmiInterface := range vmi.Status.Interfaces {
+			nodeStatus.IPs = append(nodeStatus.IPs, vmiInterface.IPs...)
+		}
+	}
+	return nodeStatus
+}
+
+// ReconcileSubnet allocates a subnet for the lab instance and records it in the status of the lab instance.
+func (r *LabInstanceReconciler) ReconcileSubnet(ctx context.Context, labInstance *ltbv1alpha1.LabInstance) ReturnToReconciler {
 	log := log.FromContext(ctx)
 	retValue := ReturnToReconciler{shouldReturn: true, result: ctrl.Result{}, err: nil}
 	if labInstance == nil {
 		retValue.err = errors.NewBadRequest("labInstance is nil")
 		return retValue
 	}
-	podNetworkDefinitionName := labInstance.Name + "-pod"
-	vmNetworkDefinitionName := labInstance.Name + "-vm"
-	networkdefinitionNames := []string{podNetworkDefinitionName, vmNetworkDefinitionName}
-	for _, networkDefinitionName := range networkdefinitionNames {
-		foundNetworkAttachmentDefinition := &network.NetworkAttachmentDefinition{}
-		err := r.Get(ctx, types.NamespacedName{Name: networkDefinitionName, Namespace: labInstance.Namespace}, foundNetworkAttachmentDefinition)
-		if errors.IsNotFound(err) {
-			networkAttachmentDefinition := &network.NetworkAttachmentDefinition{}
-			networkAttachmentDefinition.Name = networkDefinitionName
-			networkAttachmentDefinition.Namespace = labInstance.Namespace
-			if networkDefinitionName == podNetworkDefinitionName {
-				// Don't change mode to "passthru" as it will takeover the kubernetes node interface and cause a network outage
-				networkAttachmentDefinition.Spec.Config = `{
-				"cniVersion": "0.3.1",
-				"name": "mynet",
-				"type": "bridge",
-				"bridge": "mynet0",
-				"ipam": {
-					"type": "host-local",
-					"ranges": [
-						[ {
-							"subnet": "10.10.0.0/24",
-							"rangeStart": "10.10.0.10",
-							"rangeEnd": "10.10.0.250"
-						} ]
-					]
+	owner := client.ObjectKeyFromObject(labInstance).String()
+	labInstance.Status.Network.Name = LabNetworkName(labInstance)
+	if r.SubnetAllocator == nil {
+		retValue.shouldReturn = false
+		return retValue
+	}
+	if labInstance.Status.Network.Subnet != "" {
+		err := r.SubnetAllocator.Reserve(owner, labInstance.Status.Network.Subnet)
+		if err != nil {
+			retValue.err = err
+			log.Error(err, "Failed to reserve subnet", "Subnet", labInstance.Status.Network.Subnet)
+			return retValue
+		}
+		retValue.shouldReturn = false
+		return retValue
+	}
+
+	// Allocations of other lab instances have to be known, in case the operator has been restarted
+	labInstances := &ltbv1alpha1.LabInstanceList{}
+	err := r.List(ctx, labInstances)
+	if err != nil {
+		retValue.err = err
+		log.Error(err, "Failed to list LabInstances")
+		return retValue
+	}
+	for _, otherLabInstance := range labInstances.Items {
+		if otherLabInstance.Status.Network.Subnet == "" {
+			continue
+		}
+		err = r.SubnetAllocator.Reserve(client.ObjectKeyFromObject(&otherLabInstance).String(), otherLabInstance.Status.Network.Subnet)
+		if err != nil {
+			log.Error(err, "Failed to reserve subnet of LabInstance", "LabInstance", otherLabInstance.Name)
+		}
+	}
+
+	subnet, err := r.SubnetAllocator.Allocate(owner)
+	if err != nil {
+		retValue.err = err
+		log.Error(err, "Failed to allocate subnet")
+		return retValue
+	}
+	labInstance.Status.Network.Subnet = subnet
+	log.Info("Allocated subnet for LabInstance", "Subnet", subnet)
+	err = r.Status().Update(ctx, labInstance)
+	if err != nil {
+		r.SubnetAllocator.Release(owner)
+		retValue.err = err
+		log.Error(err, "Failed to update LabInstance status")
+		return retValue
+	}
+	retValue.result = ctrl.Result{Requeue: true}
+	return retValue
+}
+
+func (r *LabInstanceReconciler) ReconcileNetwork(ctx context.Context, labInstance *ltbv1alpha1.LabInstance, labTemplate *ltbv1alpha1.LabTemplate) ReturnToReconciler {
+	log := log.FromContext(ctx)
+	retValue := ReturnToReconciler{shouldReturn: true, result: ctrl.Result{}, err: nil}
+	if labInstance == nil {
+		retValue.err = errors.NewBadRequest("labInstance is nil")
+		return retValue
+	}
+	if labTemplate == nil {
+		retValue.err = errors.NewBadRequest("labTemplate is nil")
+		return retValue
+	}
+	if err := ValidateLinks(labTemplate); err != nil {
+		retValue.err = err
+		log.Error(err, "Invalid links in LabTemplate", "LabTemplate", labTemplate.Name)
+		return retValue
+	}
+	if err := ValidateInterfaces(labTemplate); err != nil {
+		retValue.err = err
+		log.Error(err, "Invalid interfaces in LabTemplate", "LabTemplate", labTemplate.Name)
+		return retValue
+	}
+	backend, err := labnet.NewBackend(string(labInstance.Spec.NetworkBackend), r.NetworkConfig)
+	if err != nil {
+		retValue.err = errors.NewBadRequest(err.Error())
+		log.Error(err, "Invalid network backend")
+		return retValue
+	}
+	if backend.NeedsSegmentID() {
+		segmentsRetValue := r.ReconcileSegmentIDs(ctx, labInstance, labTemplate)
+		if segmentsRetValue.shouldReturn {
+			return segmentsRetValue
+		}
+	}
+	for _, node := range labTemplate.Spec.Nodes {
+		for _, nodeInterface := range GetNodeInterfaces(labTemplate, &node) {
+			networkDefinitionName := NetworkAttachmentDefinitionName(labInstance, node.Name, nodeInterface.Name)
+			foundNetworkAttachmentDefinition := &network.NetworkAttachmentDefinition{}
+			err := r.Get(ctx, types.NamespacedName{Name: networkDefinitionName, Namespace: labInstance.Namespace}, foundNetworkAttachmentDefinition)
+			if errors.IsNotFound(err) {
+				segment := InterfaceSegmentName(labInstance, labTemplate, node.Name, nodeInterface.Name)
+				networkAttachmentDefinition, err := CreateNetworkAttachmentDefinition(labInstance, node.Name, &nodeInterface, segment, backend)
+				if err != nil {
+					retValue.err = err
+					log.Error(err, "Failed to create NetworkAttachmentDefinition")
+					return retValue
+				}
+				ctrl.SetControllerReference(labInstance, networkAttachmentDefinition, r.Scheme)
+				r.ReportMissingResource(ctx, labInstance, networkAttachmentDefinition)
+				log.Info("Creating a new NetworkAttachmentDefinition", "NetworkAttachmentDefinition.Namespace", networkAttachmentDefinition.Namespace, "NetworkAttachmentDefinition.Name", networkAttachmentDefinition.Name)
+
+				err = r.Create(ctx, networkAttachmentDefinition)
+				if err != nil {
+					retValue.err = err
+					log.Error(err, "Failed to create NetworkAttachmentDefinition")
+					return retValue
+				}
+				recordEvent(r.Recorder, labInstance, corev1.EventTypeNormal, CreatedReason, "Created NetworkAttachmentDefinition %s", networkAttachmentDefinition.Name)
+				retValue.result = ctrl.Result{Requeue: true}
+				return retValue
+			}
+			if err != nil {
+				retValue.err = err
+				log.Error(err, "Failed to get NetworkAttachmentDefinition")
+				return retValue
+			}
+			segment := InterfaceSegmentName(labInstance, labTemplate, node.Name, nodeInterface.Name)
+			networkAttachmentDefinition, err := CreateNetworkAttachmentDefinition(labInstance, node.Name, &nodeInterface, segment, backend)
+			if err != nil {
+				retValue.err = err
+				log.Error(err, "Failed to create desired NetworkAttachmentDefinition")
+				return retValue
+			}
+			if updated, _ := MergeResource(foundNetworkAttachmentDefinition, networkAttachmentDefinition); updated {
+				log.Info("Updating NetworkAttachmentDefinition", "NetworkAttachmentDefinition.Namespace", foundNetworkAttachmentDefinition.Namespace, "NetworkAttachmentDefinition.Name", foundNetworkAttachmentDefinition.Name)
+				err = r.Update(ctx, foundNetworkAttachmentDefinition)
+				if err != nil {
+					retValue.err = err
+					log.Error(err, "Failed to update NetworkAttachmentDefinition")
+					return retValue
+				}
+				recordEvent(r.Recorder, labInstance, corev1.EventTypeNormal, UpdatedReason, "Updated NetworkAttachmentDefinition %s", foundNetworkAttachmentDefinition.Name)
+				// The network of an interface is only set up, when the pod of the node is started
+				if restartRetValue := r.RestartNode(ctx, labInstance, node.Name); restartRetValue.shouldReturn {
+					return restartRetValue
 				}
-			}`
-			} else {
-				networkAttachmentDefinition.Spec.Config = `{
-					"cniVersion": "0.3.1",
-					"name": "mynet",
-					"type": "bridge",
-					"bridge": "mynet0",
-					"ipam": {}
-				}`
-			}
-			ctrl.SetControllerReference(labInstance, networkAttachmentDefinition, r.Scheme)
-			log.Info("Creating a new NetworkAttachmentDefinition", "NetworkAttachmentDefinition.Namespace", networkAttachmentDefinition.Namespace, "NetworkAttachmentDefinition.Name", networkAttachmentDefinition.Name)
-
-			err = r.Create(ctx, networkAttachmentDefinition)
+			}
+		}
+	}
+	retValue.shouldReturn = false
+	return retValue
+}
+
+// RestartNode deletes the pod of a lab node or the VirtualMachineInstance of a VM, so it is started again with its current network attachments.
+// The pod is created again by one of the next reconciliations, the VirtualMachineInstance by KubeVirt.
+// With the manual rollout policy, the node keeps running until it is deleted by the user.
+func (r *LabInstanceReconciler) RestartNode(ctx context.Context, labInstance *ltbv1alpha1.LabInstance, nodeName string) ReturnToReconciler {
+	log := log.FromContext(ctx)
+	retValue := ReturnToReconciler{shouldReturn: true, result: ctrl.Result{RequeueAfter: resourceRecreateInterval}, err: nil}
+	if labInstance.Spec.RolloutPolicy == ltbv1alpha1.ManualRolloutPolicy {
+		retValue.shouldReturn = false
+		retValue.result = ctrl.Result{}
+		return retValue
+	}
+	name := labInstance.Name + "-" + nodeName
+	for _, resource := range []client.Object{&corev1.Pod{}, &kubevirtv1.VirtualMachineInstance{}} {
+		resource.SetNamespace(labInstance.Namespace)
+		resource.SetName(name)
+		err := r.Delete(ctx, resource, client.PropagationPolicy(metav1.DeletePropagationBackground))
+		if errors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			retValue.err = err
+			log.Error(err, "Failed to delete resource", "resource.Namespace", labInstance.Namespace, "resource.Name", name)
+			return retValue
+		}
+		log.Info("Recreating resource, its network changed", "resource.Namespace", labInstance.Namespace, "resource.Name", name)
+		recordEvent(r.Recorder, labInstance, corev1.EventTypeNormal, RecreatingReason, "Recreating %s %s, its network changed", reflect.TypeOf(resource).Elem().Name(), name)
+	}
+	return retValue
+}
+
+// ReconcileSegmentIDs allocates an ID for every segment of the lab instance and records them in the status of the lab instance.
+func (r *LabInstanceReconciler) ReconcileSegmentIDs(ctx context.Context, labInstance *ltbv1alpha1.LabInstance, labTemplate *ltbv1alpha1.LabTemplate) ReturnToReconciler {
+	log := log.FromContext(ctx)
+	retValue := ReturnToReconciler{shouldReturn: true, result: ctrl.Result{}, err: nil}
+	if r.SegmentAllocator == nil {
+		retValue.err = errors.NewBadRequest("network backend " + string(labInstance.Spec.NetworkBackend) + " needs segment IDs, but no segment allocator is configured")
+		log.Error(retValue.err, "Failed to allocate segment IDs")
+		return retValue
+	}
+	owner := client.ObjectKeyFromObject(labInstance).String()
+	missingSegments := []string{}
+	for _, node := range labTemplate.Spec.Nodes {
+		for _, nodeInterface := range GetNodeInterfaces(labTemplate, &node) {
+			segment := InterfaceSegmentName(labInstance, labTemplate, node.Name, nodeInterface.Name)
+			id, ok := labInstance.Status.Network.Segments[segment]
+			if !ok {
+				missingSegments = append(missingSegments, segment)
+				continue
+			}
+			err := r.SegmentAllocator.Reserve(owner, segment, id)
 			if err != nil {
 				retValue.err = err
-				log.Error(err, "Failed to create NetworkAttachmentDefinition")
+				log.Error(err, "Failed to reserve segment ID", "Segment", segment, "ID", id)
 				return retValue
 			}
-			retValue.result = ctrl.Result{Requeue: true}
+		}
+	}
+	if len(missingSegments) == 0 {
+		retValue.shouldReturn = false
+		return retValue
+	}
+
+	// Allocations of other lab instances have to be known, in case the operator has been restarted
+	labInstances := &ltbv1alpha1.LabInstanceList{}
+	err := r.List(ctx, labInstances)
+	if err != nil {
+		retValue.err = err
+		log.Error(err, "Failed to list LabInstances")
+		return retValue
+	}
+	for _, otherLabInstance := range labInstances.Items {
+		for segment, id := range otherLabInstance.Status.Network.Segments {
+			err = r.SegmentAllocator.Reserve(client.ObjectKeyFromObject(&otherLabInstance).String(), segment, id)
+			if err != nil {
+				log.Error(err, "Failed to reserve segment ID of LabInstance", "LabInstance", otherLabInstance.Name)
+			}
+		}
+	}
+
+	if labInstance.Status.Network.Segments == nil {
+		labInstance.Status.Network.Segments = map[string]int32{}
+	}
+	for _, segment := range missingSegments {
+		id, err := r.SegmentAllocator.Allocate(owner, segment)
+		if err != nil {
+			retValue.err = err
+			log.Error(err, "Failed to allocate segment ID", "Segment", segment)
+			return retValue
+		}
+		labInstance.Status.Network.Segments[segment] = id
+	}
+	log.Info("Allocated segment IDs for LabInstance", "Segments", labInstance.Status.Network.Segments)
+	err = r.Status().Update(ctx, labInstance)
+	if err != nil {
+		retValue.err = err
+		log.Error(err, "Failed to update LabInstance status")
+		return retValue
+	}
+	retValue.result = ctrl.Result{Requeue: true}
+	return retValue
+}
+
+// ReconcileLinkConfig stores the state and the netem arguments of the link interfaces of every node in a ConfigMap and keeps it up to date,
+// so the link sidecars of the pods can apply changes without redeploying the lab.
+func (r *LabInstanceReconciler) ReconcileLinkConfig(ctx context.Context, labInstance *ltbv1alpha1.LabInstance, labTemplate *ltbv1alpha1.LabTemplate) ReturnToReconciler {
+	log := log.FromContext(ctx)
+	retValue := ReturnToReconciler{shouldReturn: true, result: ctrl.Result{}, err: nil}
+	if labInstance == nil {
+		retValue.err = errors.NewBadRequest("labInstance is nil")
+		return retValue
+	}
+	if labTemplate == nil {
+		retValue.err = errors.NewBadRequest("labTemplate is nil")
+		return retValue
+	}
+	packetCaptures := &ltbv1alpha1.PacketCaptureList{}
+	err := r.List(ctx, packetCaptures, client.InNamespace(labInstance.Namespace))
+	if err != nil {
+		retValue.err = err
+		log.Error(err, "Failed to list PacketCaptures")
+		return retValue
+	}
+	configMap, err := CreateLinkConfigMap(labInstance, labTemplate, packetCaptures.Items)
+	if err != nil {
+		retValue.err = err
+		log.Error(err, "Invalid link configuration")
+		return retValue
+	}
+	foundConfigMap := &corev1.ConfigMap{}
+	err = r.Get(ctx, types.NamespacedName{Name: configMap.Name, Namespace: configMap.Namespace}, foundConfigMap)
+	if errors.IsNotFound(err) {
+		ctrl.SetControllerReference(labInstance, configMap, r.Scheme)
+		r.ReportMissingResource(ctx, labInstance, configMap)
+		log.Info("Creating a new ConfigMap", "ConfigMap.Namespace", configMap.Namespace, "ConfigMap.Name", configMap.Name)
+		err = r.Create(ctx, configMap)
+		if err != nil {
+			retValue.err = err
+			log.Error(err, "Failed to create ConfigMap")
 			return retValue
 		}
+		recordEvent(r.Recorder, labInstance, corev1.EventTypeNormal, CreatedReason, "Created ConfigMap %s", configMap.Name)
+		retValue.result = ctrl.Result{Requeue: true}
+		return retValue
+	}
+	if err != nil {
+		retValue.err = err
+		log.Error(err, "Failed to get ConfigMap")
+		return retValue
+	}
+	if !reflect.DeepEqual(foundConfigMap.Data, configMap.Data) {
+		foundConfigMap.Data = configMap.Data
+		log.Info("Updating link configuration", "ConfigMap.Namespace", foundConfigMap.Namespace, "ConfigMap.Name", foundConfigMap.Name)
+		err = r.Update(ctx, foundConfigMap)
 		if err != nil {
 			retValue.err = err
-			log.Error(err, "Failed to get NetworkAttachmentDefinition")
+			log.Error(err, "Failed to update ConfigMap")
 			return retValue
 		}
 	}
@@ -251,6 +780,348 @@ func (r *LabInstanceReconciler) ReconcileNetwork(ctx context.Context, labInstanc
 	return retValue
 }
 
+// LinkConfigMapName returns the name of the ConfigMap, which holds the state and the impairments of the links of a lab instance.
+func LinkConfigMapName(labInstance *ltbv1alpha1.LabInstance) string {
+	return labInstance.Name + "-links"
+}
+
+// GetLinkImpairment returns the impairment of a link, an impairment of the lab instance replaces the one of the lab template.
+func GetLinkImpairment(labInstance *ltbv1alpha1.LabInstance, link *ltbv1alpha1.Link) *ltbv1alpha1.Impairment {
+	for _, linkImpairment := range labInstance.Spec.LinkImpairments {
+		for _, endpoint := range link.Endpoints {
+			if endpoint == linkImpairment.Endpoint {
+				impairment := linkImpairment.Impairment
+				return &impairment
+			}
+		}
+	}
+	return link.Impairment
+}
+
+// IsLinkDown reports whether a link is administratively down in the lab instance.
+func IsLinkDown(labInstance *ltbv1alpha1.LabInstance, link *ltbv1alpha1.Link) bool {
+	for _, downLink := range labInstance.Spec.DownLinks {
+		for _, endpoint := range link.Endpoints {
+			if endpoint == downLink {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// CreateLinkConfigMap returns a ConfigMap with a key per node, which lists the link interfaces of the node with their state and netem arguments.
+// As netem only delays outgoing packets, the impairment is applied to both interfaces of a link by the link sidecars of the pods.
+// VMs don't get the sidecar, so their lines are unused and impairments of links to VMs are rejected by ValidateVMLinks.
+// Additionally, it lists the capture containers of every node, which are allowed to run.
+func CreateLinkConfigMap(labInstance *ltbv1alpha1.LabInstance, labTemplate *ltbv1alpha1.LabTemplate, packetCaptures []ltbv1alpha1.PacketCapture) (*corev1.ConfigMap, error) {
+	referencedEndpoints := append([]ltbv1alpha1.LinkEndpoint{}, labInstance.Spec.DownLinks...)
+	for _, linkImpairment := range labInstance.Spec.LinkImpairments {
+		referencedEndpoints = append(referencedEndpoints, linkImpairment.Endpoint)
+	}
+	for _, endpoint := range referencedEndpoints {
+		if !hasLinkEndpoint(labTemplate, endpoint) {
+			return nil, errors.NewBadRequest(fmt.Sprintf("lab instance references interface %s of node %s, which is not part of a link", endpoint.Interface, endpoint.Node))
+		}
+	}
+	configMap := &corev1.ConfigMap{}
+	configMap.Name = LinkConfigMapName(labInstance)
+	configMap.Namespace = labInstance.Namespace
+	configMap.Data = map[string]string{}
+	for _, link := range labTemplate.Spec.Links {
+		arguments, err := labnet.NetemArguments(GetLinkImpairment(labInstance, &link))
+		if err != nil {
+			return nil, errors.NewBadRequest(err.Error())
+		}
+		up := !IsLinkDown(labInstance, &link)
+		for _, endpoint := range link.Endpoints {
+			configMap.Data[endpoint.Node] += labnet.LinkConfigLine(endpoint.Interface, up, arguments)
+		}
+	}
+	for _, packetCapture := range packetCaptures {
+		if packetCapture.Spec.LabInstanceRef != labInstance.Name || !packetCapture.DeletionTimestamp.IsZero() {
+			continue
+		}
+		configMap.Data[labnet.CaptureListKey(packetCapture.Spec.Node)] += labnet.CaptureContainerName(string(packetCapture.UID)) + "\n"
+	}
+	return configMap, nil
+}
+
+// GetLinkStatus returns the state of every link of the lab template and the state and the impairment of each of its interfaces.
+// Only the interfaces of pods are set down and impaired, because VMs don't get the link sidecar. A link is down, once one of its interfaces is down.
+func GetLinkStatus(labInstance *ltbv1alpha1.LabInstance, labTemplate *ltbv1alpha1.LabTemplate, nodeKinds map[string]string) []ltbv1alpha1.LinkStatus {
+	linkStatus := []ltbv1alpha1.LinkStatus{}
+	for _, link := range labTemplate.Spec.Links {
+		status := ltbv1alpha1.LinkStatus{Name: link.Name, Endpoints: link.Endpoints, State: ltbv1alpha1.LinkStateUp}
+		down := IsLinkDown(labInstance, &link)
+		impaired := isImpaired(GetLinkImpairment(labInstance, &link))
+		messages := []string{}
+		for _, endpoint := range link.Endpoints {
+			vm := nodeKinds[endpoint.Node] == "vm"
+			interfaceStatus := ltbv1alpha1.LinkInterfaceStatus{LinkEndpoint: endpoint, State: ltbv1alpha1.LinkStateUp, Impaired: impaired && !vm}
+			if down && vm {
+				messages = append(messages, fmt.Sprintf("Interface %s of VM %s stays up", endpoint.Interface, endpoint.Node))
+			} else if down {
+				interfaceStatus.State = ltbv1alpha1.LinkStateDown
+				status.State = ltbv1alpha1.LinkStateDown
+			}
+			if impaired && vm {
+				messages = append(messages, fmt.Sprintf("The impairment isn't applied to interface %s of VM %s", endpoint.Interface, endpoint.Node))
+			}
+			status.Interfaces = append(status.Interfaces, interfaceStatus)
+		}
+		status.Message = strings.Join(messages, ". ")
+		linkStatus = append(linkStatus, status)
+	}
+	return linkStatus
+}
+
+// isImpaired reports whether an impairment changes the packets of a link.
+func isImpaired(impairment *ltbv1alpha1.Impairment) bool {
+	return impairment != nil && *impairment != ltbv1alpha1.Impairment{}
+}
+
+// linkVMNode returns the name of a VM connected by a link, or an empty string, if the link only connects pods or nodes of unknown kind.
+func linkVMNode(link *ltbv1alpha1.Link, nodeKinds map[string]string) string {
+	for _, endpoint := range link.Endpoints {
+		if nodeKinds[endpoint.Node] == "vm" {
+			return endpoint.Node
+		}
+	}
+	return ""
+}
+
+// ValidateVMLinks checks, that no link to a VM is impaired by the lab template or, if it is given, impaired or set down by the lab instance.
+// The state and the impairments are applied by the link sidecars of the pods, KubeVirt can neither set the interface of a VM down nor impair it,
+// so the VM would keep its interface up or the link would only be impaired in one direction.
+// The node kinds map the names of the nodes to the kinds of their node types, nodes of unknown kind are skipped.
+func ValidateVMLinks(labInstance *ltbv1alpha1.LabInstance, labTemplate *ltbv1alpha1.LabTemplate, nodeKinds map[string]string) error {
+	for i := range labTemplate.Spec.Links {
+		link := &labTemplate.Spec.Links[i]
+		vmNode := linkVMNode(link, nodeKinds)
+		if vmNode == "" {
+			continue
+		}
+		if labInstance == nil {
+			if isImpaired(link.Impairment) {
+				return errors.NewBadRequest(fmt.Sprintf("the link of VM %s can't be impaired, only the interfaces of pods are impaired", vmNode))
+			}
+			continue
+		}
+		if IsLinkDown(labInstance, link) {
+			return errors.NewBadRequest(fmt.Sprintf("the link of VM %s can't be set down, only the interfaces of pods are set down", vmNode))
+		}
+		for _, linkImpairment := range labInstance.Spec.LinkImpairments {
+			if hasEndpoint(link, linkImpairment.Endpoint) && isImpaired(&linkImpairment.Impairment) {
+				return errors.NewBadRequest(fmt.Sprintf("the link of VM %s can't be impaired, only the interfaces of pods are impaired", vmNode))
+			}
+		}
+	}
+	return nil
+}
+
+// hasEndpoint reports whether an interface is one of the endpoints of a link.
+func hasEndpoint(link *ltbv1alpha1.Link, linkEndpoint ltbv1alpha1.LinkEndpoint) bool {
+	for _, endpoint := range link.Endpoints {
+		if endpoint == linkEndpoint {
+			return true
+		}
+	}
+	return false
+}
+
+// GetRenderedNodeKinds returns the kinds of the rendered nodes of a lab template by node name.
+func GetRenderedNodeKinds(labTemplate *ltbv1alpha1.LabTemplate) map[string]string {
+	nodeKinds := map[string]string{}
+	for _, renderedNode := range labTemplate.Status.Nodes {
+		nodeKinds[renderedNode.Name] = renderedNode.Kind
+	}
+	return nodeKinds
+}
+
+func hasLinkEndpoint(labTemplate *ltbv1alpha1.LabTemplate, linkEndpoint ltbv1alpha1.LinkEndpoint) bool {
+	for _, link := range labTemplate.Spec.Links {
+		for _, endpoint := range link.Endpoints {
+			if endpoint == linkEndpoint {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ValidateLinks checks that every link connects two interfaces of existing lab nodes and that no interface is used by more than one link.
+// The interfaces of the lab nodes also have to get distinct resource names, as these are derived from the sanitized names of the nodes and interfaces.
+func ValidateLinks(labTemplate *ltbv1alpha1.LabTemplate) error {
+	nodeNames := map[string]bool{}
+	for _, node := range labTemplate.Spec.Nodes {
+		nodeNames[node.Name] = true
+	}
+	usedInterfaces := map[string]bool{}
+	for i, link := range labTemplate.Spec.Links {
+		if len(link.Endpoints) != 2 {
+			return errors.NewBadRequest(fmt.Sprintf("link %d must have exactly two endpoints", i))
+		}
+		for _, endpoint := range link.Endpoints {
+			if !nodeNames[endpoint.Node] {
+				return errors.NewBadRequest(fmt.Sprintf("link %d references unknown node %s", i, endpoint.Node))
+			}
+			if endpoint.Interface == "" {
+				return errors.NewBadRequest(fmt.Sprintf("link %d has no interface for node %s", i, endpoint.Node))
+			}
+			key := endpoint.Node + ":" + endpoint.Interface
+			if usedInterfaces[key] {
+				return errors.NewBadRequest(fmt.Sprintf("interface %s is used by more than one link", key))
+			}
+			usedInterfaces[key] = true
+		}
+	}
+	return validateInterfaceResourceNames(labTemplate)
+}
+
+// validateInterfaceResourceNames checks that no two interfaces, e.g. eth1/1 and eth1-1, share the name of their NetworkAttachmentDefinition or VM network.
+func validateInterfaceResourceNames(labTemplate *ltbv1alpha1.LabTemplate) error {
+	resourceNames := map[string]string{}
+	for i := range labTemplate.Spec.Nodes {
+		node := &labTemplate.Spec.Nodes[i]
+		for _, nodeInterface := range GetNodeInterfaces(labTemplate, node) {
+			if sanitizeName(nodeInterface.Name) == "" {
+				return errors.NewBadRequest(fmt.Sprintf("interface %q of node %s has no characters, which can be used in a resource name", nodeInterface.Name, node.Name))
+			}
+			key := node.Name + ":" + nodeInterface.Name
+			resourceName := node.Name + "-" + sanitizeName(nodeInterface.Name)
+			if other, ok := resourceNames[resourceName]; ok {
+				return errors.NewBadRequest(fmt.Sprintf("interfaces %s and %s have the same resource name %s", other, key, resourceName))
+			}
+			resourceNames[resourceName] = key
+		}
+	}
+	return nil
+}
+
+// ValidateInterfaces checks that every declared interface has a unique name within its node and valid addresses in CIDR notation.
+func ValidateInterfaces(labTemplate *ltbv1alpha1.LabTemplate) error {
+	for _, node := range labTemplate.Spec.Nodes {
+		interfaceNames := map[string]bool{}
+		for _, nodeInterface := range node.Interfaces {
+			if nodeInterface.Name == "" {
+				return errors.NewBadRequest(fmt.Sprintf("interface of node %s has no name", node.Name))
+			}
+			if interfaceNames[nodeInterface.Name] {
+				return errors.NewBadRequest(fmt.Sprintf("interface %s of node %s is declared more than once", nodeInterface.Name, node.Name))
+			}
+			interfaceNames[nodeInterface.Name] = true
+			if nodeInterface.IPv4 != "" {
+				ip, _, err := net.ParseCIDR(nodeInterface.IPv4)
+				if err != nil || ip.To4() == nil {
+					return errors.NewBadRequest(fmt.Sprintf("interface %s of node %s has an invalid IPv4 address %s", nodeInterface.Name, node.Name, nodeInterface.IPv4))
+				}
+			}
+			if nodeInterface.IPv6 != "" {
+				ip, _, err := net.ParseCIDR(nodeInterface.IPv6)
+				if err != nil || ip.To4() != nil {
+					return errors.NewBadRequest(fmt.Sprintf("interface %s of node %s has an invalid IPv6 address %s", nodeInterface.Name, node.Name, nodeInterface.IPv6))
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// GetNodeInterfaces returns the interfaces of a node in the order they are attached to the node.
+// The declared interfaces of the node come first, followed by the interfaces that are only referenced by a link in the order of the links.
+func GetNodeInterfaces(labTemplate *ltbv1alpha1.LabTemplate, node *ltbv1alpha1.LabInstanceNodes) []ltbv1alpha1.NodeInterface {
+	interfaces := []ltbv1alpha1.NodeInterface{}
+	if labTemplate == nil || node == nil {
+		return interfaces
+	}
+	declaredInterfaces := map[string]bool{}
+	for _, declaredInterface := range node.Interfaces {
+		declaredInterfaces[declaredInterface.Name] = true
+		interfaces = append(interfaces, declaredInterface)
+	}
+	for _, link := range labTemplate.Spec.Links {
+		for _, endpoint := range link.Endpoints {
+			if endpoint.Node != node.Name || declaredInterfaces[endpoint.Interface] {
+				continue
+			}
+			declaredInterfaces[endpoint.Interface] = true
+			interfaces = append(interfaces, ltbv1alpha1.NodeInterface{Name: endpoint.Interface})
+		}
+	}
+	return interfaces
+}
+
+// NetworkAttachmentDefinitionName returns the name of the NetworkAttachmentDefinition, which attaches an interface of a node to its link.
+func NetworkAttachmentDefinitionName(labInstance *ltbv1alpha1.LabInstance, nodeName string, interfaceName string) string {
+	return labInstance.Name + "-" + nodeName + "-" + sanitizeName(interfaceName)
+}
+
+// LabNetworkName returns the name of the network shared by all links of a lab instance.
+func LabNetworkName(labInstance *ltbv1alpha1.LabInstance) string {
+	return segmentName(labInstance, nil)
+}
+
+// LinkSegmentName returns the name of the layer 2 segment of a link, e.g. the name of its linux bridge.
+func LinkSegmentName(labInstance *ltbv1alpha1.LabInstance, link *ltbv1alpha1.Link) string {
+	endpoints := []string{}
+	for _, endpoint := range link.Endpoints {
+		endpoints = append(endpoints, endpoint.Node+":"+endpoint.Interface)
+	}
+	return segmentName(labInstance, endpoints)
+}
+
+// InterfaceSegmentName returns the name of the layer 2 segment an interface of a node is attached to.
+// Interfaces which are not part of a link get a segment of their own.
+func InterfaceSegmentName(labInstance *ltbv1alpha1.LabInstance, labTemplate *ltbv1alpha1.LabTemplate, nodeName string, interfaceName string) string {
+	for _, link := range labTemplate.Spec.Links {
+		for _, endpoint := range link.Endpoints {
+			if endpoint.Node == nodeName && endpoint.Interface == interfaceName {
+				return LinkSegmentName(labInstance, &link)
+			}
+		}
+	}
+	return segmentName(labInstance, []string{nodeName + ":" + interfaceName})
+}
+
+// segmentName derives the segment name from a hash of the endpoints, because linux limits interface names to 15 characters.
+func segmentName(labInstance *ltbv1alpha1.LabInstance, endpoints []string) string {
+	sort.Strings(endpoints)
+	hash := sha256.Sum256([]byte(labInstance.Namespace + "/" + labInstance.Name + "/" + strings.Join(endpoints, "-")))
+	return "ltb" + hex.EncodeToString(hash[:])[:12]
+}
+
+// CreateNetworkAttachmentDefinition creates the NetworkAttachmentDefinition, which attaches an interface of a node to a segment with the given network backend.
+func CreateNetworkAttachmentDefinition(labInstance *ltbv1alpha1.LabInstance, nodeName string, nodeInterface *ltbv1alpha1.NodeInterface, segment string, backend labnet.Backend) (*network.NetworkAttachmentDefinition, error) {
+	networkAttachmentDefinition := &network.NetworkAttachmentDefinition{}
+	networkAttachmentDefinition.Name = NetworkAttachmentDefinitionName(labInstance, nodeName, nodeInterface.Name)
+	networkAttachmentDefinition.Namespace = labInstance.Namespace
+	attachment := &labnet.Attachment{
+		Namespace:   networkAttachmentDefinition.Namespace,
+		Name:        networkAttachmentDefinition.Name,
+		NetworkName: LabNetworkName(labInstance),
+		Segment:     segment,
+		SegmentID:   labInstance.Status.Network.Segments[segment],
+		Subnet:      labInstance.Status.Network.Subnet,
+	}
+	for _, address := range []string{nodeInterface.IPv4, nodeInterface.IPv6} {
+		if address != "" {
+			attachment.Addresses = append(attachment.Addresses, address)
+		}
+	}
+	config, err := backend.NetworkConfig(attachment)
+	if err != nil {
+		return nil, err
+	}
+	networkAttachmentDefinition.Spec.Config = config
+	return networkAttachmentDefinition, nil
+}
+
+// sanitizeName converts an interface name like Ethernet1/1 into a string, which can be used as part of a Kubernetes resource name.
+func sanitizeName(name string) string {
+	return strings.Trim(invalidNameCharacters.ReplaceAllString(strings.ToLower(name), "-"), "-")
+}
+
 func (r *LabInstanceReconciler) ReconcileResource(labInstance *ltbv1alpha1.LabInstance, resource client.Object, node *ltbv1alpha1.LabInstanceNodes, nodeKind string) ReturnToReconciler {
 	ctx := context.Context(context.Background())
 	log := log.FromContext(ctx)
@@ -270,6 +1141,7 @@ func (r *LabInstanceReconciler) ReconcileResource(labInstance *ltbv1alpha1.LabIn
 		}
 		log.Info("Creating a new resource", "resource.Namespace", labInstance.Namespace, "resource.Name", reflect.ValueOf(resource).Elem().FieldByName("Name"))
 		ctrl.SetControllerReference(labInstance, resource, r.Scheme)
+		r.ReportMissingResource(ctx, labInstance, resource)
 
 		err = r.Create(ctx, resource)
 		if err != nil {
@@ -277,6 +1149,7 @@ func (r *LabInstanceReconciler) ReconcileResource(labInstance *ltbv1alpha1.LabIn
 			log.Error(err, "Failed to create new resource", "resource.Namespace", labInstance.Namespace, "resource.Name", reflect.TypeOf(resource).Elem().Name())
 			return retValue
 		}
+		recordEvent(r.Recorder, labInstance, corev1.EventTypeNormal, CreatedReason, "Created %s %s", reflect.TypeOf(resource).Elem().Name(), resource.GetName())
 		retValue.result = ctrl.Result{Requeue: true}
 		return retValue
 	} else if err != nil {
@@ -284,10 +1157,182 @@ func (r *LabInstanceReconciler) ReconcileResource(labInstance *ltbv1alpha1.LabIn
 		log.Error(err, "Failed to get resource")
 		return retValue
 	}
+	return r.UpdateResource(ctx, labInstance, resource, node, nodeKind)
+}
+
+// ReportMissingResource shows a missing resource in the status of a lab instance, which has already been deployed, while the resource is created again.
+// Failing to update the status is only logged, as the resource is created anyway.
+func (r *LabInstanceReconciler) ReportMissingResource(ctx context.Context, labInstance *ltbv1alpha1.LabInstance, resource client.Object) {
+	log := log.FromContext(ctx)
+	if labInstance.Status.Status == "" {
+		return
+	}
+	labInstance.Status.Status = fmt.Sprintf("Missing %s %s", reflect.TypeOf(resource).Elem().Name(), resource.GetName())
+	recordEvent(r.Recorder, labInstance, corev1.EventTypeWarning, ResourceMissingReason, "%s was deleted and is created again", resource.GetName())
+	err := r.Status().Update(ctx, labInstance)
+	if err != nil {
+		log.Error(err, "Failed to update LabInstance status")
+	}
+}
+
+// UpdateResource compares an existing resource with its desired state and updates the mutable fields in place.
+// A resource, whose immutable fields differ, is deleted and created again by one of the next reconciliations.
+func (r *LabInstanceReconciler) UpdateResource(ctx context.Context, labInstance *ltbv1alpha1.LabInstance, resource client.Object, node *ltbv1alpha1.LabInstanceNodes, nodeKind string) ReturnToReconciler {
+	log := log.FromContext(ctx)
+	retValue := ReturnToReconciler{shouldReturn: true, result: ctrl.Result{}, err: nil}
+	if !resource.GetDeletionTimestamp().IsZero() {
+		log.Info("Waiting for resource to be deleted", "resource.Namespace", resource.GetNamespace(), "resource.Name", resource.GetName())
+		retValue.result = ctrl.Result{RequeueAfter: resourceRecreateInterval}
+		return retValue
+	}
+	// With the manual rollout policy, a pod or VM is only created again with the current spec after it was deleted
+	if labInstance.Spec.RolloutPolicy == ltbv1alpha1.ManualRolloutPolicy {
+		switch resource.(type) {
+		case *corev1.Pod, *kubevirtv1.VirtualMachine:
+			retValue.shouldReturn = false
+			return retValue
+		}
+	}
+	desired, err := CreateResource(labInstance, node, resource, nodeKind)
+	if err != nil {
+		retValue.err = err
+		log.Error(err, "Failed to create desired resource", "resource.Namespace", resource.GetNamespace(), "resource.Name", resource.GetName())
+		return retValue
+	}
+	updated, recreate := MergeResource(resource, desired)
+	if recreate {
+		log.Info("Recreating resource, immutable fields changed", "resource.Namespace", resource.GetNamespace(), "resource.Name", resource.GetName())
+		err = r.Delete(ctx, resource, client.PropagationPolicy(metav1.DeletePropagationBackground))
+		if err != nil && !errors.IsNotFound(err) {
+			retValue.err = err
+			log.Error(err, "Failed to delete resource", "resource.Namespace", resource.GetNamespace(), "resource.Name", resource.GetName())
+			return retValue
+		}
+		recordEvent(r.Recorder, labInstance, corev1.EventTypeNormal, RecreatingReason, "Recreating %s %s, its spec changed", reflect.TypeOf(resource).Elem().Name(), resource.GetName())
+		retValue.result = ctrl.Result{RequeueAfter: resourceRecreateInterval}
+		return retValue
+	}
+	if updated {
+		log.Info("Updating resource", "resource.Namespace", resource.GetNamespace(), "resource.Name", resource.GetName())
+		err = r.Update(ctx, resource)
+		if err != nil {
+			retValue.err = err
+			log.Error(err, "Failed to update resource", "resource.Namespace", resource.GetNamespace(), "resource.Name", resource.GetName())
+			return retValue
+		}
+		recordEvent(r.Recorder, labInstance, corev1.EventTypeNormal, UpdatedReason, "Updated %s %s", reflect.TypeOf(resource).Elem().Name(), resource.GetName())
+	}
 	retValue.shouldReturn = false
 	return retValue
 }
 
+// MergeResource copies the desired state into the mutable fields of an existing resource and reports whether the resource changed.
+// Fields, which are not set in the desired state, are ignored, so defaults of the API server don't count as drift.
+// It reports that the resource has to be recreated, if an immutable field differs, like the spec of a pod.
+func MergeResource(resource client.Object, desired client.Object) (updated bool, recreate bool) {
+	// The interfaces of a pod are only attached when it is started
+	if desired.GetAnnotations()[network.NetworkAttachmentAnnot] != resource.GetAnnotations()[network.NetworkAttachmentAnnot] {
+		if _, ok := resource.(*corev1.Pod); ok {
+			return false, true
+		}
+	}
+	updated = mergeMetadata(resource, desired)
+	switch actual := resource.(type) {
+	case *corev1.Pod:
+		if !equality.Semantic.DeepDerivative(desired.(*corev1.Pod).Spec, actual.Spec) {
+			return updated, true
+		}
+	case *kubevirtv1.VirtualMachine:
+		desiredVM := desired.(*kubevirtv1.VirtualMachine)
+		if !equality.Semantic.DeepDerivative(desiredVM.Spec, actual.Spec) {
+			actual.Spec = desiredVM.Spec
+			updated = true
+		}
+	case *corev1.Service:
+		desiredService := desired.(*corev1.Service)
+		if !equality.Semantic.DeepDerivative(desiredService.Spec.Ports, actual.Spec.Ports) || len(desiredService.Spec.Ports) != len(actual.Spec.Ports) {
+			actual.Spec.Ports = desiredService.Spec.Ports
+			updated = true
+		}
+		if !equality.Semantic.DeepEqual(desiredService.Spec.Selector, actual.Spec.Selector) || desiredService.Spec.Type != actual.Spec.Type {
+			actual.Spec.Selector = desiredService.Spec.Selector
+			actual.Spec.Type = desiredService.Spec.Type
+			updated = true
+		}
+	case *networkingv1.Ingress:
+		desiredIngress := desired.(*networkingv1.Ingress)
+		if !equality.Semantic.DeepDerivative(desiredIngress.Spec, actual.Spec) {
+			actual.Spec = desiredIngress.Spec
+			updated = true
+		}
+	case *network.NetworkAttachmentDefinition:
+		desiredConfig := desired.(*network.NetworkAttachmentDefinition).Spec.Config
+		if !networkConfigEqual(desiredConfig, actual.Spec.Config) {
+			actual.Spec.Config = desiredConfig
+			updated = true
+		}
+	case *rbacv1.Role:
+		desiredRole := desired.(*rbacv1.Role)
+		if !equality.Semantic.DeepEqual(desiredRole.Rules, actual.Rules) {
+			actual.Rules = desiredRole.Rules
+			updated = true
+		}
+	case *rbacv1.RoleBinding:
+		desiredRoleBinding := desired.(*rbacv1.RoleBinding)
+		if desiredRoleBinding.RoleRef != actual.RoleRef {
+			return updated, true
+		}
+		if !equality.Semantic.DeepEqual(desiredRoleBinding.Subjects, actual.Subjects) {
+			actual.Subjects = desiredRoleBinding.Subjects
+			updated = true
+		}
+	}
+	return updated, false
+}
+
+// networkConfigEqual compares two CNI configs as JSON, so formatting differences don't count as drift.
+func networkConfigEqual(desired string, actual string) bool {
+	var desiredConfig, actualConfig interface{}
+	if err := json.Unmarshal([]byte(desired), &desiredConfig); err != nil {
+		return desired == actual
+	}
+	if err := json.Unmarshal([]byte(actual), &actualConfig); err != nil {
+		return false
+	}
+	return equality.Semantic.DeepEqual(desiredConfig, actualConfig)
+}
+
+// mergeMetadata adds the desired labels and annotations to a resource, others are kept, as they might be set by other controllers.
+func mergeMetadata(resource client.Object, desired client.Object) bool {
+	updated := false
+	labels, labelsUpdated := mergeMap(resource.GetLabels(), desired.GetLabels())
+	if labelsUpdated {
+		resource.SetLabels(labels)
+		updated = true
+	}
+	annotations, annotationsUpdated := mergeMap(resource.GetAnnotations(), desired.GetAnnotations())
+	if annotationsUpdated {
+		resource.SetAnnotations(annotations)
+		updated = true
+	}
+	return updated
+}
+
+func mergeMap(actual map[string]string, desired map[string]string) (map[string]string, bool) {
+	updated := false
+	for key, value := range desired {
+		if current, ok := actual[key]; ok && current == value {
+			continue
+		}
+		if actual == nil {
+			actual = map[string]string{}
+		}
+		actual[key] = value
+		updated = true
+	}
+	return actual, updated
+}
+
 func CreateResource(labInstance *ltbv1alpha1.LabInstance, node *ltbv1alpha1.LabInstanceNodes, resource client.Object, kind string) (client.Object, error) {
 	ctx := context.Context(context.Background())
 	log := log.FromContext(ctx)
@@ -383,6 +1428,56 @@ func (r *LabInstanceReconciler) GetNodeType(ctx context.Context, nodeTypeRef *lt
 	return returnValue
 }
 
+// GetRenderedNodeSpec renders the node spec of a lab node, which is stored in the status of its lab template, with the lab instance in its template context.
+// It waits for the lab template controller, if the current spec of the lab template hasn't been rendered yet.
+// Lab templates, which were rendered without storing the node spec of the node type, provide their rendered node spec as is.
+// A config, which the node type delivers as a file, is added as volume to the rendered node spec.
+func GetRenderedNodeSpec(labTemplate *ltbv1alpha1.LabTemplate, labInstance *ltbv1alpha1.LabInstance, node *ltbv1alpha1.LabInstanceNodes) (string, ReturnToReconciler) {
+	returnValue := ReturnToReconciler{shouldReturn: false, result: ctrl.Result{}, err: nil}
+	renderedNode := GetRenderedNode(labTemplate, node.Name)
+	if renderedNode == nil || labTemplate.Status.ObservedGeneration != labTemplate.Generation {
+		returnValue.shouldReturn = true
+		returnValue.result = ctrl.Result{RequeueAfter: 2 * time.Second}
+		return "", returnValue
+	}
+	rendered := meta.FindStatusCondition(renderedNode.Conditions, ltbv1alpha1.RenderedCondition)
+	if rendered == nil || rendered.Status != metav1.ConditionTrue {
+		returnValue.shouldReturn = true
+		message := "the node spec hasn't been rendered"
+		if rendered != nil {
+			message = rendered.Message
+		}
+		returnValue.err = errors.NewBadRequest(fmt.Sprintf("Node %s of LabTemplate %s can't be rendered: %s", node.Name, labTemplate.Name, message))
+		return "", returnValue
+	}
+	if renderedNode.NodeSpec == "" {
+		return renderedNode.RenderedNodeSpec, returnValue
+	}
+	var renderedNodeSpec strings.Builder
+	templateContext, err := GetTemplateContext(labTemplate, labInstance, node)
+	if err == nil {
+		err = util.ParseAndRenderTemplate(renderedNode.NodeSpec, &renderedNodeSpec, templateContext)
+	}
+	if err != nil {
+		returnValue.shouldReturn = true
+		returnValue.err = errors.NewBadRequest(fmt.Sprintf("Node %s of LabTemplate %s can't be rendered for LabInstance %s: %s", node.Name, labTemplate.Name, labInstance.Name, err))
+		return "", returnValue
+	}
+	nodeSpec, err := AddNodeConfigVolume(labInstance, node, renderedNode, renderedNodeSpec.String())
+	if err != nil {
+		returnValue.shouldReturn = true
+		returnValue.err = errors.NewBadRequest(fmt.Sprintf("The config of node %s can't be delivered: %s", node.Name, err))
+		return "", returnValue
+	}
+	nodeSpec, err = AddSecretVolumes(labInstance, node, renderedNode, nodeSpec)
+	if err != nil {
+		returnValue.shouldReturn = true
+		returnValue.err = errors.NewBadRequest(fmt.Sprintf("The secrets of node %s can't be mounted: %s", node.Name, err))
+		return "", returnValue
+	}
+	return nodeSpec, returnValue
+}
+
 func MapTemplateToPod(labInstance *ltbv1alpha1.LabInstance, node *ltbv1alpha1.LabInstanceNodes) (*corev1.Pod, error) {
 	log := log.FromContext(context.Background())
 	if node == nil {
@@ -394,19 +1489,43 @@ func MapTemplateToPod(labInstance *ltbv1alpha1.LabInstance, node *ltbv1alpha1.La
 	metadata := metav1.ObjectMeta{
 		Name:      labInstance.Name + "-" + node.Name,
 		Namespace: labInstance.Namespace,
-		Annotations: map[string]string{
-			"k8s.v1.cni.cncf.io/networks": labInstance.Name + "-pod",
-		},
 		Labels: map[string]string{
 			"app": labInstance.Name + "-" + node.Name + "-remote-access",
 		},
 	}
+	if len(node.Interfaces) > 0 {
+		networkSelections := []network.NetworkSelectionElement{}
+		for _, nodeInterface := range node.Interfaces {
+			networkSelections = append(networkSelections, network.NetworkSelectionElement{
+				Name:             NetworkAttachmentDefinitionName(labInstance, node.Name, nodeInterface.Name),
+				InterfaceRequest: nodeInterface.Name,
+			})
+		}
+		networks, err := json.Marshal(networkSelections)
+		if err != nil {
+			log.Error(err, "Failed to marshal network selection")
+			return nil, err
+		}
+		metadata.Annotations = map[string]string{network.NetworkAttachmentAnnot: string(networks)}
+	}
 	podSpec := &corev1.PodSpec{}
 	err := yaml.Unmarshal([]byte(node.RenderedNodeSpec), podSpec)
 	if err != nil {
 		log.Error(err, "Failed to unmarshal node spec")
 		return nil, err
 	}
+	if nodeOverride := GetNodeOverride(labInstance, node.Name); nodeOverride != nil && nodeOverride.Resources != nil && len(podSpec.Containers) > 0 {
+		podSpec.Containers[0].Resources = *nodeOverride.Resources
+	}
+	// Every pod with interfaces gets the link sidecar, so links can be impaired or set down in a running lab
+	if len(node.Interfaces) > 0 {
+		if len(podSpec.Containers) > 0 {
+			metadata.Annotations["kubectl.kubernetes.io/default-container"] = podSpec.Containers[0].Name
+		}
+		container, volumes := labnet.LinkSidecar(LinkConfigMapName(labInstance), node.Name)
+		podSpec.Containers = append(podSpec.Containers, container)
+		podSpec.Volumes = append(podSpec.Volumes, volumes...)
+	}
 	log.Info("PodSpec", "Spec applied to Pod", podSpec)
 	pod := &corev1.Pod{
 		ObjectMeta: metadata,
@@ -433,13 +1552,21 @@ func MapTemplateToVM(labInstance *ltbv1alpha1.LabInstance, node *ltbv1alpha1.Lab
 		log.Error(err, "Failed to unmarshal node spec")
 		return nil, err
 	}
+	if nodeOverride := GetNodeOverride(labInstance, node.Name); nodeOverride != nil && nodeOverride.Resources != nil && vmSpec.Template != nil {
+		vmSpec.Template.Spec.Domain.Resources.Requests = nodeOverride.Resources.Requests
+		vmSpec.Template.Spec.Domain.Resources.Limits = nodeOverride.Resources.Limits
+	}
 	networks := []kubevirtv1.Network{
 		{Name: "default", NetworkSource: kubevirtv1.NetworkSource{Pod: &kubevirtv1.PodNetwork{}}},
-		{Name: labInstance.Name, NetworkSource: kubevirtv1.NetworkSource{Multus: &kubevirtv1.MultusNetwork{NetworkName: labInstance.Name + "-vm"}}},
 	}
 	interfaces := []kubevirtv1.Interface{
 		{Name: "default", InterfaceBindingMethod: kubevirtv1.InterfaceBindingMethod{Bridge: &kubevirtv1.InterfaceBridge{}}},
-		{Name: labInstance.Name, InterfaceBindingMethod: kubevirtv1.InterfaceBindingMethod{Bridge: &kubevirtv1.InterfaceBridge{}}},
+	}
+	// The guest names its interfaces by itself, therefore only the order of the interfaces is preserved.
+	for _, nodeInterface := range node.Interfaces {
+		networkName := sanitizeName(nodeInterface.Name)
+		networks = append(networks, kubevirtv1.Network{Name: networkName, NetworkSource: kubevirtv1.NetworkSource{Multus: &kubevirtv1.MultusNetwork{NetworkName: NetworkAttachmentDefinitionName(labInstance, node.Name, nodeInterface.Name)}}})
+		interfaces = append(interfaces, kubevirtv1.Interface{Name: networkName, InterfaceBindingMethod: kubevirtv1.InterfaceBindingMethod{Bridge: &kubevirtv1.InterfaceBridge{}}})
 	}
 	vmSpec.Template.Spec.Domain.Devices.Interfaces = interfaces
 	vmSpec.Template.Spec.Networks = networks
@@ -630,9 +1757,9 @@ func CreateSvcAccRoleRoleBind(labInstance *ltbv1alpha1.LabInstance) (*corev1.Ser
 
 }
 
+// UpdateLabInstanceStatus counts the running pods and the ready VMs of a lab instance.
+// The status is Running if all of them run, otherwise it is the phase of the first pod or the status of the first VM, which doesn't run.
 func UpdateLabInstanceStatus(pods []*corev1.Pod, vms []*kubevirtv1.VirtualMachine, labInstance *ltbv1alpha1.LabInstance) error {
-	var podStatus corev1.PodPhase
-	var vmStatus kubevirtv1.VirtualMachinePrintableStatus
 	var numVMsRunning, numPodsRunning int
 	if pods == nil && vms == nil {
 		return errors.NewBadRequest("No resources found")
@@ -640,40 +1767,92 @@ func UpdateLabInstanceStatus(pods []*corev1.Pod, vms []*kubevirtv1.VirtualMachin
 	if labInstance == nil {
 		return errors.NewBadRequest("LabInstance is nil")
 	}
+	status := "Running"
 	for _, pod := range pods {
-		podStatus = pod.Status.Phase
-		if podStatus != corev1.PodRunning {
-			break
+		if pod.Status.Phase != corev1.PodRunning {
+			if status == "Running" {
+				status = string(pod.Status.Phase)
+			}
+			continue
 		}
 		numPodsRunning++
 	}
 	labInstance.Status.NumPodsRunning = fmt.Sprint(numPodsRunning) + "/" + fmt.Sprint(len(pods))
 
 	for _, vm := range vms {
-		vmStatus = vm.Status.PrintableStatus
 		if !vm.Status.Ready {
-			break
+			if status == "Running" {
+				status = string(vm.Status.PrintableStatus)
+			}
+			continue
 		}
 		numVMsRunning++
 	}
 	labInstance.Status.NumVMsRunning = fmt.Sprint(numVMsRunning) + "/" + fmt.Sprint(len(vms))
-
-	if podStatus == "Running" && vmStatus == "VM Ready" {
-		labInstance.Status.Status = "Running"
-	} else {
-		if podStatus != "Running" {
-			labInstance.Status.Status = string(podStatus)
-		} else {
-			labInstance.Status.Status = string(vmStatus)
-		}
-	}
+	labInstance.Status.Status = status
 	return nil
 }
 
 func (r *LabInstanceReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
+	err := ctrl.NewControllerManagedBy(mgr).
 		For(&ltbv1alpha1.LabInstance{}).
 		Owns(&corev1.Pod{}).
 		Owns(&kubevirtv1.VirtualMachine{}).
+		// Deleted children are created again right away
+		Owns(&corev1.Service{}).
+		Owns(&networkingv1.Ingress{}).
+		Owns(&corev1.ServiceAccount{}).
+		Owns(&rbacv1.Role{}).
+		Owns(&rbacv1.RoleBinding{}).
+		Owns(&corev1.ConfigMap{}).
+		Owns(&corev1.Secret{}).
+		Owns(&network.NetworkAttachmentDefinition{}).
+		// The link ConfigMap lists the capture containers, which are allowed to run
+		Watches(&source.Kind{Type: &ltbv1alpha1.PacketCapture{}}, handler.EnqueueRequestsFromMapFunc(func(object client.Object) []reconcile.Request {
+			packetCapture := object.(*ltbv1alpha1.PacketCapture)
+			return []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: packetCapture.Namespace, Name: packetCapture.Spec.LabInstanceRef}}}
+		})).
+		// Changes of a lab template and its rendered nodes, including node types rendered again into it, are applied to its lab instances
+		Watches(&source.Kind{Type: &ltbv1alpha1.LabTemplate{}}, handler.EnqueueRequestsFromMapFunc(r.labInstancesOfLabTemplate), builder.WithPredicates(predicate.Or(predicate.GenerationChangedPredicate{}, renderedNodesChangedPredicate))).
 		Complete(r)
+	if err != nil {
+		return err
+	}
+	return mgr.GetFieldIndexer().IndexField(context.Background(), &ltbv1alpha1.LabInstance{}, labTemplateRefField, LabTemplateRef)
+}
+
+// renderedNodesChangedPredicate passes the updates of lab templates, whose rendered nodes changed.
+var renderedNodesChangedPredicate = predicate.Funcs{
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		oldLabTemplate, ok := e.ObjectOld.(*ltbv1alpha1.LabTemplate)
+		if !ok {
+			return false
+		}
+		newLabTemplate, ok := e.ObjectNew.(*ltbv1alpha1.LabTemplate)
+		if !ok {
+			return false
+		}
+		return !reflect.DeepEqual(oldLabTemplate.Status.Nodes, newLabTemplate.Status.Nodes)
+	},
+}
+
+// LabTemplateRef returns the name of the lab template, which is referenced by a lab instance.
+func LabTemplateRef(object client.Object) []string {
+	return []string{object.(*ltbv1alpha1.LabInstance).Spec.LabTemplateReference}
+}
+
+// labInstancesOfLabTemplate returns the lab instances, which use the lab template.
+// Lab instances with the manual rollout policy are requeued as well, as only their pods and VMs keep the old spec.
+func (r *LabInstanceReconciler) labInstancesOfLabTemplate(object client.Object) []reconcile.Request {
+	labInstances := &ltbv1alpha1.LabInstanceList{}
+	err := r.List(context.Background(), labInstances, client.MatchingFields{labTemplateRefField: object.GetName()})
+	if err != nil {
+		log.Log.Error(err, "Failed to list LabInstances", "LabTemplate", object.GetName())
+		return nil
+	}
+	requests := []reconcile.Request{}
+	for _, labInstance := range labInstances.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&labInstance)})
+	}
+	return requests
 }
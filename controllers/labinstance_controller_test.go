@@ -9,18 +9,34 @@ import (
 	networkingv1 "k8s.io/api/networking/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
 	kubevirtv1 "kubevirt.io/api/core/v1"
 
+	network "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+
+	ltbv1alpha1 "github.com/Lab-Topology-Builder/LTB-K8s-Backend/api/v1alpha1"
+	"github.com/Lab-Topology-Builder/LTB-K8s-Backend/labnet"
+	util "github.com/Lab-Topology-Builder/LTB-K8s-Backend/util"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 )
 
 var _ = Describe("LabInstance Controller", func() {
 
 	var r *LabInstanceReconciler
 
+	AfterEach(func() {
+		if r != nil {
+			r.Recorder = nil
+		}
+	})
+
 	Describe("Reconcile", func() {
 		var (
 			ctx context.Context
@@ -46,6 +62,16 @@ var _ = Describe("LabInstance Controller", func() {
 				Expect(apiErrors.IsNotFound(err)).To(BeFalse())
 				Expect(result).To(Equal(ctrl.Result{Requeue: false}))
 			})
+			It("should release the subnet of the deleted lab instance", func() {
+				r.SubnetAllocator, _ = util.NewSubnetAllocator("10.10.0.0/24", 24)
+				_, err := r.SubnetAllocator.Allocate(namespace + "/test")
+				Expect(err).NotTo(HaveOccurred())
+				req.NamespacedName = types.NamespacedName{Namespace: namespace, Name: "test"}
+				_, err = r.Reconcile(ctx, req)
+				Expect(err).NotTo(HaveOccurred())
+				_, err = r.SubnetAllocator.Allocate(namespace + "/other")
+				Expect(err).NotTo(HaveOccurred())
+			})
 		})
 
 		Describe("Namespace request with correct name of available lab instance", func() {
@@ -82,7 +108,7 @@ var _ = Describe("LabInstance Controller", func() {
 			})
 			Context("Ttyd role doesn't exist", func() {
 				BeforeEach(func() {
-					r.Client = fake.NewClientBuilder().WithObjects(testLabInstance, testLabTemplateWithRenderedNodeSpec, testPodNodeType, testNodeVMType, testPodNetworkAttachmentDefinition, testVMNetworkAttachmentDefinition, testServiceAccount, testRoleBinding, testTtydPod, testTtydService, testService, testVMIngress, testPodIngress).Build()
+					r.Client = fake.NewClientBuilder().WithObjects(testLabInstance, testLabTemplateWithRenderedNodeSpec, testPodNodeType, testNodeVMType, testPodNetworkAttachmentDefinition, testPodUnlinkedNetworkAttachmentDefinition, testVMNetworkAttachmentDefinition, testLinkConfigMap, testServiceAccount, testRoleBinding, testTtydPod, testTtydService, testService, testVMIngress, testPodIngress).Build()
 				})
 				It("should create a ttyd role", func() {
 					req.NamespacedName = types.NamespacedName{Namespace: namespace, Name: testLabInstance.Name}
@@ -93,7 +119,7 @@ var _ = Describe("LabInstance Controller", func() {
 			})
 			Context("Ttyd role binding doesn't exist", func() {
 				BeforeEach(func() {
-					r.Client = fake.NewClientBuilder().WithObjects(testLabInstance, testLabTemplateWithRenderedNodeSpec, testPodNodeType, testNodeVMType, testPodNetworkAttachmentDefinition, testVMNetworkAttachmentDefinition, testServiceAccount, testRole, testTtydPod, testTtydService, testService, testVMIngress, testPodIngress).Build()
+					r.Client = fake.NewClientBuilder().WithObjects(testLabInstance, testLabTemplateWithRenderedNodeSpec, testPodNodeType, testNodeVMType, testPodNetworkAttachmentDefinition, testPodUnlinkedNetworkAttachmentDefinition, testVMNetworkAttachmentDefinition, testLinkConfigMap, testServiceAccount, testRole, testTtydPod, testTtydService, testService, testVMIngress, testPodIngress).Build()
 				})
 				It("should create a ttyd rolebinding", func() {
 					req.NamespacedName = types.NamespacedName{Namespace: namespace, Name: testLabInstance.Name}
@@ -104,7 +130,7 @@ var _ = Describe("LabInstance Controller", func() {
 			})
 			Context("Ttyd pod doesn't exist", func() {
 				BeforeEach(func() {
-					r.Client = fake.NewClientBuilder().WithObjects(testLabInstance, testLabTemplateWithRenderedNodeSpec, testPodNodeType, testNodeVMType, testPodNetworkAttachmentDefinition, testVMNetworkAttachmentDefinition, testServiceAccount, testRoleBinding, testRole, testTtydService, testService, testVMIngress, testPodIngress).Build()
+					r.Client = fake.NewClientBuilder().WithObjects(testLabInstance, testLabTemplateWithRenderedNodeSpec, testPodNodeType, testNodeVMType, testPodNetworkAttachmentDefinition, testPodUnlinkedNetworkAttachmentDefinition, testVMNetworkAttachmentDefinition, testLinkConfigMap, testServiceAccount, testRoleBinding, testRole, testTtydService, testService, testVMIngress, testPodIngress).Build()
 				})
 				It("should create a ttyd pod", func() {
 					req.NamespacedName = types.NamespacedName{Namespace: namespace, Name: testLabInstance.Name}
@@ -115,7 +141,7 @@ var _ = Describe("LabInstance Controller", func() {
 			})
 			Context("Ttyd service doesn't exist", func() {
 				BeforeEach(func() {
-					r.Client = fake.NewClientBuilder().WithObjects(testLabInstance, testLabTemplateWithRenderedNodeSpec, testPodNodeType, testNodeVMType, testPodNetworkAttachmentDefinition, testVMNetworkAttachmentDefinition, testServiceAccount, testRoleBinding, testRole, testTtydPod, testService, testVMIngress, testPodIngress).Build()
+					r.Client = fake.NewClientBuilder().WithObjects(testLabInstance, testLabTemplateWithRenderedNodeSpec, testPodNodeType, testNodeVMType, testPodNetworkAttachmentDefinition, testPodUnlinkedNetworkAttachmentDefinition, testVMNetworkAttachmentDefinition, testLinkConfigMap, testServiceAccount, testRoleBinding, testRole, testTtydPod, testService, testVMIngress, testPodIngress).Build()
 				})
 				It("should create a ttyd service", func() {
 					req.NamespacedName = types.NamespacedName{Namespace: namespace, Name: testLabInstance.Name}
@@ -126,7 +152,7 @@ var _ = Describe("LabInstance Controller", func() {
 			})
 			Context("Node type not found", func() {
 				BeforeEach(func() {
-					r.Client = fake.NewClientBuilder().WithObjects(testLabInstance, testLabTemplateWithRenderedNodeSpec, testPodNetworkAttachmentDefinition, testVMNetworkAttachmentDefinition, testServiceAccount, testRoleBinding, testRole, testTtydPod, testTtydService, testService, testVMIngress, testPodIngress).Build()
+					r.Client = fake.NewClientBuilder().WithObjects(testLabInstance, testLabTemplateWithRenderedNodeSpec, testPodNetworkAttachmentDefinition, testPodUnlinkedNetworkAttachmentDefinition, testVMNetworkAttachmentDefinition, testLinkConfigMap, testServiceAccount, testRoleBinding, testRole, testTtydPod, testTtydService, testService, testVMIngress, testPodIngress).Build()
 				})
 				It("should return not found error", func() {
 					req.NamespacedName = types.NamespacedName{Namespace: namespace, Name: testLabInstance.Name}
@@ -138,7 +164,7 @@ var _ = Describe("LabInstance Controller", func() {
 			})
 			Context("VM doesn't exist", func() {
 				BeforeEach(func() {
-					r.Client = fake.NewClientBuilder().WithObjects(testLabInstance, testLabTemplateWithRenderedNodeSpec, testPodNodeType, testNodeVMType, testPodNetworkAttachmentDefinition, testVMNetworkAttachmentDefinition, testServiceAccount, testRoleBinding, testRole, testTtydPod, testTtydService, testService, testVMIngress, testPodIngress).Build()
+					r.Client = fake.NewClientBuilder().WithObjects(testLabInstance, testLabTemplateWithRenderedNodeSpec, testPodNodeType, testNodeVMType, testPodNetworkAttachmentDefinition, testPodUnlinkedNetworkAttachmentDefinition, testVMNetworkAttachmentDefinition, testLinkConfigMap, testServiceAccount, testRoleBinding, testRole, testTtydPod, testTtydService, testService, testVMIngress, testPodIngress).Build()
 				})
 				It("should create a VM", func() {
 					req.NamespacedName = types.NamespacedName{Namespace: namespace, Name: testLabInstance.Name}
@@ -149,7 +175,7 @@ var _ = Describe("LabInstance Controller", func() {
 			})
 			Context("Pod doesn't exist", func() {
 				BeforeEach(func() {
-					r.Client = fake.NewClientBuilder().WithObjects(testLabInstance, testLabTemplateWithRenderedNodeSpec, testPodNodeType, testNodeVMType, testPodNetworkAttachmentDefinition, testVM, testVMNetworkAttachmentDefinition, testServiceAccount, testRoleBinding, testRole, testTtydPod, testTtydService, testService, testVMIngress, testPodIngress).Build()
+					r.Client = fake.NewClientBuilder().WithObjects(testLabInstance, testLabTemplateWithRenderedNodeSpec, testPodNodeType, testNodeVMType, testPodNetworkAttachmentDefinition, testPodUnlinkedNetworkAttachmentDefinition, testVM, testVMNetworkAttachmentDefinition, testLinkConfigMap, testServiceAccount, testRoleBinding, testRole, testTtydPod, testTtydService, testService, testVMIngress, testPodIngress).Build()
 				})
 				It("should create a Pod", func() {
 					req.NamespacedName = types.NamespacedName{Namespace: namespace, Name: testLabInstance.Name}
@@ -160,7 +186,7 @@ var _ = Describe("LabInstance Controller", func() {
 			})
 			Context("Service for remote access doesn't exist", func() {
 				BeforeEach(func() {
-					r.Client = fake.NewClientBuilder().WithObjects(testLabInstance, testLabTemplateWithRenderedNodeSpec, testPodNodeType, testNodeVMType, testPodNetworkAttachmentDefinition, testVM, testVMNetworkAttachmentDefinition, testServiceAccount, testRoleBinding, testRole, testTtydPod, testTtydService, testVMIngress, testPodIngress).Build()
+					r.Client = fake.NewClientBuilder().WithObjects(testLabInstance, testLabTemplateWithRenderedNodeSpec, testPodNodeType, testNodeVMType, testPodNetworkAttachmentDefinition, testPodUnlinkedNetworkAttachmentDefinition, testVM, testVMNetworkAttachmentDefinition, testLinkConfigMap, testServiceAccount, testRoleBinding, testRole, testTtydPod, testTtydService, testVMIngress, testPodIngress).Build()
 				})
 				It("should create a Service for remote access", func() {
 					req.NamespacedName = types.NamespacedName{Namespace: namespace, Name: testLabInstance.Name}
@@ -171,7 +197,7 @@ var _ = Describe("LabInstance Controller", func() {
 			})
 			Context("Ingress for remote access doesn't exist", func() {
 				BeforeEach(func() {
-					r.Client = fake.NewClientBuilder().WithObjects(testLabInstance, testLabTemplateWithRenderedNodeSpec, testPodNodeType, testNodeVMType, testPodNetworkAttachmentDefinition, testVM, testVMNetworkAttachmentDefinition, testServiceAccount, testRoleBinding, testRole, testTtydPod, testTtydService, testService).Build()
+					r.Client = fake.NewClientBuilder().WithObjects(testLabInstance, testLabTemplateWithRenderedNodeSpec, testPodNodeType, testNodeVMType, testPodNetworkAttachmentDefinition, testPodUnlinkedNetworkAttachmentDefinition, testVM, testVMNetworkAttachmentDefinition, testLinkConfigMap, testServiceAccount, testRoleBinding, testRole, testTtydPod, testTtydService, testService).Build()
 				})
 				It("should create an Ingress for remote access", func() {
 					req.NamespacedName = types.NamespacedName{Namespace: namespace, Name: testLabInstance.Name}
@@ -182,7 +208,7 @@ var _ = Describe("LabInstance Controller", func() {
 			})
 			Context("All resources exists", func() {
 				BeforeEach(func() {
-					r.Client = fake.NewClientBuilder().WithObjects(testLabInstance, testLabTemplateWithRenderedNodeSpec, testPodNodeType, testNodeVMType, testPod, testVM, testPodNetworkAttachmentDefinition, testVMNetworkAttachmentDefinition, testServiceAccount, testRoleBinding, testRole, testTtydPod, testTtydService, testService, testVMIngress, testPodIngress).Build()
+					r.Client = fake.NewClientBuilder().WithObjects(testLabInstance, testLabTemplateWithRenderedNodeSpec, testPodNodeType, testNodeVMType, testPod, testVM, testPodNetworkAttachmentDefinition, testPodUnlinkedNetworkAttachmentDefinition, testVMNetworkAttachmentDefinition, testLinkConfigMap, testServiceAccount, testRoleBinding, testRole, testTtydPod, testTtydService, testService, testVMIngress, testPodIngress).Build()
 				})
 				It("should return nil error", func() {
 					req.NamespacedName = types.NamespacedName{Namespace: namespace, Name: testLabInstance.Name}
@@ -190,6 +216,99 @@ var _ = Describe("LabInstance Controller", func() {
 					Expect(err).NotTo(HaveOccurred())
 					Expect(result).To(Equal(ctrl.Result{}))
 				})
+				It("should show the status of the nodes and the conditions", func() {
+					req.NamespacedName = types.NamespacedName{Namespace: namespace, Name: testLabInstance.Name}
+					_, err := r.Reconcile(ctx, req)
+					Expect(err).NotTo(HaveOccurred())
+					labInstance := &ltbv1alpha1.LabInstance{}
+					err = r.Get(ctx, req.NamespacedName, labInstance)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(labInstance.Status.Nodes).To(HaveLen(2))
+					Expect(labInstance.Status.Nodes[0].Kind).To(Equal("vm"))
+					Expect(labInstance.Status.Nodes[1].Kind).To(Equal("pod"))
+					Expect(labInstance.Status.Nodes[0].WebTerminalURL).To(Equal("https://" + testVM.Name + ".example.com/"))
+					Expect(labInstance.Status.Nodes[0].RemoteAccess).To(Equal([]ltbv1alpha1.RemoteAccessEndpoint{{Name: "test-ssh-port", Host: "192.0.2.10", Port: 22, Protocol: "TCP"}}))
+					Expect(meta.IsStatusConditionTrue(labInstance.Status.Conditions, ltbv1alpha1.NetworkReadyCondition)).To(BeTrue())
+					Expect(meta.IsStatusConditionTrue(labInstance.Status.Conditions, ltbv1alpha1.RemoteAccessReadyCondition)).To(BeTrue())
+				})
+			})
+		})
+	})
+
+	Describe("ReconcileSubnet", func() {
+		var (
+			ctx         context.Context
+			labInstance *ltbv1alpha1.LabInstance
+		)
+		BeforeEach(func() {
+			ctx = context.Background()
+			labInstance = testLabInstance.DeepCopy()
+			labInstance.Status.Network = ltbv1alpha1.LabInstanceNetworkStatus{}
+			r.Client = fake.NewClientBuilder().WithObjects(labInstance).Build()
+			r.SubnetAllocator, _ = util.NewSubnetAllocator("10.10.0.0/23", 24)
+		})
+		AfterEach(func() {
+			r.SubnetAllocator = nil
+		})
+		Context("labInstance nil", func() {
+			It("should return error", func() {
+				returnValue := r.ReconcileSubnet(ctx, nil)
+				Expect(returnValue.err).To(Equal(apiErrors.NewBadRequest("labInstance is nil")))
+				Expect(returnValue.shouldReturn).To(BeTrue())
+			})
+		})
+		Context("No subnet allocator configured", func() {
+			It("should only set the network name", func() {
+				r.SubnetAllocator = nil
+				returnValue := r.ReconcileSubnet(ctx, labInstance)
+				Expect(returnValue.err).To(BeNil())
+				Expect(returnValue.shouldReturn).To(BeFalse())
+				Expect(labInstance.Status.Network.Name).To(Equal(LabNetworkName(labInstance)))
+				Expect(labInstance.Status.Network.Subnet).To(BeEmpty())
+			})
+		})
+		Context("Subnet not yet allocated", func() {
+			It("should allocate a subnet and record it in the status", func() {
+				returnValue := r.ReconcileSubnet(ctx, labInstance)
+				Expect(returnValue.err).To(BeNil())
+				Expect(returnValue.result).To(Equal(ctrl.Result{Requeue: true}))
+				Expect(returnValue.shouldReturn).To(BeTrue())
+				updatedLabInstance := &ltbv1alpha1.LabInstance{}
+				Expect(r.Get(ctx, types.NamespacedName{Namespace: labInstance.Namespace, Name: labInstance.Name}, updatedLabInstance)).To(Succeed())
+				Expect(updatedLabInstance.Status.Network.Subnet).To(Equal("10.10.0.0/24"))
+			})
+			It("should not allocate a subnet of another lab instance", func() {
+				otherLabInstance := labInstance.DeepCopy()
+				otherLabInstance.Name = "other-labinstance"
+				otherLabInstance.Status.Network.Subnet = "10.10.0.0/24"
+				r.Client = fake.NewClientBuilder().WithObjects(labInstance, otherLabInstance).Build()
+				returnValue := r.ReconcileSubnet(ctx, labInstance)
+				Expect(returnValue.err).To(BeNil())
+				Expect(labInstance.Status.Network.Subnet).To(Equal("10.10.1.0/24"))
+			})
+			It("should fail if the pool is exhausted", func() {
+				_, _ = r.SubnetAllocator.Allocate("other/lab1")
+				_, _ = r.SubnetAllocator.Allocate("other/lab2")
+				returnValue := r.ReconcileSubnet(ctx, labInstance)
+				Expect(returnValue.err).To(HaveOccurred())
+				Expect(returnValue.shouldReturn).To(BeTrue())
+			})
+		})
+		Context("Subnet already allocated", func() {
+			It("should reserve the subnet and continue", func() {
+				labInstance.Status.Network.Subnet = "10.10.1.0/24"
+				returnValue := r.ReconcileSubnet(ctx, labInstance)
+				Expect(returnValue.err).To(BeNil())
+				Expect(returnValue.shouldReturn).To(BeFalse())
+				subnet, _ := r.SubnetAllocator.Allocate("other/lab")
+				Expect(subnet).To(Equal("10.10.0.0/24"))
+			})
+			It("should fail if the subnet belongs to another lab instance", func() {
+				Expect(r.SubnetAllocator.Reserve("other/lab", "10.10.1.0/24")).To(Succeed())
+				labInstance.Status.Network.Subnet = "10.10.1.0/24"
+				returnValue := r.ReconcileSubnet(ctx, labInstance)
+				Expect(returnValue.err).To(HaveOccurred())
+				Expect(returnValue.shouldReturn).To(BeTrue())
 			})
 		})
 	})
@@ -203,24 +322,470 @@ var _ = Describe("LabInstance Controller", func() {
 		})
 		Context("labInstance nil", func() {
 			It("should return error", func() {
-				returnValue := r.ReconcileNetwork(ctx, nil)
+				returnValue := r.ReconcileNetwork(ctx, nil, testLabTemplateWithRenderedNodeSpec)
 				Expect(returnValue.result).To(Equal(ctrl.Result{}))
 				Expect(returnValue.err).To(Equal(apiErrors.NewBadRequest("labInstance is nil")))
 				Expect(returnValue.shouldReturn).To(BeTrue())
 			})
 		})
+		Context("labTemplate nil", func() {
+			It("should return error", func() {
+				returnValue := r.ReconcileNetwork(ctx, testLabInstance, nil)
+				Expect(returnValue.result).To(Equal(ctrl.Result{}))
+				Expect(returnValue.err).To(Equal(apiErrors.NewBadRequest("labTemplate is nil")))
+				Expect(returnValue.shouldReturn).To(BeTrue())
+			})
+		})
+		Context("Link references an unknown node", func() {
+			It("should return error", func() {
+				returnValue := r.ReconcileNetwork(ctx, testLabInstance, testLabTemplateWithInvalidLink)
+				Expect(returnValue.result).To(Equal(ctrl.Result{}))
+				Expect(apiErrors.IsBadRequest(returnValue.err)).To(BeTrue())
+				Expect(returnValue.shouldReturn).To(BeTrue())
+			})
+		})
+		Context("Interface has an invalid address", func() {
+			It("should return error", func() {
+				labTemplate := testLabTemplateWithRenderedNodeSpec.DeepCopy()
+				labTemplate.Spec.Nodes[1].Interfaces[1].IPv4 = "10.0.0.1"
+				returnValue := r.ReconcileNetwork(ctx, testLabInstance, labTemplate)
+				Expect(returnValue.result).To(Equal(ctrl.Result{}))
+				Expect(apiErrors.IsBadRequest(returnValue.err)).To(BeTrue())
+				Expect(returnValue.shouldReturn).To(BeTrue())
+			})
+		})
 		Context("Valid lab instance provided", func() {
 			BeforeEach(func() {
 				r.Client = fake.NewClientBuilder().WithObjects(testLabInstance).Build()
 			})
 			It("should create a network attachment definition", func() {
-				returnValue := r.ReconcileNetwork(ctx, testLabInstance)
+				returnValue := r.ReconcileNetwork(ctx, testLabInstance, testLabTemplateWithRenderedNodeSpec)
 				Expect(returnValue.result).To(Equal(ctrl.Result{Requeue: true}))
 				Expect(returnValue.err).To(BeNil())
 				Expect(returnValue.shouldReturn).To(BeTrue())
 
 			})
 		})
+		Context("Unknown network backend", func() {
+			It("should return error", func() {
+				labInstance := testLabInstance.DeepCopy()
+				labInstance.Spec.NetworkBackend = "unknown"
+				returnValue := r.ReconcileNetwork(ctx, labInstance, testLabTemplateWithRenderedNodeSpec)
+				Expect(apiErrors.IsBadRequest(returnValue.err)).To(BeTrue())
+				Expect(returnValue.shouldReturn).To(BeTrue())
+			})
+		})
+		Context("Network backend needs segment IDs", func() {
+			var labInstance *ltbv1alpha1.LabInstance
+			BeforeEach(func() {
+				labInstance = testLabInstance.DeepCopy()
+				labInstance.Spec.NetworkBackend = ltbv1alpha1.VXLANNetworkBackend
+				r.Client = fake.NewClientBuilder().WithObjects(labInstance).Build()
+				r.SegmentAllocator, _ = util.NewSegmentAllocator(1, 4094)
+			})
+			AfterEach(func() {
+				r.SegmentAllocator = nil
+			})
+			It("should return error without a segment allocator", func() {
+				r.SegmentAllocator = nil
+				returnValue := r.ReconcileNetwork(ctx, labInstance, testLabTemplateWithRenderedNodeSpec)
+				Expect(apiErrors.IsBadRequest(returnValue.err)).To(BeTrue())
+				Expect(returnValue.shouldReturn).To(BeTrue())
+			})
+			It("should allocate an ID for every segment and record them in the status", func() {
+				returnValue := r.ReconcileNetwork(ctx, labInstance, testLabTemplateWithRenderedNodeSpec)
+				Expect(returnValue.err).To(BeNil())
+				Expect(returnValue.result).To(Equal(ctrl.Result{Requeue: true}))
+				updatedLabInstance := &ltbv1alpha1.LabInstance{}
+				Expect(r.Get(ctx, types.NamespacedName{Namespace: labInstance.Namespace, Name: labInstance.Name}, updatedLabInstance)).To(Succeed())
+				link := testLabTemplateWithRenderedNodeSpec.Spec.Links[0]
+				Expect(updatedLabInstance.Status.Network.Segments).To(HaveLen(2))
+				Expect(updatedLabInstance.Status.Network.Segments).To(HaveKey(LinkSegmentName(labInstance, &link)))
+			})
+			It("should not allocate the segment IDs of another lab instance", func() {
+				otherLabInstance := labInstance.DeepCopy()
+				otherLabInstance.Name = "other-labinstance"
+				otherLabInstance.Status.Network.Segments = map[string]int32{"ltbother": 1}
+				r.Client = fake.NewClientBuilder().WithObjects(labInstance, otherLabInstance).Build()
+				returnValue := r.ReconcileNetwork(ctx, labInstance, testLabTemplateWithRenderedNodeSpec)
+				Expect(returnValue.err).To(BeNil())
+				Expect(labInstance.Status.Network.Segments).NotTo(ContainElement(int32(1)))
+			})
+			It("should create a network attachment definition once all segments have an ID", func() {
+				returnValue := r.ReconcileNetwork(ctx, labInstance, testLabTemplateWithRenderedNodeSpec)
+				Expect(returnValue.err).To(BeNil())
+				returnValue = r.ReconcileNetwork(ctx, labInstance, testLabTemplateWithRenderedNodeSpec)
+				Expect(returnValue.err).To(BeNil())
+				Expect(returnValue.result).To(Equal(ctrl.Result{Requeue: true}))
+				networkAttachmentDefinitions := &network.NetworkAttachmentDefinitionList{}
+				Expect(r.List(ctx, networkAttachmentDefinitions)).To(Succeed())
+				Expect(networkAttachmentDefinitions.Items).To(HaveLen(1))
+			})
+		})
+		Context("Network attachment definitions of all links exist", func() {
+			BeforeEach(func() {
+				r.Client = fake.NewClientBuilder().WithObjects(testLabInstance, testPodNetworkAttachmentDefinition, testPodUnlinkedNetworkAttachmentDefinition, testVMNetworkAttachmentDefinition).Build()
+			})
+			It("should not return", func() {
+				returnValue := r.ReconcileNetwork(ctx, testLabInstance, testLabTemplateWithRenderedNodeSpec)
+				Expect(returnValue.result).To(Equal(ctrl.Result{}))
+				Expect(returnValue.err).To(BeNil())
+				Expect(returnValue.shouldReturn).To(BeFalse())
+			})
+		})
+		Context("Network attachment definition drifted", func() {
+			var networkAttachmentDefinition *network.NetworkAttachmentDefinition
+			BeforeEach(func() {
+				networkAttachmentDefinition = testPodNetworkAttachmentDefinition.DeepCopy()
+				networkAttachmentDefinition.Spec.Config = `{"cniVersion":"0.3.1","name":"ltb6457a0eb1b30","type":"bridge","bridge":"ltb4f84fa63049a","ipam":{}}`
+			})
+			It("should update the config and recreate the pod attached to it", func() {
+				r.Client = fake.NewClientBuilder().WithObjects(testLabInstance, networkAttachmentDefinition, testPodUnlinkedNetworkAttachmentDefinition, testVMNetworkAttachmentDefinition, testPod).Build()
+				returnValue := r.ReconcileNetwork(ctx, testLabInstance, testLabTemplateWithRenderedNodeSpec)
+				Expect(returnValue.err).To(BeNil())
+				Expect(returnValue.result).To(Equal(ctrl.Result{RequeueAfter: resourceRecreateInterval}))
+				Expect(returnValue.shouldReturn).To(BeTrue())
+				updatedNetworkAttachmentDefinition := &network.NetworkAttachmentDefinition{}
+				Expect(r.Get(ctx, types.NamespacedName{Namespace: namespace, Name: networkAttachmentDefinition.Name}, updatedNetworkAttachmentDefinition)).To(Succeed())
+				Expect(updatedNetworkAttachmentDefinition.Spec.Config).To(MatchJSON(testPodNetworkAttachmentDefinition.Spec.Config))
+				err := r.Get(ctx, types.NamespacedName{Namespace: namespace, Name: testPod.Name}, &corev1.Pod{})
+				Expect(apiErrors.IsNotFound(err)).To(BeTrue())
+			})
+			It("should update the config and keep the pod with the manual rollout policy", func() {
+				labInstance := testLabInstance.DeepCopy()
+				labInstance.Spec.RolloutPolicy = ltbv1alpha1.ManualRolloutPolicy
+				r.Client = fake.NewClientBuilder().WithObjects(labInstance, networkAttachmentDefinition, testPodUnlinkedNetworkAttachmentDefinition, testVMNetworkAttachmentDefinition, testPod).Build()
+				returnValue := r.ReconcileNetwork(ctx, labInstance, testLabTemplateWithRenderedNodeSpec)
+				Expect(returnValue.err).To(BeNil())
+				Expect(returnValue.shouldReturn).To(BeFalse())
+				updatedNetworkAttachmentDefinition := &network.NetworkAttachmentDefinition{}
+				Expect(r.Get(ctx, types.NamespacedName{Namespace: namespace, Name: networkAttachmentDefinition.Name}, updatedNetworkAttachmentDefinition)).To(Succeed())
+				Expect(updatedNetworkAttachmentDefinition.Spec.Config).To(MatchJSON(testPodNetworkAttachmentDefinition.Spec.Config))
+				Expect(r.Get(ctx, types.NamespacedName{Namespace: namespace, Name: testPod.Name}, &corev1.Pod{})).To(Succeed())
+			})
+		})
+	})
+
+	Describe("ReconcileLinkConfig", func() {
+		var (
+			ctx context.Context
+		)
+		BeforeEach(func() {
+			ctx = context.Background()
+		})
+		Context("labInstance nil", func() {
+			It("should return error", func() {
+				returnValue := r.ReconcileLinkConfig(ctx, nil, testLabTemplateWithRenderedNodeSpec)
+				Expect(returnValue.err).To(Equal(apiErrors.NewBadRequest("labInstance is nil")))
+				Expect(returnValue.shouldReturn).To(BeTrue())
+			})
+		})
+		Context("labTemplate nil", func() {
+			It("should return error", func() {
+				returnValue := r.ReconcileLinkConfig(ctx, testLabInstance, nil)
+				Expect(returnValue.err).To(Equal(apiErrors.NewBadRequest("labTemplate is nil")))
+				Expect(returnValue.shouldReturn).To(BeTrue())
+			})
+		})
+		Context("ConfigMap doesn't exist", func() {
+			BeforeEach(func() {
+				r.Client = fake.NewClientBuilder().WithObjects(testLabInstance).Build()
+			})
+			It("should create the ConfigMap and requeue", func() {
+				returnValue := r.ReconcileLinkConfig(ctx, testLabInstance, testLabTemplateWithRenderedNodeSpec)
+				Expect(returnValue.err).To(BeNil())
+				Expect(returnValue.result).To(Equal(ctrl.Result{Requeue: true}))
+				configMap := &corev1.ConfigMap{}
+				Expect(r.Get(ctx, types.NamespacedName{Namespace: testLinkConfigMap.Namespace, Name: testLinkConfigMap.Name}, configMap)).To(Succeed())
+				Expect(configMap.Data).To(Equal(testLinkConfigMap.Data))
+			})
+		})
+		Context("ConfigMap exists", func() {
+			BeforeEach(func() {
+				r.Client = fake.NewClientBuilder().WithObjects(testLabInstance, testLinkConfigMap.DeepCopy()).Build()
+			})
+			It("should not return if nothing changed", func() {
+				returnValue := r.ReconcileLinkConfig(ctx, testLabInstance, testLabTemplateWithRenderedNodeSpec)
+				Expect(returnValue.err).To(BeNil())
+				Expect(returnValue.shouldReturn).To(BeFalse())
+			})
+			It("should update the ConfigMap with the impairments of the lab instance", func() {
+				labInstance := testLabInstance.DeepCopy()
+				labInstance.Spec.LinkImpairments = []ltbv1alpha1.LinkImpairment{
+					{Endpoint: ltbv1alpha1.LinkEndpoint{Node: testPodNode.Name, Interface: "eth1"}, Impairment: ltbv1alpha1.Impairment{Rate: "1mbit"}},
+				}
+				returnValue := r.ReconcileLinkConfig(ctx, labInstance, testLabTemplateWithRenderedNodeSpec)
+				Expect(returnValue.err).To(BeNil())
+				Expect(returnValue.shouldReturn).To(BeFalse())
+				configMap := &corev1.ConfigMap{}
+				Expect(r.Get(ctx, types.NamespacedName{Namespace: testLinkConfigMap.Namespace, Name: testLinkConfigMap.Name}, configMap)).To(Succeed())
+				Expect(configMap.Data).To(Equal(map[string]string{testVMNode.Name: "eth1 up rate 1mbit\n", testPodNode.Name: "eth1 up rate 1mbit\n"}))
+			})
+			It("should return error for an impairment of an interface without link", func() {
+				labInstance := testLabInstance.DeepCopy()
+				labInstance.Spec.LinkImpairments = []ltbv1alpha1.LinkImpairment{
+					{Endpoint: ltbv1alpha1.LinkEndpoint{Node: testPodNode.Name, Interface: "eth2"}, Impairment: ltbv1alpha1.Impairment{Rate: "1mbit"}},
+				}
+				returnValue := r.ReconcileLinkConfig(ctx, labInstance, testLabTemplateWithRenderedNodeSpec)
+				Expect(apiErrors.IsBadRequest(returnValue.err)).To(BeTrue())
+				Expect(returnValue.shouldReturn).To(BeTrue())
+			})
+		})
+	})
+
+	Describe("GetLinkImpairment", func() {
+		It("should return the impairment of the lab template", func() {
+			link := testLabTemplateWithRenderedNodeSpec.Spec.Links[0]
+			Expect(GetLinkImpairment(testLabInstance, &link)).To(Equal(link.Impairment))
+		})
+		It("should prefer the impairment of the lab instance", func() {
+			link := testLabTemplateWithRenderedNodeSpec.Spec.Links[0]
+			labInstance := testLabInstance.DeepCopy()
+			labInstance.Spec.LinkImpairments = []ltbv1alpha1.LinkImpairment{{Endpoint: link.Endpoints[1]}}
+			Expect(GetLinkImpairment(labInstance, &link)).To(Equal(&ltbv1alpha1.Impairment{}))
+		})
+	})
+
+	Describe("IsLinkDown", func() {
+		It("should return false if the link is not listed", func() {
+			link := testLabTemplateWithRenderedNodeSpec.Spec.Links[0]
+			Expect(IsLinkDown(testLabInstance, &link)).To(BeFalse())
+		})
+		It("should return true if one of the interfaces of the link is listed", func() {
+			link := testLabTemplateWithRenderedNodeSpec.Spec.Links[0]
+			labInstance := testLabInstance.DeepCopy()
+			labInstance.Spec.DownLinks = []ltbv1alpha1.LinkEndpoint{link.Endpoints[1]}
+			Expect(IsLinkDown(labInstance, &link)).To(BeTrue())
+		})
+	})
+
+	Describe("GetLinkStatus", func() {
+		var labInstance *ltbv1alpha1.LabInstance
+		BeforeEach(func() {
+			labInstance = testLabInstance.DeepCopy()
+			labInstance.Spec.DownLinks = []ltbv1alpha1.LinkEndpoint{{Node: testPodNode.Name, Interface: "eth1"}}
+		})
+		It("should report a link as up if it is not listed", func() {
+			linkStatus := GetLinkStatus(testLabInstance, testLabTemplateWithRenderedNodeSpec, map[string]string{testVMNode.Name: "vm", testPodNode.Name: "pod"})
+			Expect(linkStatus).To(HaveLen(1))
+			Expect(linkStatus[0].State).To(Equal(ltbv1alpha1.LinkStateUp))
+			Expect(linkStatus[0].Endpoints).To(Equal(testLabTemplateWithRenderedNodeSpec.Spec.Links[0].Endpoints))
+		})
+		It("should report a link with a pod as down", func() {
+			linkStatus := GetLinkStatus(labInstance, testLabTemplateWithRenderedNodeSpec, map[string]string{testVMNode.Name: "vm", testPodNode.Name: "pod"})
+			Expect(linkStatus[0].State).To(Equal(ltbv1alpha1.LinkStateDown))
+		})
+		It("should report, that only the interfaces of pods are set down", func() {
+			linkStatus := GetLinkStatus(labInstance, testLabTemplateWithRenderedNodeSpec, map[string]string{testVMNode.Name: "vm", testPodNode.Name: "pod"})
+			Expect(linkStatus[0].Interfaces).To(HaveLen(2))
+			Expect(linkStatus[0].Interfaces[0].State).To(Equal(ltbv1alpha1.LinkStateUp))
+			Expect(linkStatus[0].Interfaces[1].State).To(Equal(ltbv1alpha1.LinkStateDown))
+			Expect(linkStatus[0].Message).To(ContainSubstring("Interface eth1 of VM " + testVMNode.Name + " stays up"))
+		})
+		It("should report, that the impairment is only applied to the interfaces of pods", func() {
+			linkStatus := GetLinkStatus(testLabInstance, testLabTemplateWithRenderedNodeSpec, map[string]string{testVMNode.Name: "vm", testPodNode.Name: "pod"})
+			Expect(linkStatus[0].Interfaces).To(Equal([]ltbv1alpha1.LinkInterfaceStatus{
+				{LinkEndpoint: ltbv1alpha1.LinkEndpoint{Node: testVMNode.Name, Interface: "eth1"}, State: ltbv1alpha1.LinkStateUp, Impaired: false},
+				{LinkEndpoint: ltbv1alpha1.LinkEndpoint{Node: testPodNode.Name, Interface: "eth1"}, State: ltbv1alpha1.LinkStateUp, Impaired: true},
+			}))
+			Expect(linkStatus[0].Message).To(Equal("The impairment isn't applied to interface eth1 of VM " + testVMNode.Name))
+			labInstance.Spec.LinkImpairments = []ltbv1alpha1.LinkImpairment{{Endpoint: ltbv1alpha1.LinkEndpoint{Node: testPodNode.Name, Interface: "eth1"}}}
+			linkStatus = GetLinkStatus(labInstance, testLabTemplateWithRenderedNodeSpec, map[string]string{testVMNode.Name: "vm", testPodNode.Name: "pod"})
+			Expect(linkStatus[0].Interfaces[1].Impaired).To(BeFalse())
+		})
+		It("should report a link between two VMs as up with a message", func() {
+			linkStatus := GetLinkStatus(labInstance, testLabTemplateWithRenderedNodeSpec, map[string]string{testVMNode.Name: "vm", testPodNode.Name: "vm"})
+			Expect(linkStatus[0].State).To(Equal(ltbv1alpha1.LinkStateUp))
+			Expect(linkStatus[0].Message).NotTo(BeEmpty())
+		})
+	})
+
+	Describe("CreateLinkConfigMap", func() {
+		It("should set the interfaces of a down link down", func() {
+			labInstance := testLabInstance.DeepCopy()
+			labInstance.Spec.DownLinks = []ltbv1alpha1.LinkEndpoint{{Node: testVMNode.Name, Interface: "eth1"}}
+			configMap, err := CreateLinkConfigMap(labInstance, testLabTemplateWithRenderedNodeSpec, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(configMap.Data).To(Equal(map[string]string{testVMNode.Name: "eth1 down delay 10ms loss 1%\n", testPodNode.Name: "eth1 down delay 10ms loss 1%\n"}))
+		})
+		It("should return error for a down link, which is not part of the lab template", func() {
+			labInstance := testLabInstance.DeepCopy()
+			labInstance.Spec.DownLinks = []ltbv1alpha1.LinkEndpoint{{Node: testVMNode.Name, Interface: "eth9"}}
+			_, err := CreateLinkConfigMap(labInstance, testLabTemplateWithRenderedNodeSpec, nil)
+			Expect(apiErrors.IsBadRequest(err)).To(BeTrue())
+		})
+		It("should list the link interfaces of every node", func() {
+			configMap, err := CreateLinkConfigMap(testLabInstance, testLabTemplateWithRenderedNodeSpec, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(configMap.Name).To(Equal(testLinkConfigMap.Name))
+			Expect(configMap.Data).To(Equal(testLinkConfigMap.Data))
+		})
+		It("should list the capture containers of the lab instance", func() {
+			otherPacketCapture := testVMPacketCapture.DeepCopy()
+			otherPacketCapture.Spec.LabInstanceRef = "other-labinstance"
+			configMap, err := CreateLinkConfigMap(testLabInstance, testLabTemplateWithRenderedNodeSpec, []ltbv1alpha1.PacketCapture{*testPacketCapture, *otherPacketCapture})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(configMap.Data).To(HaveKeyWithValue(testPodNode.Name+".captures", "capture-01234567\n"))
+			Expect(configMap.Data).NotTo(HaveKey(testVMNode.Name + ".captures"))
+		})
+		It("should return error for an invalid impairment", func() {
+			labTemplate := testLabTemplateWithRenderedNodeSpec.DeepCopy()
+			labTemplate.Spec.Links[0].Impairment = &ltbv1alpha1.Impairment{Delay: "10ms; reboot"}
+			_, err := CreateLinkConfigMap(testLabInstance, labTemplate, nil)
+			Expect(apiErrors.IsBadRequest(err)).To(BeTrue())
+		})
+	})
+
+	Describe("ValidateLinks", func() {
+		It("should accept valid links", func() {
+			Expect(ValidateLinks(testLabTemplateWithRenderedNodeSpec)).To(Succeed())
+		})
+		It("should reject a link to an unknown node", func() {
+			Expect(apiErrors.IsBadRequest(ValidateLinks(testLabTemplateWithInvalidLink))).To(BeTrue())
+		})
+		It("should reject an interface which is used twice", func() {
+			labTemplate := testLabTemplateWithRenderedNodeSpec.DeepCopy()
+			labTemplate.Spec.Links = append(labTemplate.Spec.Links, labTemplate.Spec.Links[0])
+			Expect(apiErrors.IsBadRequest(ValidateLinks(labTemplate))).To(BeTrue())
+		})
+		It("should reject a link without two endpoints", func() {
+			labTemplate := testLabTemplateWithRenderedNodeSpec.DeepCopy()
+			labTemplate.Spec.Links[0].Endpoints = labTemplate.Spec.Links[0].Endpoints[:1]
+			Expect(apiErrors.IsBadRequest(ValidateLinks(labTemplate))).To(BeTrue())
+		})
+		It("should reject interfaces, which have the same name after sanitizing", func() {
+			labTemplate := testLabTemplateWithRenderedNodeSpec.DeepCopy()
+			labTemplate.Spec.Nodes[1].Interfaces[0].Name = "eth1/1"
+			labTemplate.Spec.Nodes[1].Interfaces[1].Name = "eth1-1"
+			err := ValidateLinks(labTemplate)
+			Expect(apiErrors.IsBadRequest(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("have the same resource name"))
+		})
+	})
+
+	Describe("ValidateInterfaces", func() {
+		var labTemplate *ltbv1alpha1.LabTemplate
+		BeforeEach(func() {
+			labTemplate = testLabTemplateWithRenderedNodeSpec.DeepCopy()
+		})
+		It("should accept valid interfaces", func() {
+			Expect(ValidateInterfaces(labTemplate)).To(Succeed())
+		})
+		It("should reject an interface without name", func() {
+			labTemplate.Spec.Nodes[1].Interfaces[0].Name = ""
+			Expect(apiErrors.IsBadRequest(ValidateInterfaces(labTemplate))).To(BeTrue())
+		})
+		It("should reject an interface which is declared twice", func() {
+			labTemplate.Spec.Nodes[1].Interfaces[0].Name = "eth1"
+			Expect(apiErrors.IsBadRequest(ValidateInterfaces(labTemplate))).To(BeTrue())
+		})
+		It("should reject an IPv6 address as IPv4 address", func() {
+			labTemplate.Spec.Nodes[1].Interfaces[1].IPv4 = "2001:db8::1/64"
+			Expect(apiErrors.IsBadRequest(ValidateInterfaces(labTemplate))).To(BeTrue())
+		})
+		It("should reject an IPv4 address as IPv6 address", func() {
+			labTemplate.Spec.Nodes[1].Interfaces[1].IPv6 = "10.0.0.1/24"
+			Expect(apiErrors.IsBadRequest(ValidateInterfaces(labTemplate))).To(BeTrue())
+		})
+	})
+
+	Describe("GetNodeInterfaces", func() {
+		It("should return the declared interfaces in order", func() {
+			interfaces := GetNodeInterfaces(testLabTemplateWithRenderedNodeSpec, testPodNode)
+			Expect(interfaces).To(Equal(testPodNode.Interfaces))
+		})
+		It("should append the linked interfaces, which are not declared", func() {
+			interfaces := GetNodeInterfaces(testLabTemplateWithRenderedNodeSpec, testVMNode)
+			Expect(interfaces).To(Equal([]ltbv1alpha1.NodeInterface{{Name: "eth1"}}))
+		})
+		It("should return no interfaces for an unlinked node", func() {
+			Expect(GetNodeInterfaces(testLabTemplateWithRenderedNodeSpec, nodeWithUndefinedNodeType)).To(BeEmpty())
+		})
+	})
+
+	Describe("LinkSegmentName", func() {
+		It("should return a valid linux interface name independent of the endpoint order", func() {
+			link := testLabTemplateWithRenderedNodeSpec.Spec.Links[0]
+			reversedLink := ltbv1alpha1.Link{Endpoints: []ltbv1alpha1.LinkEndpoint{link.Endpoints[1], link.Endpoints[0]}}
+			segment := LinkSegmentName(testLabInstance, &link)
+			Expect(len(segment)).To(BeNumerically("<=", 15))
+			Expect(LinkSegmentName(testLabInstance, &reversedLink)).To(Equal(segment))
+		})
+	})
+
+	Describe("InterfaceSegmentName", func() {
+		It("should return the segment of the link for a linked interface", func() {
+			link := testLabTemplateWithRenderedNodeSpec.Spec.Links[0]
+			Expect(InterfaceSegmentName(testLabInstance, testLabTemplateWithRenderedNodeSpec, testPodNode.Name, "eth1")).To(Equal(LinkSegmentName(testLabInstance, &link)))
+		})
+		It("should return a segment of its own for an unlinked interface", func() {
+			link := testLabTemplateWithRenderedNodeSpec.Spec.Links[0]
+			segment := InterfaceSegmentName(testLabInstance, testLabTemplateWithRenderedNodeSpec, testPodNode.Name, "eth2")
+			Expect(segment).NotTo(Equal(LinkSegmentName(testLabInstance, &link)))
+			Expect(len(segment)).To(BeNumerically("<=", 15))
+		})
+	})
+
+	Describe("NetworkAttachmentDefinitionName", func() {
+		It("should convert the interface name into a valid resource name", func() {
+			Expect(NetworkAttachmentDefinitionName(testLabInstance, testPodNode.Name, "Ethernet1/1")).To(Equal(testLabInstance.Name + "-" + testPodNode.Name + "-ethernet1-1"))
+		})
+	})
+
+	Describe("CreateNetworkAttachmentDefinition", func() {
+		var bridgeBackend labnet.Backend
+		BeforeEach(func() {
+			bridgeBackend, _ = labnet.NewBackend(labnet.Bridge, labnet.Config{})
+		})
+		It("should configure static addresses of the interface", func() {
+			networkAttachmentDefinition, err := CreateNetworkAttachmentDefinition(testLabInstance, testPodNode.Name, &testPodNode.Interfaces[1], "ltb4f84fa63049a", bridgeBackend)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(networkAttachmentDefinition.Name).To(Equal(testPodNetworkAttachmentDefinition.Name))
+			Expect(networkAttachmentDefinition.Namespace).To(Equal(testPodNetworkAttachmentDefinition.Namespace))
+			Expect(networkAttachmentDefinition.Spec.Config).To(MatchJSON(testPodNetworkAttachmentDefinition.Spec.Config))
+		})
+		It("should configure addresses from the subnet of the lab instance for an interface without addresses", func() {
+			labInstance := testLabInstance.DeepCopy()
+			labInstance.Status.Network.Subnet = "10.10.3.0/24"
+			networkAttachmentDefinition, err := CreateNetworkAttachmentDefinition(labInstance, testVMNode.Name, &ltbv1alpha1.NodeInterface{Name: "eth1"}, "ltb4f84fa63049a", bridgeBackend)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(networkAttachmentDefinition.Spec.Config).To(MatchJSON(`{
+				"cniVersion": "0.3.1",
+				"name": "ltb6457a0eb1b30",
+				"type": "bridge",
+				"bridge": "ltb4f84fa63049a",
+				"ipam": {
+					"type": "host-local",
+					"ranges": [[{"subnet": "10.10.3.0/24"}]]
+				}
+			}`))
+		})
+		It("should not configure addresses for an interface without addresses", func() {
+			networkAttachmentDefinition, err := CreateNetworkAttachmentDefinition(testLabInstance, testVMNode.Name, &ltbv1alpha1.NodeInterface{Name: "eth1"}, "ltb4f84fa63049a", bridgeBackend)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(networkAttachmentDefinition.Name).To(Equal(testVMNetworkAttachmentDefinition.Name))
+			Expect(networkAttachmentDefinition.Spec.Config).To(MatchJSON(testVMNetworkAttachmentDefinition.Spec.Config))
+		})
+		It("should use the segment ID of the lab instance", func() {
+			vxlanBackend, _ := labnet.NewBackend(labnet.VXLAN, labnet.Config{})
+			labInstance := testLabInstance.DeepCopy()
+			labInstance.Status.Network.Segments = map[string]int32{"ltb4f84fa63049a": 42}
+			networkAttachmentDefinition, err := CreateNetworkAttachmentDefinition(labInstance, testVMNode.Name, &ltbv1alpha1.NodeInterface{Name: "eth1"}, "ltb4f84fa63049a", vxlanBackend)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(networkAttachmentDefinition.Spec.Config).To(MatchJSON(`{
+				"cniVersion": "0.3.1",
+				"name": "ltb6457a0eb1b30",
+				"type": "bridge",
+				"bridge": "ltb-vxlan0",
+				"vlan": 42,
+				"ipam": {}
+			}`))
+		})
+		It("should return the error of the backend", func() {
+			ovnK8sBackend, _ := labnet.NewBackend(labnet.OVNK8s, labnet.Config{})
+			_, err := CreateNetworkAttachmentDefinition(testLabInstance, testPodNode.Name, &testPodNode.Interfaces[1], "ltb4f84fa63049a", ovnK8sBackend)
+			Expect(err).To(HaveOccurred())
+		})
 	})
 
 	Describe("ReconcileResource", func() {
@@ -239,7 +804,7 @@ var _ = Describe("LabInstance Controller", func() {
 			It("should not create a resource, but retrieve it", func() {
 				resource := &corev1.Pod{}
 				resource.Name = testLabInstance.Name + "-" + testPodNode.Name
-				returnValue := r.ReconcileResource(testLabInstance, resource, nil, "")
+				returnValue := r.ReconcileResource(testLabInstance, resource, testPodNode, "")
 				Expect(resource.GetName()).To(Equal(testPod.Name))
 				Expect(resource.GetNamespace()).To(Equal(testPod.Namespace))
 				Expect(resource.GetAnnotations()).To(Equal(testPod.Annotations))
@@ -269,6 +834,137 @@ var _ = Describe("LabInstance Controller", func() {
 				Expect(returnValue.shouldReturn).To(BeTrue())
 			})
 		})
+		Context("Resource of a deployed lab instance was deleted", func() {
+			It("should show the missing resource in the status, while it is created again", func() {
+				labInstance := testLabInstance.DeepCopy()
+				recorder := record.NewFakeRecorder(10)
+				r.Client = fake.NewClientBuilder().WithObjects(labInstance).Build()
+				r.Recorder = recorder
+				resource := &corev1.Service{}
+				resource.Name = testService.Name
+				returnValue := r.ReconcileResource(labInstance, resource, testVMNode, "")
+				Expect(returnValue.err).To(BeNil())
+				Expect(returnValue.result).To(Equal(ctrl.Result{Requeue: true}))
+				updatedLabInstance := &ltbv1alpha1.LabInstance{}
+				err := r.Get(context.Background(), types.NamespacedName{Namespace: namespace, Name: testLabInstance.Name}, updatedLabInstance)
+				Expect(err).To(BeNil())
+				Expect(updatedLabInstance.Status.Status).To(Equal("Missing Service " + testService.Name))
+				Expect(recorder.Events).To(Receive(HavePrefix("Warning ResourceMissing")))
+				Expect(recorder.Events).To(Receive(Equal("Normal Created Created Service " + testService.Name)))
+			})
+		})
+		Context("Resource has drifted", func() {
+			It("should recreate a pod with a changed spec", func() {
+				pod := testPod.DeepCopy()
+				pod.Spec.Containers[0].Image = "ubuntu:20.04"
+				recorder := record.NewFakeRecorder(10)
+				r.Client = fake.NewClientBuilder().WithObjects(testLabInstance, pod).Build()
+				r.Recorder = recorder
+				resource := &corev1.Pod{}
+				resource.Name = testPod.Name
+				returnValue := r.ReconcileResource(testLabInstance, resource, testPodNode, "")
+				Expect(recorder.Events).To(Receive(Equal("Normal Recreating Recreating Pod " + testPod.Name + ", its spec changed")))
+				Expect(returnValue.result).To(Equal(ctrl.Result{RequeueAfter: resourceRecreateInterval}))
+				Expect(returnValue.err).To(BeNil())
+				Expect(returnValue.shouldReturn).To(BeTrue())
+				err := r.Get(context.Background(), types.NamespacedName{Namespace: namespace, Name: testPod.Name}, &corev1.Pod{})
+				Expect(apiErrors.IsNotFound(err)).To(BeTrue())
+			})
+			It("should update the ports of a service in place", func() {
+				service := testService.DeepCopy()
+				service.Spec.Ports[0].Port = 2222
+				r.Client = fake.NewClientBuilder().WithObjects(testLabInstance, service).Build()
+				resource := &corev1.Service{}
+				resource.Name = testService.Name
+				returnValue := r.ReconcileResource(testLabInstance, resource, testVMNode, "")
+				Expect(returnValue.err).To(BeNil())
+				Expect(returnValue.shouldReturn).To(BeFalse())
+				updatedService := &corev1.Service{}
+				err := r.Get(context.Background(), types.NamespacedName{Namespace: namespace, Name: testService.Name}, updatedService)
+				Expect(err).To(BeNil())
+				Expect(updatedService.Spec.Ports[0].Port).To(BeEquivalentTo(22))
+			})
+		})
+	})
+
+	Describe("UpdateResource", func() {
+		It("should keep a drifted pod with the manual rollout policy", func() {
+			labInstance := testLabInstance.DeepCopy()
+			labInstance.Spec.RolloutPolicy = ltbv1alpha1.ManualRolloutPolicy
+			pod := testPod.DeepCopy()
+			pod.Spec.Containers[0].Image = "ubuntu:20.04"
+			r.Client = fake.NewClientBuilder().WithObjects(labInstance, pod).Build()
+			returnValue := r.UpdateResource(context.Background(), labInstance, pod, testPodNode, "")
+			Expect(returnValue.err).To(BeNil())
+			Expect(returnValue.shouldReturn).To(BeFalse())
+			err := r.Get(context.Background(), types.NamespacedName{Namespace: namespace, Name: testPod.Name}, &corev1.Pod{})
+			Expect(err).To(BeNil())
+		})
+	})
+
+	Describe("labInstancesOfLabTemplate", func() {
+		It("should requeue the lab instances, which use the lab template", func() {
+			otherLabInstance := testLabInstance.DeepCopy()
+			otherLabInstance.Name = "other-labinstance"
+			otherLabInstance.Spec.LabTemplateReference = "other-labtemplate"
+			r.Client = fake.NewClientBuilder().WithObjects(testLabInstance, otherLabInstance).WithIndex(&ltbv1alpha1.LabInstance{}, labTemplateRefField, LabTemplateRef).Build()
+			requests := r.labInstancesOfLabTemplate(testLabTemplateWithRenderedNodeSpec)
+			Expect(requests).To(HaveLen(1))
+			Expect(requests[0].NamespacedName).To(Equal(types.NamespacedName{Namespace: namespace, Name: testLabInstance.Name}))
+		})
+	})
+
+	Describe("MergeResource", func() {
+		It("should ignore fields, which are set by the API server", func() {
+			pod := testPod.DeepCopy()
+			pod.Spec.RestartPolicy = corev1.RestartPolicyAlways
+			pod.Spec.Containers[0].ImagePullPolicy = corev1.PullIfNotPresent
+			updated, recreate := MergeResource(pod, testPod.DeepCopy())
+			Expect(updated).To(BeFalse())
+			Expect(recreate).To(BeFalse())
+		})
+		It("should add labels without removing others", func() {
+			pod := testPod.DeepCopy()
+			pod.Labels = map[string]string{"other": "label"}
+			updated, recreate := MergeResource(pod, testPod.DeepCopy())
+			Expect(updated).To(BeTrue())
+			Expect(recreate).To(BeFalse())
+			Expect(pod.Labels).To(HaveKeyWithValue("other", "label"))
+			Expect(pod.Labels).To(HaveKeyWithValue("app", testPod.Labels["app"]))
+		})
+		It("should recreate a pod with changed interfaces", func() {
+			pod := testPod.DeepCopy()
+			pod.Annotations[network.NetworkAttachmentAnnot] = "[]"
+			_, recreate := MergeResource(pod, testPod.DeepCopy())
+			Expect(recreate).To(BeTrue())
+		})
+		It("should update the spec of a VM", func() {
+			vm := testVM.DeepCopy()
+			desiredVM := testVM.DeepCopy()
+			running := false
+			desiredVM.Spec.Running = &running
+			updated, recreate := MergeResource(vm, desiredVM)
+			Expect(updated).To(BeTrue())
+			Expect(recreate).To(BeFalse())
+			Expect(*vm.Spec.Running).To(BeFalse())
+		})
+		It("should update the host of an ingress", func() {
+			ingress := testPodIngress.DeepCopy()
+			labInstance := testLabInstance.DeepCopy()
+			labInstance.Spec.DNSAddress = "example.com"
+			desiredIngress, err := CreateIngress(labInstance, testPodNode, "pod")
+			Expect(err).NotTo(HaveOccurred())
+			updated, recreate := MergeResource(ingress, desiredIngress)
+			Expect(updated).To(BeTrue())
+			Expect(recreate).To(BeFalse())
+			Expect(ingress.Spec.Rules[0].Host).To(HaveSuffix(".example.com"))
+		})
+		It("should recreate a role binding with a changed role", func() {
+			desiredRoleBinding := testRoleBinding.DeepCopy()
+			desiredRoleBinding.RoleRef.Name = "other-role"
+			_, recreate := MergeResource(testRoleBinding.DeepCopy(), desiredRoleBinding)
+			Expect(recreate).To(BeTrue())
+		})
 	})
 
 	Describe("CreateResource", func() {
@@ -411,6 +1107,79 @@ var _ = Describe("LabInstance Controller", func() {
 		})
 	})
 
+	Describe("GetRenderedNodeSpec", func() {
+		It("should return the rendered node spec", func() {
+			renderedNodeSpec, returnValue := GetRenderedNodeSpec(testLabTemplateWithRenderedNodeSpec, testLabInstance, testPodNode)
+			Expect(returnValue.shouldReturn).To(BeFalse())
+			Expect(renderedNodeSpec).To(Equal(testPodNode.RenderedNodeSpec))
+		})
+		It("should wait, if the current spec hasn't been rendered yet", func() {
+			labTemplate := testLabTemplateWithRenderedNodeSpec.DeepCopy()
+			labTemplate.Generation = 2
+			labTemplate.Status.ObservedGeneration = 1
+			_, returnValue := GetRenderedNodeSpec(labTemplate, testLabInstance, testPodNode)
+			Expect(returnValue.shouldReturn).To(BeTrue())
+			Expect(returnValue.err).To(BeNil())
+			Expect(returnValue.result.RequeueAfter).ToNot(BeZero())
+		})
+		It("should return error, if the node failed to render", func() {
+			labTemplate := testLabTemplateWithRenderedNodeSpec.DeepCopy()
+			labTemplate.Status.Nodes[1].Conditions[0].Status = metav1.ConditionFalse
+			labTemplate.Status.Nodes[1].Conditions[0].Message = "NodeType test not found"
+			_, returnValue := GetRenderedNodeSpec(labTemplate, testLabInstance, testPodNode)
+			Expect(returnValue.shouldReturn).To(BeTrue())
+			Expect(apiErrors.IsBadRequest(returnValue.err)).To(BeTrue())
+			Expect(returnValue.err.Error()).To(ContainSubstring("NodeType test not found"))
+		})
+		It("should render the node spec of the node type with the lab instance", func() {
+			labTemplate := testLabTemplateWithRenderedNodeSpec.DeepCopy()
+			labTemplate.Status.Nodes[1].NodeSpec = "hostname: {{ .Instance.Name }}-{{ .Node.Index }}\npeer: {{ (index .Links 0).Peer }}"
+			renderedNodeSpec, returnValue := GetRenderedNodeSpec(labTemplate, testLabInstance, testPodNode)
+			Expect(returnValue.shouldReturn).To(BeFalse())
+			Expect(renderedNodeSpec).To(Equal("hostname: " + testLabInstance.Name + "-1\npeer: " + testVMNode.Name))
+		})
+		It("should return error, if the node spec can't be rendered with the lab instance", func() {
+			labTemplate := testLabTemplateWithRenderedNodeSpec.DeepCopy()
+			labTemplate.Status.Nodes[1].NodeSpec = "peer: {{ (index .Links 1).Peer }}"
+			_, returnValue := GetRenderedNodeSpec(labTemplate, testLabInstance, testPodNode)
+			Expect(returnValue.shouldReturn).To(BeTrue())
+			Expect(apiErrors.IsBadRequest(returnValue.err)).To(BeTrue())
+		})
+		It("should render the parameters and overrides of the lab instance", func() {
+			labTemplate := testLabTemplateWithRenderedNodeSpec.DeepCopy()
+			labTemplate.Spec.Parameters = []ltbv1alpha1.Parameter{{Name: "subnet", Type: ltbv1alpha1.CIDRParameterType, Required: true}}
+			labTemplate.Status.Nodes[1].NodeSpec = "image: {{ .NodeTypeRef.Image }}\naddress: {{ cidrhost .Params.subnet .Node.Index }}"
+			labInstance := testLabInstance.DeepCopy()
+			labInstance.Spec.Parameters = []ltbv1alpha1.ParameterValue{{Name: "subnet", Value: "10.1.0.0/24"}}
+			labInstance.Spec.NodeOverrides = []ltbv1alpha1.NodeOverride{{Node: testPodNode.Name, Image: "debian"}}
+			renderedNodeSpec, returnValue := GetRenderedNodeSpec(labTemplate, labInstance, testPodNode)
+			Expect(returnValue.shouldReturn).To(BeFalse())
+			Expect(renderedNodeSpec).To(Equal("image: debian\naddress: 10.1.0.1"))
+		})
+		It("should return error, if a required parameter is missing", func() {
+			labTemplate := testLabTemplateWithRenderedNodeSpec.DeepCopy()
+			labTemplate.Spec.Parameters = []ltbv1alpha1.Parameter{{Name: "subnet", Type: ltbv1alpha1.CIDRParameterType, Required: true}}
+			labTemplate.Status.Nodes[1].NodeSpec = "address: {{ cidrhost .Params.subnet 1 }}"
+			_, returnValue := GetRenderedNodeSpec(labTemplate, testLabInstance, testPodNode)
+			Expect(returnValue.shouldReturn).To(BeTrue())
+			Expect(apiErrors.IsBadRequest(returnValue.err)).To(BeTrue())
+			Expect(returnValue.err.Error()).To(ContainSubstring("parameter subnet is required"))
+		})
+	})
+
+	Describe("renderedNodesChangedPredicate", func() {
+		It("should pass updates of the rendered nodes", func() {
+			newLabTemplate := testLabTemplateWithRenderedNodeSpec.DeepCopy()
+			newLabTemplate.Status.Nodes[0].RenderedNodeSpec = "running: false"
+			Expect(renderedNodesChangedPredicate.Update(event.UpdateEvent{ObjectOld: testLabTemplateWithRenderedNodeSpec, ObjectNew: newLabTemplate})).To(BeTrue())
+		})
+		It("should filter other status updates", func() {
+			newLabTemplate := testLabTemplateWithRenderedNodeSpec.DeepCopy()
+			newLabTemplate.Status.ObservedGeneration = 3
+			Expect(renderedNodesChangedPredicate.Update(event.UpdateEvent{ObjectOld: testLabTemplateWithRenderedNodeSpec, ObjectNew: newLabTemplate})).To(BeFalse())
+		})
+	})
+
 	Describe("MapTemplateToPod", func() {
 		Context("Invalid lab instance", func() {
 			It("Lab instance nil should return error", func() {
@@ -437,6 +1206,41 @@ var _ = Describe("LabInstance Controller", func() {
 				Expect(pod.Namespace).To(Equal(testPod.Namespace))
 				Expect(pod.Labels).To(Equal(testPod.Labels))
 			})
+			It("Node without interfaces, should not attach secondary networks", func() {
+				pod, err := MapTemplateToPod(testLabInstance, testVMNode)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(pod.Annotations).To(BeEmpty())
+			})
+			It("Node with interfaces, should attach the interfaces in order", func() {
+				pod, err := MapTemplateToPod(testLabInstance, testPodNode)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(pod.Annotations["k8s.v1.cni.cncf.io/networks"]).To(MatchJSON(testPod.Annotations["k8s.v1.cni.cncf.io/networks"]))
+			})
+			It("Node with interfaces, should get the link sidecar", func() {
+				pod, err := MapTemplateToPod(testLabInstance, testPodNode)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(pod.Spec.Containers[len(pod.Spec.Containers)-1].Name).To(Equal(labnet.LinkContainerName))
+				Expect(pod.Spec.Volumes[len(pod.Spec.Volumes)-2].ConfigMap.Name).To(Equal(testLinkConfigMap.Name))
+				Expect(pod.Spec.Volumes[len(pod.Spec.Volumes)-1].Name).To(Equal(labnet.CaptureVolumeName))
+				Expect(pod.Spec.Volumes[len(pod.Spec.Volumes)-1].EmptyDir).NotTo(BeNil())
+				Expect(pod.Annotations["kubectl.kubernetes.io/default-container"]).To(Equal(pod.Spec.Containers[0].Name))
+			})
+			It("Node without interfaces, should not get the link sidecar", func() {
+				pod, err := MapTemplateToPod(testLabInstance, testVMNode)
+				Expect(err).NotTo(HaveOccurred())
+				for _, container := range pod.Spec.Containers {
+					Expect(container.Name).NotTo(Equal(labnet.LinkContainerName))
+				}
+			})
+			It("Node with overridden resources, should replace the resources of the first container", func() {
+				labInstance := testLabInstance.DeepCopy()
+				resources := corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")}}
+				labInstance.Spec.NodeOverrides = []ltbv1alpha1.NodeOverride{{Node: testPodNode.Name, Resources: &resources}}
+				pod, err := MapTemplateToPod(labInstance, testPodNode)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(pod.Spec.Containers[0].Resources).To(Equal(resources))
+				Expect(pod.Spec.Containers[len(pod.Spec.Containers)-1].Resources).NotTo(Equal(resources))
+			})
 		})
 	})
 
@@ -465,6 +1269,24 @@ var _ = Describe("LabInstance Controller", func() {
 				Expect(vm.Name).To(Equal(testVM.Name))
 				Expect(vm.Namespace).To(Equal(testVM.Namespace))
 				Expect(vm.Labels).To(Equal(testVM.Labels))
+				Expect(vm.Spec.Template.Spec.Networks).To(HaveLen(1))
+			})
+			It("Node with interfaces, should attach the interfaces after the pod network", func() {
+				node := testVMNode.DeepCopy()
+				node.Interfaces = GetNodeInterfaces(testLabTemplateWithRenderedNodeSpec, node)
+				vm, err := MapTemplateToVM(testLabInstance, node)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(vm.Spec.Template.Spec.Networks).To(HaveLen(2))
+				Expect(vm.Spec.Template.Spec.Networks[1].Multus.NetworkName).To(Equal(testVMNetworkAttachmentDefinition.Name))
+				Expect(vm.Spec.Template.Spec.Domain.Devices.Interfaces[1].Name).To(Equal(vm.Spec.Template.Spec.Networks[1].Name))
+			})
+			It("Node with overridden resources, should replace the resources of the domain", func() {
+				labInstance := testLabInstance.DeepCopy()
+				resources := corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("8Gi")}}
+				labInstance.Spec.NodeOverrides = []ltbv1alpha1.NodeOverride{{Node: testVMNode.Name, Resources: &resources}}
+				vm, err := MapTemplateToVM(labInstance, testVMNode)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(vm.Spec.Template.Spec.Domain.Resources.Requests).To(Equal(resources.Requests))
 			})
 		})
 	})
@@ -608,6 +1430,179 @@ var _ = Describe("LabInstance Controller", func() {
 		})
 	})
 
+	Describe("UpdateLabInstanceStatus counting", func() {
+		It("should count the running pods after a pending pod", func() {
+			labInstance := testLabInstance.DeepCopy()
+			err := UpdateLabInstanceStatus([]*corev1.Pod{testPodUndefinedNode, testPod}, []*kubevirtv1.VirtualMachine{testVM2, testVM}, labInstance)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(labInstance.Status.Status).To(Equal("Pending"))
+			Expect(labInstance.Status.NumPodsRunning).To(Equal("1/2"))
+			Expect(labInstance.Status.NumVMsRunning).To(Equal("1/2"))
+		})
+	})
+
+	Describe("GetPodNodeStatus", func() {
+		It("should show the phase, readiness, addresses and host of the pod", func() {
+			pod := testPod.DeepCopy()
+			pod.Spec.NodeName = "worker-1"
+			pod.Status.PodIPs = []corev1.PodIP{{IP: "10.244.0.5"}}
+			pod.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}
+			nodeStatus := GetPodNodeStatus(testPodNode.Name, pod)
+			Expect(nodeStatus).To(Equal(ltbv1alpha1.NodeStatus{Name: testPodNode.Name, Kind: "pod", Phase: "Running", Ready: true, IPs: []string{"10.244.0.5"}, HostNode: "worker-1"}))
+		})
+		It("should show why a container doesn't start", func() {
+			pod := testPod.DeepCopy()
+			pod.Status.Phase = corev1.PodPending
+			pod.Status.ContainerStatuses = []corev1.ContainerStatus{{Name: "testnode", State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ImagePullBackOff"}}}}
+			nodeStatus := GetPodNodeStatus(testPodNode.Name, pod)
+			Expect(nodeStatus.Ready).To(BeFalse())
+			Expect(nodeStatus.LastError).To(Equal("Container testnode: ImagePullBackOff"))
+		})
+	})
+
+	Describe("GetVMNodeStatus", func() {
+		It("should show the addresses and host of the VM instance", func() {
+			vmi := &kubevirtv1.VirtualMachineInstance{Status: kubevirtv1.VirtualMachineInstanceStatus{
+				NodeName:   "worker-2",
+				Interfaces: []kubevirtv1.VirtualMachineInstanceNetworkInterface{{IPs: []string{"10.244.0.6", "fd00::6"}}},
+			}}
+			nodeStatus := GetVMNodeStatus(testVMNode.Name, testVM, vmi)
+			Expect(nodeStatus).To(Equal(ltbv1alpha1.NodeStatus{Name: testVMNode.Name, Kind: "vm", Phase: "VM Ready", Ready: true, IPs: []string{"10.244.0.6", "fd00::6"}, HostNode: "worker-2"}))
+		})
+		It("should show an error status of the VM", func() {
+			vm := testVM.DeepCopy()
+			vm.Status.Ready = false
+			vm.Status.PrintableStatus = kubevirtv1.VirtualMachineStatusCrashLoopBackOff
+			nodeStatus := GetVMNodeStatus(testVMNode.Name, vm, nil)
+			Expect(nodeStatus.LastError).To(Equal(string(kubevirtv1.VirtualMachineStatusCrashLoopBackOff)))
+		})
+	})
+
+	Describe("GetWebTerminalURL", func() {
+		It("should return the URL of the host of the ingress", func() {
+			ingress, err := CreateIngress(testLabInstance, testPodNode, "pod")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(GetWebTerminalURL(ingress)).To(Equal("https://" + testPod.Name + ".example.com/"))
+		})
+		It("should return an empty URL for an ingress without host", func() {
+			Expect(GetWebTerminalURL(testPodIngress)).To(BeEmpty())
+		})
+	})
+
+	Describe("GetRemoteAccessEndpoints", func() {
+		It("should list every port for every address of the load balancer", func() {
+			service := testService.DeepCopy()
+			service.Status.LoadBalancer.Ingress = append(service.Status.LoadBalancer.Ingress, corev1.LoadBalancerIngress{Hostname: "lb.example.com"})
+			Expect(GetRemoteAccessEndpoints(service)).To(Equal([]ltbv1alpha1.RemoteAccessEndpoint{
+				{Name: "test-ssh-port", Host: "192.0.2.10", Port: 22, Protocol: "TCP"},
+				{Name: "test-ssh-port", Host: "lb.example.com", Port: 22, Protocol: "TCP"},
+			}))
+		})
+		It("should not list any endpoint, while the load balancer has no address", func() {
+			service := testService.DeepCopy()
+			service.Status = corev1.ServiceStatus{}
+			Expect(GetRemoteAccessEndpoints(service)).To(BeEmpty())
+		})
+	})
+
+	Describe("UpdateLabInstanceConditions", func() {
+		It("should be ready, if all nodes are ready", func() {
+			labInstance := testLabInstance.DeepCopy()
+			labInstance.Generation = 3
+			labInstance.Status.Nodes = []ltbv1alpha1.NodeStatus{{Name: testPodNode.Name, Ready: true}}
+			UpdateLabInstanceConditions(labInstance)
+			Expect(meta.IsStatusConditionTrue(labInstance.Status.Conditions, ltbv1alpha1.NetworkReadyCondition)).To(BeTrue())
+			Expect(meta.IsStatusConditionTrue(labInstance.Status.Conditions, ltbv1alpha1.NodesReadyCondition)).To(BeTrue())
+			Expect(meta.IsStatusConditionTrue(labInstance.Status.Conditions, ltbv1alpha1.RemoteAccessReadyCondition)).To(BeTrue())
+			Expect(meta.IsStatusConditionFalse(labInstance.Status.Conditions, ltbv1alpha1.DegradedCondition)).To(BeTrue())
+			Expect(labInstance.Status.ObservedGeneration).To(BeEquivalentTo(3))
+		})
+		It("should list the nodes, which are not ready or failed", func() {
+			labInstance := testLabInstance.DeepCopy()
+			labInstance.Status.Nodes = []ltbv1alpha1.NodeStatus{{Name: testPodNode.Name, LastError: "Container testnode: ImagePullBackOff"}, {Name: testVMNode.Name, Ready: true}}
+			UpdateLabInstanceConditions(labInstance)
+			nodesReady := meta.FindStatusCondition(labInstance.Status.Conditions, ltbv1alpha1.NodesReadyCondition)
+			Expect(nodesReady.Status).To(Equal(metav1.ConditionFalse))
+			Expect(nodesReady.Message).To(Equal("Not ready: " + testPodNode.Name))
+			degraded := meta.FindStatusCondition(labInstance.Status.Conditions, ltbv1alpha1.DegradedCondition)
+			Expect(degraded.Status).To(Equal(metav1.ConditionTrue))
+			Expect(degraded.Message).To(ContainSubstring("ImagePullBackOff"))
+		})
+	})
+
+	Describe("ReturnWithCondition", func() {
+		It("should mark the lab instance as degraded on errors", func() {
+			labInstance := testLabInstance.DeepCopy()
+			recorder := record.NewFakeRecorder(10)
+			r.Client = fake.NewClientBuilder().WithObjects(labInstance).Build()
+			r.Recorder = recorder
+			retValue := ReturnToReconciler{shouldReturn: true, err: apiErrors.NewBadRequest("Invalid network backend")}
+			_, err := r.ReturnWithCondition(context.Background(), labInstance, ltbv1alpha1.NetworkReadyCondition, retValue)
+			Expect(apiErrors.IsBadRequest(err)).To(BeTrue())
+			Expect(recorder.Events).To(Receive(Equal("Warning NetworkReadyFailed Invalid network backend")))
+			updatedLabInstance := &ltbv1alpha1.LabInstance{}
+			err = r.Get(context.Background(), types.NamespacedName{Namespace: namespace, Name: testLabInstance.Name}, updatedLabInstance)
+			Expect(err).To(BeNil())
+			Expect(meta.IsStatusConditionFalse(updatedLabInstance.Status.Conditions, ltbv1alpha1.NetworkReadyCondition)).To(BeTrue())
+			Expect(meta.IsStatusConditionTrue(updatedLabInstance.Status.Conditions, ltbv1alpha1.DegradedCondition)).To(BeTrue())
+		})
+		It("should only mark the condition as not ready, while resources are created", func() {
+			labInstance := testLabInstance.DeepCopy()
+			r.Client = fake.NewClientBuilder().WithObjects(labInstance).Build()
+			retValue := ReturnToReconciler{shouldReturn: true, result: ctrl.Result{Requeue: true}}
+			result, err := r.ReturnWithCondition(context.Background(), labInstance, ltbv1alpha1.NodesReadyCondition, retValue)
+			Expect(err).To(BeNil())
+			Expect(result).To(Equal(ctrl.Result{Requeue: true}))
+			Expect(meta.FindStatusCondition(labInstance.Status.Conditions, ltbv1alpha1.NodesReadyCondition).Reason).To(Equal("Reconciling"))
+			Expect(meta.FindStatusCondition(labInstance.Status.Conditions, ltbv1alpha1.DegradedCondition)).To(BeNil())
+		})
+	})
+
+	Describe("RecordConditionEvents", func() {
+		var (
+			labInstance *ltbv1alpha1.LabInstance
+			recorder    *record.FakeRecorder
+		)
+		BeforeEach(func() {
+			labInstance = testLabInstance.DeepCopy()
+			recorder = record.NewFakeRecorder(10)
+			r = &LabInstanceReconciler{Recorder: recorder}
+		})
+		It("should record, when all nodes became ready", func() {
+			previousConditions := []metav1.Condition{{Type: ltbv1alpha1.NodesReadyCondition, Status: metav1.ConditionFalse}}
+			SetCondition(labInstance, ltbv1alpha1.NodesReadyCondition, metav1.ConditionTrue, "NodesReady", "")
+			r.RecordConditionEvents(labInstance, previousConditions)
+			Expect(recorder.Events).To(Receive(Equal("Normal Ready All lab nodes are ready")))
+		})
+		It("should record, when a ready node is no longer ready", func() {
+			previousConditions := []metav1.Condition{{Type: ltbv1alpha1.NodesReadyCondition, Status: metav1.ConditionTrue}}
+			SetCondition(labInstance, ltbv1alpha1.NodesReadyCondition, metav1.ConditionFalse, "NodesNotReady", "Not ready: "+testPodNode.Name)
+			r.RecordConditionEvents(labInstance, previousConditions)
+			Expect(recorder.Events).To(Receive(Equal("Warning NotReady Not ready: " + testPodNode.Name)))
+		})
+		It("should not record nodes, which are starting", func() {
+			SetCondition(labInstance, ltbv1alpha1.NodesReadyCondition, metav1.ConditionFalse, "NodesNotReady", "Not ready: "+testPodNode.Name)
+			r.RecordConditionEvents(labInstance, nil)
+			Expect(recorder.Events).ToNot(Receive())
+		})
+		It("should record failed nodes only once", func() {
+			SetCondition(labInstance, ltbv1alpha1.DegradedCondition, metav1.ConditionTrue, NodesFailedReason, testPodNode.Name+": ImagePullBackOff")
+			r.RecordConditionEvents(labInstance, nil)
+			Expect(recorder.Events).To(Receive(Equal("Warning NodesFailed " + testPodNode.Name + ": ImagePullBackOff")))
+			r.RecordConditionEvents(labInstance, labInstance.Status.Conditions)
+			Expect(recorder.Events).ToNot(Receive())
+		})
+	})
+
+	Describe("SetNodeError", func() {
+		It("should record the error of a node without status", func() {
+			labInstance := testLabInstance.DeepCopy()
+			SetNodeError(labInstance, testPodNode.Name, "pod", apiErrors.NewBadRequest("Invalid pod spec"))
+			SetNodeError(labInstance, testPodNode.Name, "pod", nil)
+			Expect(labInstance.Status.Nodes).To(Equal([]ltbv1alpha1.NodeStatus{{Name: testPodNode.Name, Kind: "pod", LastError: "Invalid pod spec"}}))
+		})
+	})
+
 	Describe("SetupWithManager", func() {
 		It("should fail", func() {
 			Expect(r.SetupWithManager(nil)).ToNot(Succeed())
@@ -0,0 +1,129 @@
+package controllers
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+
+	ltbv1alpha1 "github.com/Lab-Topology-Builder/LTB-K8s-Backend/api/v1alpha1"
+)
+
+// Names of parameters are identifiers of Go templates, so they can be used as .Params.<name>.
+var parameterNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Value of a cidr parameter without a default, when the lab template is rendered into its status, so address functions like cidrhost still work.
+const cidrParameterPlaceholder = "0.0.0.0/0"
+
+// GetParameters returns the values of the parameters of a lab template for a lab instance by name, converted to their types.
+// Without a lab instance, every parameter has its default or the zero value of its type, as the lab template is rendered into its status.
+func GetParameters(labTemplate *ltbv1alpha1.LabTemplate, labInstance *ltbv1alpha1.LabInstance) (map[string]interface{}, error) {
+	values := map[string]string{}
+	if labInstance != nil {
+		for _, parameterValue := range labInstance.Spec.Parameters {
+			if GetParameter(labTemplate, parameterValue.Name) == nil {
+				return nil, fmt.Errorf("parameter %s is not declared by LabTemplate %s", parameterValue.Name, labTemplate.Name)
+			}
+			values[parameterValue.Name] = parameterValue.Value
+		}
+	}
+	parameters := map[string]interface{}{}
+	for _, parameter := range labTemplate.Spec.Parameters {
+		value, ok := values[parameter.Name]
+		if !ok && parameter.Required && labInstance != nil {
+			return nil, fmt.Errorf("parameter %s is required by LabTemplate %s", parameter.Name, labTemplate.Name)
+		}
+		if !ok && parameter.Default == "" {
+			parameters[parameter.Name] = parameterZeroValue(parameter.Type)
+			continue
+		}
+		if !ok {
+			value = parameter.Default
+		}
+		parsedValue, err := ParseParameterValue(parameter.Type, value)
+		if err != nil {
+			return nil, fmt.Errorf("parameter %s: %w", parameter.Name, err)
+		}
+		parameters[parameter.Name] = parsedValue
+	}
+	return parameters, nil
+}
+
+// GetParameter returns the parameter of a lab template with the given name, or nil if the lab template doesn't declare it.
+func GetParameter(labTemplate *ltbv1alpha1.LabTemplate, name string) *ltbv1alpha1.Parameter {
+	for i := range labTemplate.Spec.Parameters {
+		if labTemplate.Spec.Parameters[i].Name == name {
+			return &labTemplate.Spec.Parameters[i]
+		}
+	}
+	return nil
+}
+
+// ParseParameterValue converts the value of a parameter to its type, an empty type is a string.
+func ParseParameterValue(parameterType ltbv1alpha1.ParameterType, value string) (interface{}, error) {
+	switch parameterType {
+	case "", ltbv1alpha1.StringParameterType:
+		return value, nil
+	case ltbv1alpha1.IntParameterType:
+		number, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not an int", value)
+		}
+		return number, nil
+	case ltbv1alpha1.BoolParameterType:
+		boolean, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a bool", value)
+		}
+		return boolean, nil
+	case ltbv1alpha1.CIDRParameterType:
+		if _, _, err := net.ParseCIDR(value); err != nil {
+			return nil, fmt.Errorf("%q is not a prefix in CIDR notation", value)
+		}
+		return value, nil
+	}
+	return nil, fmt.Errorf("unknown type %q, must be string, int, bool or cidr", parameterType)
+}
+
+// parameterZeroValue returns the value of a parameter of the given type without a value.
+func parameterZeroValue(parameterType ltbv1alpha1.ParameterType) interface{} {
+	switch parameterType {
+	case ltbv1alpha1.IntParameterType:
+		return 0
+	case ltbv1alpha1.BoolParameterType:
+		return false
+	case ltbv1alpha1.CIDRParameterType:
+		return cidrParameterPlaceholder
+	}
+	return ""
+}
+
+// GetNodeOverride returns the override of a lab node by a lab instance, or nil if the lab instance doesn't override the lab node.
+func GetNodeOverride(labInstance *ltbv1alpha1.LabInstance, nodeName string) *ltbv1alpha1.NodeOverride {
+	if labInstance == nil {
+		return nil
+	}
+	for i := range labInstance.Spec.NodeOverrides {
+		if labInstance.Spec.NodeOverrides[i].Node == nodeName {
+			return &labInstance.Spec.NodeOverrides[i]
+		}
+	}
+	return nil
+}
+
+// ApplyNodeOverride replaces the image, version and configuration of a lab node with those set by its override.
+// The resources are applied to the pod or VM of the lab node, after its node spec is rendered.
+func ApplyNodeOverride(node *ltbv1alpha1.LabInstanceNodes, nodeOverride *ltbv1alpha1.NodeOverride) {
+	if nodeOverride == nil {
+		return
+	}
+	if nodeOverride.Image != "" {
+		node.NodeTypeRef.Image = nodeOverride.Image
+	}
+	if nodeOverride.Version != "" {
+		node.NodeTypeRef.Version = nodeOverride.Version
+	}
+	if nodeOverride.Config != "" {
+		node.Config = nodeOverride.Config
+	}
+}
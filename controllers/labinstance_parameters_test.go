@@ -0,0 +1,61 @@
+package controllers
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	ltbv1alpha1 "github.com/Lab-Topology-Builder/LTB-K8s-Backend/api/v1alpha1"
+)
+
+var _ = Describe("LabInstance Parameters", func() {
+	var (
+		labTemplate *ltbv1alpha1.LabTemplate
+		labInstance *ltbv1alpha1.LabInstance
+	)
+
+	BeforeEach(func() {
+		labTemplate = testLabTemplateWithRenderedNodeSpec.DeepCopy()
+		labTemplate.Spec.Parameters = []ltbv1alpha1.Parameter{
+			{Name: "subnet", Type: ltbv1alpha1.CIDRParameterType, Required: true},
+			{Name: "vlan", Type: ltbv1alpha1.IntParameterType, Default: "100"},
+			{Name: "ospf", Type: ltbv1alpha1.BoolParameterType},
+			{Name: "image", Type: ltbv1alpha1.StringParameterType, Default: "ubuntu"},
+		}
+		labInstance = testLabInstance.DeepCopy()
+		labInstance.Spec.Parameters = []ltbv1alpha1.ParameterValue{{Name: "subnet", Value: "10.1.0.0/24"}, {Name: "ospf", Value: "true"}}
+	})
+
+	Describe("GetParameters", func() {
+		It("should convert the values of the lab instance and the defaults to their types", func() {
+			parameters, err := GetParameters(labTemplate, labInstance)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(parameters).To(Equal(map[string]interface{}{"subnet": "10.1.0.0/24", "vlan": 100, "ospf": true, "image": "ubuntu"}))
+		})
+		It("should use the defaults and zero values without a lab instance", func() {
+			parameters, err := GetParameters(labTemplate, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(parameters).To(Equal(map[string]interface{}{"subnet": cidrParameterPlaceholder, "vlan": 100, "ospf": false, "image": "ubuntu"}))
+		})
+		It("should reject missing required, undeclared and invalid values", func() {
+			labInstance.Spec.Parameters = nil
+			_, err := GetParameters(labTemplate, labInstance)
+			Expect(err).To(MatchError(ContainSubstring("parameter subnet is required")))
+			labInstance.Spec.Parameters = []ltbv1alpha1.ParameterValue{{Name: "subnet", Value: "10.1.0.0/24"}, {Name: "area", Value: "0"}}
+			_, err = GetParameters(labTemplate, labInstance)
+			Expect(err).To(MatchError(ContainSubstring("parameter area is not declared")))
+			labInstance.Spec.Parameters = []ltbv1alpha1.ParameterValue{{Name: "subnet", Value: "10.1.0.1"}}
+			_, err = GetParameters(labTemplate, labInstance)
+			Expect(err).To(MatchError(ContainSubstring("not a prefix in CIDR notation")))
+		})
+	})
+
+	Describe("ApplyNodeOverride", func() {
+		It("should only replace the fields set by the override", func() {
+			node := testPodNode.DeepCopy()
+			ApplyNodeOverride(node, &ltbv1alpha1.NodeOverride{Node: node.Name, Version: "12"})
+			Expect(node.NodeTypeRef.Image).To(Equal(testPodNode.NodeTypeRef.Image))
+			Expect(node.NodeTypeRef.Version).To(Equal("12"))
+			Expect(node.Config).To(Equal(testPodNode.Config))
+		})
+	})
+})
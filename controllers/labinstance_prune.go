@@ -0,0 +1,126 @@
+package controllers
+
+import (
+	"context"
+	"reflect"
+
+	network "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	ltbv1alpha1 "github.com/Lab-Topology-Builder/LTB-K8s-Backend/api/v1alpha1"
+)
+
+// pruneLists returns new lists of the kinds of the resources, which belong to the nodes, links or interfaces of a lab instance.
+// The lab nodes come first, so they are deleted before their remote access and network.
+func pruneLists() []client.ObjectList {
+	return []client.ObjectList{
+		&kubevirtv1.VirtualMachineList{},
+		&corev1.PodList{},
+		&networkingv1.IngressList{},
+		&corev1.ServiceList{},
+		&network.NetworkAttachmentDefinitionList{},
+		&corev1.ConfigMapList{},
+		&corev1.SecretList{},
+	}
+}
+
+// DesiredResources returns the kinds and names of the resources, which the lab instance needs for the current lab template, e.g. "Pod lab-node1".
+// The node kinds map the name of every node to the kind of its node type.
+func DesiredResources(labInstance *ltbv1alpha1.LabInstance, labTemplate *ltbv1alpha1.LabTemplate, nodeKinds map[string]string) map[string]bool {
+	desired := map[string]bool{
+		"Pod " + labInstance.Name + "-ttyd-pod":         true,
+		"Service " + labInstance.Name + "-ttyd-service": true,
+		"ConfigMap " + LinkConfigMapName(labInstance):   true,
+	}
+	for _, node := range labTemplate.Spec.Nodes {
+		name := labInstance.Name + "-" + node.Name
+		if nodeKinds[node.Name] == "vm" {
+			desired["VirtualMachine "+name] = true
+		} else {
+			desired["Pod "+name] = true
+		}
+		desired["Ingress "+name] = true
+		if len(node.Ports) > 0 {
+			desired["Service "+name+"-remote-access"] = true
+		}
+		for _, nodeInterface := range GetNodeInterfaces(labTemplate, &node) {
+			desired["NetworkAttachmentDefinition "+NetworkAttachmentDefinitionName(labInstance, node.Name, nodeInterface.Name)] = true
+		}
+		if renderedNode := GetRenderedNode(labTemplate, node.Name); renderedNode != nil {
+			if config := CreateNodeConfig(labInstance, &node, renderedNode.ConfigDelivery); config != nil {
+				desired[reflect.TypeOf(config).Elem().Name()+" "+config.GetName()] = true
+			}
+		}
+		for i := range node.Secrets {
+			secretName, err := GetSecretName(labInstance, &node, &node.Secrets[i])
+			if err == nil {
+				desired["Secret "+secretName] = true
+			}
+		}
+	}
+	return desired
+}
+
+// PruneResources deletes the resources controlled by a lab instance, which aren't desired anymore, because their node, link or interface has been removed
+// from the lab template, or their node changed its kind. Afterwards the segments of removed links and interfaces are pruned.
+func (r *LabInstanceReconciler) PruneResources(ctx context.Context, labInstance *ltbv1alpha1.LabInstance, labTemplate *ltbv1alpha1.LabTemplate, desired map[string]bool) ReturnToReconciler {
+	log := log.FromContext(ctx)
+	retValue := ReturnToReconciler{shouldReturn: true, result: ctrl.Result{}, err: nil}
+	for _, list := range pruneLists() {
+		resources, err := r.ownedResources(ctx, labInstance, list)
+		if err != nil {
+			retValue.err = err
+			return retValue
+		}
+		for _, resource := range resources {
+			kind := reflect.TypeOf(resource).Elem().Name()
+			if desired[kind+" "+resource.GetName()] || !resource.GetDeletionTimestamp().IsZero() {
+				continue
+			}
+			log.Info("Deleting resource, which is no longer part of the lab", "Kind", kind, "resource.Namespace", resource.GetNamespace(), "resource.Name", resource.GetName())
+			err = r.Delete(ctx, resource, client.PropagationPolicy(metav1.DeletePropagationForeground))
+			if err != nil && !errors.IsNotFound(err) {
+				retValue.err = err
+				log.Error(err, "Failed to delete resource", "Kind", kind, "resource.Name", resource.GetName())
+				return retValue
+			}
+			recordEvent(r.Recorder, labInstance, corev1.EventTypeNormal, DeletedReason, "Deleted %s %s", kind, resource.GetName())
+		}
+	}
+	r.PruneSegments(ctx, labInstance, labTemplate)
+	retValue.shouldReturn = false
+	return retValue
+}
+
+// PruneSegments removes the segments of removed links and interfaces from the status of a lab instance and releases their IDs.
+// The status is updated at the end of the reconciliation.
+func (r *LabInstanceReconciler) PruneSegments(ctx context.Context, labInstance *ltbv1alpha1.LabInstance, labTemplate *ltbv1alpha1.LabTemplate) {
+	log := log.FromContext(ctx)
+	if len(labInstance.Status.Network.Segments) == 0 {
+		return
+	}
+	desiredSegments := map[string]bool{}
+	for _, node := range labTemplate.Spec.Nodes {
+		for _, nodeInterface := range GetNodeInterfaces(labTemplate, &node) {
+			desiredSegments[InterfaceSegmentName(labInstance, labTemplate, node.Name, nodeInterface.Name)] = true
+		}
+	}
+	owner := client.ObjectKeyFromObject(labInstance).String()
+	for segment, id := range labInstance.Status.Network.Segments {
+		if desiredSegments[segment] {
+			continue
+		}
+		log.Info("Releasing segment ID, which is no longer part of the lab", "Segment", segment, "ID", id)
+		delete(labInstance.Status.Network.Segments, segment)
+		if r.SegmentAllocator != nil {
+			r.SegmentAllocator.ReleaseSegment(owner, segment)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package controllers
+
+import (
+	"context"
+	"reflect"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	ltbv1alpha1 "github.com/Lab-Topology-Builder/LTB-K8s-Backend/api/v1alpha1"
+	util "github.com/Lab-Topology-Builder/LTB-K8s-Backend/util"
+)
+
+var _ = Describe("LabInstance Pruning", func() {
+	var (
+		ctx         context.Context
+		r           *LabInstanceReconciler
+		recorder    *record.FakeRecorder
+		labInstance *ltbv1alpha1.LabInstance
+		labTemplate *ltbv1alpha1.LabTemplate
+		nodeKinds   map[string]string
+		resources   []client.Object
+		otherPod    *corev1.Pod
+		linkSegment string
+	)
+
+	// owned returns a copy of a resource, which is controlled by the lab instance.
+	owned := func(resource client.Object) client.Object {
+		resource = resource.DeepCopyObject().(client.Object)
+		resource.SetResourceVersion("")
+		Expect(ctrl.SetControllerReference(labInstance, resource, scheme.Scheme)).To(Succeed())
+		return resource
+	}
+
+	// exists reports whether a resource is still there.
+	exists := func(resource client.Object) bool {
+		err := r.Get(ctx, client.ObjectKeyFromObject(resource), resource.DeepCopyObject().(client.Object))
+		if apiErrors.IsNotFound(err) {
+			return false
+		}
+		Expect(err).NotTo(HaveOccurred())
+		return true
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		labInstance = testLabInstance.DeepCopy()
+		labInstance.UID = "test-uid"
+		labTemplate = testLabTemplateWithRenderedNodeSpec.DeepCopy()
+		nodeKinds = map[string]string{testVMNode.Name: "vm", testPodNode.Name: "pod"}
+		linkSegment = LinkSegmentName(labInstance, &labTemplate.Spec.Links[0])
+		labInstance.Status.Network.Segments = map[string]int32{
+			linkSegment: 100,
+			InterfaceSegmentName(labInstance, labTemplate, testPodNode.Name, "eth2"): 101,
+		}
+		resources = []client.Object{}
+		for _, resource := range []client.Object{testVM, testPod, testService, testTtydService, testVMIngress, testPodIngress, testVMNetworkAttachmentDefinition, testPodNetworkAttachmentDefinition, testPodUnlinkedNetworkAttachmentDefinition, testLinkConfigMap} {
+			resources = append(resources, owned(resource))
+		}
+		otherPod = &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: labInstance.Name + "-other", Namespace: labInstance.Namespace}}
+		recorder = record.NewFakeRecorder(20)
+		r = &LabInstanceReconciler{Client: fake.NewClientBuilder().WithObjects(append(resources, otherPod)...).Build(), Scheme: scheme.Scheme, Recorder: recorder}
+		r.SegmentAllocator, _ = util.NewSegmentAllocator(100, 102)
+		owner := client.ObjectKeyFromObject(labInstance).String()
+		for segment, id := range labInstance.Status.Network.Segments {
+			Expect(r.SegmentAllocator.Reserve(owner, segment, id)).To(Succeed())
+		}
+	})
+
+	Describe("DesiredResources", func() {
+		It("should contain the resources of every node and interface", func() {
+			desired := DesiredResources(labInstance, labTemplate, nodeKinds)
+			for _, resource := range resources {
+				kind := reflect.TypeOf(resource).Elem().Name()
+				Expect(desired).To(HaveKey(kind+" "+resource.GetName()), "resource %s", resource.GetName())
+			}
+		})
+		It("should contain the VM instead of the pod of a node, which changed its kind", func() {
+			nodeKinds[testPodNode.Name] = "vm"
+			desired := DesiredResources(labInstance, labTemplate, nodeKinds)
+			Expect(desired).To(HaveKey("VirtualMachine " + testPod.Name))
+			Expect(desired).NotTo(HaveKey("Pod " + testPod.Name))
+		})
+	})
+
+	Describe("PruneResources", func() {
+		It("should keep all resources, while the lab template is unchanged", func() {
+			returnValue := r.PruneResources(ctx, labInstance, labTemplate, DesiredResources(labInstance, labTemplate, nodeKinds))
+			Expect(returnValue.err).To(BeNil())
+			Expect(returnValue.shouldReturn).To(BeFalse())
+			for _, resource := range resources {
+				Expect(exists(resource)).To(BeTrue(), "resource %s", resource.GetName())
+			}
+			Expect(labInstance.Status.Network.Segments).To(HaveLen(2))
+			Expect(recorder.Events).To(BeEmpty())
+		})
+		It("should delete the resources and release the segments of a removed node and its link", func() {
+			labTemplate.Spec.Nodes = []ltbv1alpha1.LabInstanceNodes{*testVMNode}
+			labTemplate.Spec.Links = nil
+			delete(nodeKinds, testPodNode.Name)
+			returnValue := r.PruneResources(ctx, labInstance, labTemplate, DesiredResources(labInstance, labTemplate, nodeKinds))
+			Expect(returnValue.err).To(BeNil())
+			Expect(returnValue.shouldReturn).To(BeFalse())
+			for _, removed := range []client.Object{testPod, testPodIngress, testPodNetworkAttachmentDefinition, testPodUnlinkedNetworkAttachmentDefinition, testVMNetworkAttachmentDefinition} {
+				Expect(exists(removed)).To(BeFalse(), "resource %s", removed.GetName())
+			}
+			for _, kept := range []client.Object{testVM, testVMIngress, testService, testTtydService, testLinkConfigMap, otherPod} {
+				Expect(exists(kept)).To(BeTrue(), "resource %s", kept.GetName())
+			}
+			Expect(labInstance.Status.Network.Segments).To(BeEmpty())
+			Expect(recorder.Events).To(Receive(Equal("Normal " + DeletedReason + " Deleted Pod " + testPod.Name)))
+			id, err := r.SegmentAllocator.Allocate(namespace+"/other", "segment")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(id).To(Equal(int32(100)))
+		})
+		It("should only delete the attachments of a removed link", func() {
+			labTemplate.Spec.Links = nil
+			returnValue := r.PruneResources(ctx, labInstance, labTemplate, DesiredResources(labInstance, labTemplate, nodeKinds))
+			Expect(returnValue.err).To(BeNil())
+			Expect(exists(testVMNetworkAttachmentDefinition)).To(BeFalse())
+			// The interfaces declared by the pod node are kept, they are attached to segments of their own
+			Expect(exists(testPodNetworkAttachmentDefinition)).To(BeTrue())
+			Expect(exists(testPodUnlinkedNetworkAttachmentDefinition)).To(BeTrue())
+			Expect(exists(testPod)).To(BeTrue())
+			Expect(exists(testVM)).To(BeTrue())
+			Expect(labInstance.Status.Network.Segments).NotTo(HaveKey(linkSegment))
+			Expect(labInstance.Status.Network.Segments).To(HaveLen(1))
+		})
+	})
+})
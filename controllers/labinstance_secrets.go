@@ -0,0 +1,309 @@
+package controllers
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"path"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/yaml"
+
+	ltbv1alpha1 "github.com/Lab-Topology-Builder/LTB-K8s-Backend/api/v1alpha1"
+)
+
+// Username of the generated credentials of a lab instance.
+const generatedUsername = "ltb"
+
+// Length of the generated passwords, which only consist of letters and digits, so they can be used in any config.
+const generatedPasswordLength = 20
+
+const passwordCharacters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// Prefix of the names of the volumes, which mount Secrets into the first container of a pod.
+const secretVolumePrefix = "ltb-secret-"
+
+// GeneratedSecretName returns the name of the Secret with the generated credentials of a lab instance for a secret reference.
+func GeneratedSecretName(labInstance *ltbv1alpha1.LabInstance, name string) string {
+	return labInstance.Name + "-credentials-" + sanitizeName(name)
+}
+
+// GetSecretBinding returns the Secret bound to a secret reference by a lab instance, or nil if the lab instance doesn't bind it.
+func GetSecretBinding(labInstance *ltbv1alpha1.LabInstance, name string) *ltbv1alpha1.SecretBinding {
+	if labInstance == nil {
+		return nil
+	}
+	for i := range labInstance.Spec.Secrets {
+		if labInstance.Spec.Secrets[i].Name == name {
+			return &labInstance.Spec.Secrets[i]
+		}
+	}
+	return nil
+}
+
+// GetSecretName returns the name of the Secret of a secret reference of a lab node.
+// A Secret bound by the lab instance wins over the Secret of the lab template, which wins over the generated credentials.
+// Without a lab instance, as the lab template is rendered into its status, a reference without a Secret is named after itself.
+func GetSecretName(labInstance *ltbv1alpha1.LabInstance, node *ltbv1alpha1.LabInstanceNodes, secret *ltbv1alpha1.SecretReference) (string, error) {
+	if binding := GetSecretBinding(labInstance, secret.Name); binding != nil {
+		return binding.SecretName, nil
+	}
+	if secret.SecretName != "" {
+		return secret.SecretName, nil
+	}
+	if labInstance == nil {
+		return secret.Name, nil
+	}
+	if secret.Generate {
+		return GeneratedSecretName(labInstance, secret.Name), nil
+	}
+	return "", fmt.Errorf("secret %s of node %s has to be set by the lab instance", secret.Name, node.Name)
+}
+
+// GetNodeSecrets returns the names of the Secrets of a lab node by the names of their references.
+func GetNodeSecrets(labInstance *ltbv1alpha1.LabInstance, node *ltbv1alpha1.LabInstanceNodes) (map[string]string, error) {
+	secrets := map[string]string{}
+	for i := range node.Secrets {
+		secretName, err := GetSecretName(labInstance, node, &node.Secrets[i])
+		if err != nil {
+			return nil, err
+		}
+		secrets[node.Secrets[i].Name] = secretName
+	}
+	return secrets, nil
+}
+
+// ValidateSecretBindings checks that every Secret bound by a lab instance is referenced by a node of the lab template
+// and that every secret reference of the lab template has a Secret.
+func ValidateSecretBindings(labInstance *ltbv1alpha1.LabInstance, labTemplate *ltbv1alpha1.LabTemplate) error {
+	referenced := map[string]bool{}
+	for i := range labTemplate.Spec.Nodes {
+		node := &labTemplate.Spec.Nodes[i]
+		for _, secret := range node.Secrets {
+			referenced[secret.Name] = true
+		}
+		if _, err := GetNodeSecrets(labInstance, node); err != nil {
+			return errors.NewBadRequest(err.Error())
+		}
+	}
+	bound := map[string]bool{}
+	for _, binding := range labInstance.Spec.Secrets {
+		if !referenced[binding.Name] {
+			return errors.NewBadRequest(fmt.Sprintf("secret %s is not referenced by LabTemplate %s", binding.Name, labTemplate.Name))
+		}
+		if bound[binding.Name] {
+			return errors.NewBadRequest(fmt.Sprintf("secret %s is bound more than once", binding.Name))
+		}
+		bound[binding.Name] = true
+		if messages := validation.IsDNS1123Subdomain(binding.SecretName); len(messages) > 0 {
+			return errors.NewBadRequest(fmt.Sprintf("secretName %q of secret %s is not a valid name: %s", binding.SecretName, binding.Name, strings.Join(messages, ", ")))
+		}
+	}
+	return nil
+}
+
+// validateSecrets checks that the secret references of a node have unique names, which can be used as .Secrets.<name>,
+// valid Secret names and absolute mount paths.
+func validateSecrets(node *ltbv1alpha1.LabInstanceNodes) error {
+	secretNames := map[string]bool{}
+	for _, secret := range node.Secrets {
+		if !parameterNamePattern.MatchString(secret.Name) {
+			return errors.NewBadRequest(fmt.Sprintf("secret %q of node %s is not a valid name, it has to start with a letter or an underscore and only contain letters, digits and underscores", secret.Name, node.Name))
+		}
+		if secretNames[secret.Name] {
+			return errors.NewBadRequest(fmt.Sprintf("secret %s of node %s is referenced more than once", secret.Name, node.Name))
+		}
+		secretNames[secret.Name] = true
+		if secret.SecretName != "" && secret.Generate {
+			return errors.NewBadRequest(fmt.Sprintf("secret %s of node %s can't both name a Secret and generate one", secret.Name, node.Name))
+		}
+		if secret.SecretName != "" {
+			if messages := validation.IsDNS1123Subdomain(secret.SecretName); len(messages) > 0 {
+				return errors.NewBadRequest(fmt.Sprintf("secretName %q of secret %s of node %s is not a valid name: %s", secret.SecretName, secret.Name, node.Name, strings.Join(messages, ", ")))
+			}
+		}
+		if secret.MountPath != "" && !path.IsAbs(secret.MountPath) {
+			return errors.NewBadRequest(fmt.Sprintf("secret %s of node %s needs an absolute mount path, got %q", secret.Name, node.Name, secret.MountPath))
+		}
+	}
+	return nil
+}
+
+// CreateGeneratedSecret returns the Secret with generated credentials of a lab instance for a secret reference.
+func CreateGeneratedSecret(labInstance *ltbv1alpha1.LabInstance, name string) (*corev1.Secret, error) {
+	password, err := generatePassword(generatedPasswordLength)
+	if err != nil {
+		return nil, err
+	}
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      GeneratedSecretName(labInstance, name),
+			Namespace: labInstance.Namespace,
+		},
+		Type: corev1.SecretTypeBasicAuth,
+		Data: map[string][]byte{
+			corev1.BasicAuthUsernameKey: []byte(generatedUsername),
+			corev1.BasicAuthPasswordKey: []byte(password),
+		},
+	}, nil
+}
+
+// generatePassword returns a random password of the given length from a cryptographically secure source.
+func generatePassword(length int) (string, error) {
+	password := make([]byte, length)
+	for i := range password {
+		index, err := rand.Int(rand.Reader, big.NewInt(int64(len(passwordCharacters))))
+		if err != nil {
+			return "", err
+		}
+		password[i] = passwordCharacters[index.Int64()]
+	}
+	return string(password), nil
+}
+
+// ReconcileSecrets creates the generated credentials of a lab instance and checks that the other Secrets of its nodes exist.
+// Generated credentials are never updated, so the nodes keep their passwords, and are deleted with the lab instance.
+func (r *LabInstanceReconciler) ReconcileSecrets(ctx context.Context, labInstance *ltbv1alpha1.LabInstance, labTemplate *ltbv1alpha1.LabTemplate) ReturnToReconciler {
+	log := log.FromContext(ctx)
+	retValue := ReturnToReconciler{shouldReturn: true, result: ctrl.Result{}, err: nil}
+	if labInstance == nil {
+		retValue.err = errors.NewBadRequest("labInstance is nil")
+		return retValue
+	}
+	reconciled := map[string]bool{}
+	for i := range labTemplate.Spec.Nodes {
+		node := &labTemplate.Spec.Nodes[i]
+		for j := range node.Secrets {
+			secret := &node.Secrets[j]
+			secretName, err := GetSecretName(labInstance, node, secret)
+			if err != nil {
+				retValue.err = errors.NewBadRequest(err.Error())
+				return retValue
+			}
+			if reconciled[secretName] {
+				continue
+			}
+			reconciled[secretName] = true
+			foundSecret := &corev1.Secret{}
+			err = r.Get(ctx, types.NamespacedName{Namespace: labInstance.Namespace, Name: secretName}, foundSecret)
+			if errors.IsNotFound(err) && secretName == GeneratedSecretName(labInstance, secret.Name) && secret.Generate {
+				generatedSecret, err := CreateGeneratedSecret(labInstance, secret.Name)
+				if err != nil {
+					retValue.err = err
+					log.Error(err, "Failed to generate credentials")
+					return retValue
+				}
+				err = ctrl.SetControllerReference(labInstance, generatedSecret, r.Scheme)
+				if err != nil {
+					retValue.err = err
+					log.Error(err, "Failed to set the owner of the generated credentials", "Name", generatedSecret.Name)
+					return retValue
+				}
+				log.Info("Creating generated credentials", "Namespace", generatedSecret.Namespace, "Name", generatedSecret.Name)
+				err = r.Create(ctx, generatedSecret)
+				if err != nil {
+					retValue.err = err
+					log.Error(err, "Failed to create generated credentials", "Name", generatedSecret.Name)
+					return retValue
+				}
+				recordEvent(r.Recorder, labInstance, corev1.EventTypeNormal, CreatedReason, "Created Secret %s", generatedSecret.Name)
+				continue
+			}
+			if errors.IsNotFound(err) {
+				retValue.err = errors.NewBadRequest(fmt.Sprintf("Secret %s of node %s not found", secretName, node.Name))
+				return retValue
+			}
+			if err != nil {
+				retValue.err = err
+				log.Error(err, "Failed to get Secret", "Name", secretName)
+				return retValue
+			}
+		}
+	}
+	retValue.shouldReturn = false
+	return retValue
+}
+
+// AddSecretVolumes mounts the Secrets of a lab node with a mount path read-only into the first container of its pod.
+// KubeVirt can't mount a Secret into the guest of a VM, so it is attached as a disk, whose serial is the sanitized name of its reference,
+// and the guest mounts it at its mount path, e.g. with the mounts of cloud-init.
+func AddSecretVolumes(labInstance *ltbv1alpha1.LabInstance, node *ltbv1alpha1.LabInstanceNodes, renderedNode *ltbv1alpha1.RenderedNode, renderedNodeSpec string) (string, error) {
+	mountedSecrets := []ltbv1alpha1.SecretReference{}
+	for _, secret := range node.Secrets {
+		if secret.MountPath != "" {
+			mountedSecrets = append(mountedSecrets, secret)
+		}
+	}
+	if len(mountedSecrets) == 0 {
+		return renderedNodeSpec, nil
+	}
+	var nodeSpec interface{}
+	switch renderedNode.Kind {
+	case "pod":
+		podSpec := &corev1.PodSpec{}
+		if err := yaml.Unmarshal([]byte(renderedNodeSpec), podSpec); err != nil {
+			return "", fmt.Errorf("failed to decode the pod spec: %w", err)
+		}
+		if len(podSpec.Containers) == 0 {
+			return "", fmt.Errorf("the pod spec has no container to mount the secrets into")
+		}
+		for i := range mountedSecrets {
+			secretName, err := GetSecretName(labInstance, node, &mountedSecrets[i])
+			if err != nil {
+				return "", err
+			}
+			volumeName := secretVolumePrefix + sanitizeName(mountedSecrets[i].Name)
+			podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+				Name:         volumeName,
+				VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: secretName}},
+			})
+			podSpec.Containers[0].VolumeMounts = append(podSpec.Containers[0].VolumeMounts, corev1.VolumeMount{
+				Name:      volumeName,
+				MountPath: mountedSecrets[i].MountPath,
+				ReadOnly:  true,
+			})
+		}
+		nodeSpec = podSpec
+	case "vm":
+		vmSpec := &kubevirtv1.VirtualMachineSpec{}
+		if err := yaml.Unmarshal([]byte(renderedNodeSpec), vmSpec); err != nil {
+			return "", fmt.Errorf("failed to decode the VM spec: %w", err)
+		}
+		if vmSpec.Template == nil {
+			return "", fmt.Errorf("the VM spec has no template to attach the secrets to")
+		}
+		for i := range mountedSecrets {
+			secretName, err := GetSecretName(labInstance, node, &mountedSecrets[i])
+			if err != nil {
+				return "", err
+			}
+			volumeName := secretVolumePrefix + sanitizeName(mountedSecrets[i].Name)
+			vmSpec.Template.Spec.Volumes = append(vmSpec.Template.Spec.Volumes, kubevirtv1.Volume{
+				Name:         volumeName,
+				VolumeSource: kubevirtv1.VolumeSource{Secret: &kubevirtv1.SecretVolumeSource{SecretName: secretName}},
+			})
+			vmSpec.Template.Spec.Domain.Devices.Disks = append(vmSpec.Template.Spec.Domain.Devices.Disks, kubevirtv1.Disk{
+				Name:       volumeName,
+				Serial:     sanitizeName(mountedSecrets[i].Name),
+				DiskDevice: kubevirtv1.DiskDevice{Disk: &kubevirtv1.DiskTarget{Bus: kubevirtv1.DiskBusVirtio}},
+			})
+		}
+		nodeSpec = vmSpec
+	default:
+		return "", fmt.Errorf("invalid kind %q", renderedNode.Kind)
+	}
+	nodeSpecBytes, err := yaml.Marshal(nodeSpec)
+	if err != nil {
+		return "", err
+	}
+	return string(nodeSpecBytes), nil
+}
@@ -0,0 +1,114 @@
+package controllers
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	ltbv1alpha1 "github.com/Lab-Topology-Builder/LTB-K8s-Backend/api/v1alpha1"
+)
+
+var _ = Describe("LabInstance Secrets", func() {
+	var (
+		labInstance *ltbv1alpha1.LabInstance
+		labTemplate *ltbv1alpha1.LabTemplate
+		podNode     *ltbv1alpha1.LabInstanceNodes
+	)
+
+	BeforeEach(func() {
+		labInstance = testLabInstance.DeepCopy()
+		labTemplate = testLabTemplateWithRenderedNodeSpec.DeepCopy()
+		labTemplate.Spec.Nodes[0].Secrets = []ltbv1alpha1.SecretReference{{Name: "admin", Generate: true}}
+		labTemplate.Spec.Nodes[1].Secrets = []ltbv1alpha1.SecretReference{
+			{Name: "admin", Generate: true, MountPath: "/run/secrets/admin"},
+			{Name: "license", SecretName: "router-license"},
+		}
+		podNode = &labTemplate.Spec.Nodes[1]
+	})
+
+	Describe("GetNodeSecrets", func() {
+		It("should prefer the Secrets of the lab instance over those of the lab template and the generated credentials", func() {
+			secrets, err := GetNodeSecrets(labInstance, podNode)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(secrets).To(Equal(map[string]string{"admin": labInstance.Name + "-credentials-admin", "license": "router-license"}))
+			labInstance.Spec.Secrets = []ltbv1alpha1.SecretBinding{{Name: "license", SecretName: "lab-license"}}
+			secrets, err = GetNodeSecrets(labInstance, podNode)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(secrets).To(HaveKeyWithValue("license", "lab-license"))
+		})
+		It("should return an error for a secret, which isn't set", func() {
+			podNode.Secrets = []ltbv1alpha1.SecretReference{{Name: "license"}}
+			_, err := GetNodeSecrets(labInstance, podNode)
+			Expect(err).To(MatchError(ContainSubstring("has to be set by the lab instance")))
+			secrets, err := GetNodeSecrets(nil, podNode)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(secrets).To(HaveKeyWithValue("license", "license"))
+		})
+	})
+
+	Describe("AddSecretVolumes", func() {
+		It("should mount the Secrets with a mount path into the first container of a pod", func() {
+			renderedNode := &ltbv1alpha1.RenderedNode{Name: podNode.Name, Kind: "pod"}
+			nodeSpec, err := AddSecretVolumes(labInstance, podNode, renderedNode, testPodNode.RenderedNodeSpec)
+			Expect(err).NotTo(HaveOccurred())
+			podSpec := &corev1.PodSpec{}
+			Expect(yaml.Unmarshal([]byte(nodeSpec), podSpec)).To(Succeed())
+			Expect(podSpec.Volumes).To(ConsistOf(corev1.Volume{
+				Name:         "ltb-secret-admin",
+				VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: GeneratedSecretName(labInstance, "admin")}},
+			}))
+			Expect(podSpec.Containers[0].VolumeMounts).To(ConsistOf(corev1.VolumeMount{Name: "ltb-secret-admin", MountPath: "/run/secrets/admin", ReadOnly: true}))
+		})
+		It("should attach the Secrets with a mount path as disks to a VM", func() {
+			renderedNode := &ltbv1alpha1.RenderedNode{Name: podNode.Name, Kind: "vm"}
+			nodeSpec, err := AddSecretVolumes(labInstance, podNode, renderedNode, testVMNode.RenderedNodeSpec)
+			Expect(err).NotTo(HaveOccurred())
+			vmSpec := &kubevirtv1.VirtualMachineSpec{}
+			Expect(yaml.Unmarshal([]byte(nodeSpec), vmSpec)).To(Succeed())
+			Expect(vmSpec.Template.Spec.Volumes).To(ContainElement(kubevirtv1.Volume{
+				Name:         "ltb-secret-admin",
+				VolumeSource: kubevirtv1.VolumeSource{Secret: &kubevirtv1.SecretVolumeSource{SecretName: GeneratedSecretName(labInstance, "admin")}},
+			}))
+			Expect(vmSpec.Template.Spec.Domain.Devices.Disks).To(ContainElement(kubevirtv1.Disk{
+				Name:       "ltb-secret-admin",
+				Serial:     "admin",
+				DiskDevice: kubevirtv1.DiskDevice{Disk: &kubevirtv1.DiskTarget{Bus: kubevirtv1.DiskBusVirtio}},
+			}))
+		})
+	})
+
+	Describe("ReconcileSecrets", func() {
+		It("should generate the shared credentials once and require the other Secrets", func() {
+			ctx := context.Background()
+			r := &LabInstanceReconciler{Client: fake.NewClientBuilder().Build(), Scheme: scheme.Scheme, Recorder: record.NewFakeRecorder(10)}
+			returnValue := r.ReconcileSecrets(ctx, labInstance, labTemplate)
+			Expect(returnValue.shouldReturn).To(BeTrue())
+			Expect(apiErrors.IsBadRequest(returnValue.err)).To(BeTrue())
+			Expect(returnValue.err.Error()).To(ContainSubstring("Secret router-license of node " + podNode.Name + " not found"))
+
+			secret := &corev1.Secret{}
+			key := types.NamespacedName{Namespace: labInstance.Namespace, Name: GeneratedSecretName(labInstance, "admin")}
+			Expect(r.Get(ctx, key, secret)).To(Succeed())
+			Expect(secret.Type).To(Equal(corev1.SecretTypeBasicAuth))
+			Expect(secret.Data[corev1.BasicAuthPasswordKey]).To(HaveLen(generatedPasswordLength))
+			Expect(secret.OwnerReferences).To(HaveLen(1))
+			password := secret.Data[corev1.BasicAuthPasswordKey]
+
+			Expect(r.Create(ctx, &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: labInstance.Namespace, Name: "router-license"}})).To(Succeed())
+			returnValue = r.ReconcileSecrets(ctx, labInstance, labTemplate)
+			Expect(returnValue.shouldReturn).To(BeFalse())
+			Expect(r.Get(ctx, key, secret)).To(Succeed())
+			Expect(secret.Data[corev1.BasicAuthPasswordKey]).To(Equal(password))
+		})
+	})
+})
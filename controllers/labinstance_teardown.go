@@ -0,0 +1,218 @@
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"time"
+
+	network "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	ltbv1alpha1 "github.com/Lab-Topology-Builder/LTB-K8s-Backend/api/v1alpha1"
+)
+
+// Finalizer of the lab instances, which is removed once all resources of the lab instance are torn down.
+const labInstanceFinalizer = "ltb-backend.ltb/teardown"
+
+// Interval to check again, while the resources of a deleted lab instance are torn down.
+const teardownInterval = 2 * time.Second
+
+// teardownStep deletes the resources of a lab instance of the given kinds.
+type teardownStep struct {
+	// The reason of the Terminating condition, while the resources are deleted.
+	reason string
+	// Returns new lists of the kinds of the resources.
+	lists func() []client.ObjectList
+}
+
+// The steps to tear down a lab instance after its VMs are stopped, every step waits until its resources are gone.
+// The network is deleted last, as the lab nodes are attached to it until they are gone.
+var teardownSteps = []teardownStep{
+	{
+		reason: DeletingNodesReason,
+		lists: func() []client.ObjectList {
+			return []client.ObjectList{&kubevirtv1.VirtualMachineList{}, &corev1.PodList{}}
+		},
+	},
+	{
+		reason: DeletingAccessReason,
+		lists: func() []client.ObjectList {
+			return []client.ObjectList{&networkingv1.IngressList{}, &corev1.ServiceList{}, &rbacv1.RoleBindingList{}, &rbacv1.RoleList{}, &corev1.ServiceAccountList{}}
+		},
+	},
+	{
+		reason: DeletingNetworkReason,
+		lists: func() []client.ObjectList {
+			return []client.ObjectList{&network.NetworkAttachmentDefinitionList{}, &corev1.ConfigMapList{}, &corev1.SecretList{}}
+		},
+	},
+}
+
+// Teardown tears down the resources of a deleted lab instance in order and removes its finalizer, once all resources are gone.
+// The VMs are stopped gracefully first, then the lab nodes, the remote access and the network are deleted, and finally its subnet and segment IDs are released.
+func (r *LabInstanceReconciler) Teardown(ctx context.Context, labInstance *ltbv1alpha1.LabInstance) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+	if !controllerutil.ContainsFinalizer(labInstance, labInstanceFinalizer) {
+		return ctrl.Result{}, nil
+	}
+	remaining, err := r.StopVMs(ctx, labInstance)
+	if err != nil || len(remaining) > 0 {
+		return r.ReturnWithTeardownStep(ctx, labInstance, StoppingVMsReason, remaining, err)
+	}
+	for _, step := range teardownSteps {
+		remaining, err = r.DeleteOwnedResources(ctx, labInstance, step.lists())
+		if err != nil || len(remaining) > 0 {
+			return r.ReturnWithTeardownStep(ctx, labInstance, step.reason, remaining, err)
+		}
+	}
+
+	owner := client.ObjectKeyFromObject(labInstance).String()
+	if r.SubnetAllocator != nil {
+		r.SubnetAllocator.Release(owner)
+	}
+	if r.SegmentAllocator != nil {
+		r.SegmentAllocator.Release(owner)
+	}
+	controllerutil.RemoveFinalizer(labInstance, labInstanceFinalizer)
+	err = r.Update(ctx, labInstance)
+	if err != nil {
+		log.Error(err, "Failed to remove finalizer of LabInstance")
+		return ctrl.Result{}, err
+	}
+	log.Info("Tore down LabInstance")
+	recordEvent(r.Recorder, labInstance, corev1.EventTypeNormal, TornDownReason, "Deleted all resources of the lab instance")
+	return ctrl.Result{}, nil
+}
+
+// ReturnWithTeardownStep records the current step of the teardown and the resources it waits for in the Terminating condition and returns to the reconciler.
+// An event is recorded, when the teardown reaches the next step or fails.
+func (r *LabInstanceReconciler) ReturnWithTeardownStep(ctx context.Context, labInstance *ltbv1alpha1.LabInstance, reason string, remaining []string, err error) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+	message := "Waiting for " + strings.Join(remaining, ", ")
+	if err != nil {
+		message = err.Error()
+		recordEvent(r.Recorder, labInstance, corev1.EventTypeWarning, TeardownFailedReason, "%s", message)
+	} else if previous := meta.FindStatusCondition(labInstance.Status.Conditions, ltbv1alpha1.TerminatingCondition); previous == nil || previous.Reason != reason {
+		recordEvent(r.Recorder, labInstance, corev1.EventTypeNormal, reason, "%s", message)
+	}
+	labInstance.Status.Status = "Terminating"
+	SetCondition(labInstance, ltbv1alpha1.TerminatingCondition, metav1.ConditionTrue, reason, message)
+	statusErr := r.Status().Update(ctx, labInstance)
+	if statusErr != nil {
+		log.Error(statusErr, "Failed to update LabInstance status")
+	}
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: teardownInterval}, nil
+}
+
+// StopVMs stops the running VMs of a lab instance gracefully and returns the VMs, whose VM instance still exists.
+func (r *LabInstanceReconciler) StopVMs(ctx context.Context, labInstance *ltbv1alpha1.LabInstance) ([]string, error) {
+	log := log.FromContext(ctx)
+	vms, err := r.ownedResources(ctx, labInstance, &kubevirtv1.VirtualMachineList{})
+	if err != nil {
+		return nil, err
+	}
+	remaining := []string{}
+	for _, object := range vms {
+		vm := object.(*kubevirtv1.VirtualMachine)
+		if StopVM(vm) {
+			log.Info("Stopping VirtualMachine", "VirtualMachine.Namespace", vm.Namespace, "VirtualMachine.Name", vm.Name)
+			err = r.Update(ctx, vm)
+			if err != nil {
+				log.Error(err, "Failed to stop VirtualMachine", "VirtualMachine.Name", vm.Name)
+				return nil, err
+			}
+		}
+		err = r.Get(ctx, types.NamespacedName{Namespace: vm.Namespace, Name: vm.Name}, &kubevirtv1.VirtualMachineInstance{})
+		if errors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			log.Error(err, "Failed to get VirtualMachineInstance", "VirtualMachineInstance.Name", vm.Name)
+			return nil, err
+		}
+		remaining = append(remaining, "VirtualMachineInstance "+vm.Name)
+	}
+	return remaining, nil
+}
+
+// StopVM sets the run strategy of a VM to halted, or stops it, if it has no run strategy, and reports whether the VM changed.
+func StopVM(vm *kubevirtv1.VirtualMachine) bool {
+	if vm.Spec.RunStrategy != nil {
+		if *vm.Spec.RunStrategy == kubevirtv1.RunStrategyHalted {
+			return false
+		}
+		runStrategy := kubevirtv1.RunStrategyHalted
+		vm.Spec.RunStrategy = &runStrategy
+		return true
+	}
+	if vm.Spec.Running != nil && !*vm.Spec.Running {
+		return false
+	}
+	running := false
+	vm.Spec.Running = &running
+	return true
+}
+
+// DeleteOwnedResources deletes the resources of the given kinds, which are controlled by a lab instance, and returns the resources, which still exist.
+// The resources are deleted in the foreground, so they only disappear after their own dependents, like the data volumes of a VM, are gone.
+func (r *LabInstanceReconciler) DeleteOwnedResources(ctx context.Context, labInstance *ltbv1alpha1.LabInstance, lists []client.ObjectList) ([]string, error) {
+	log := log.FromContext(ctx)
+	remaining := []string{}
+	for _, list := range lists {
+		resources, err := r.ownedResources(ctx, labInstance, list)
+		if err != nil {
+			return nil, err
+		}
+		for _, resource := range resources {
+			kind := reflect.TypeOf(resource).Elem().Name()
+			remaining = append(remaining, kind+" "+resource.GetName())
+			if !resource.GetDeletionTimestamp().IsZero() {
+				continue
+			}
+			log.Info("Deleting resource", "resource.Namespace", resource.GetNamespace(), "resource.Name", resource.GetName())
+			err = r.Delete(ctx, resource, client.PropagationPolicy(metav1.DeletePropagationForeground))
+			if err != nil && !errors.IsNotFound(err) {
+				log.Error(err, "Failed to delete resource", "resource.Namespace", resource.GetNamespace(), "resource.Name", resource.GetName())
+				return nil, err
+			}
+		}
+	}
+	return remaining, nil
+}
+
+// ownedResources lists the resources in the namespace of a lab instance, which are controlled by the lab instance.
+func (r *LabInstanceReconciler) ownedResources(ctx context.Context, labInstance *ltbv1alpha1.LabInstance, list client.ObjectList) ([]client.Object, error) {
+	log := log.FromContext(ctx)
+	err := r.List(ctx, list, client.InNamespace(labInstance.Namespace))
+	if err != nil {
+		log.Error(err, "Failed to list resources", "List", reflect.TypeOf(list).Elem().Name())
+		return nil, err
+	}
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		return nil, err
+	}
+	resources := []client.Object{}
+	for _, item := range items {
+		resource, ok := item.(client.Object)
+		if ok && metav1.IsControlledBy(resource, labInstance) {
+			resources = append(resources, resource)
+		}
+	}
+	return resources, nil
+}
@@ -0,0 +1,160 @@
+package controllers
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	ltbv1alpha1 "github.com/Lab-Topology-Builder/LTB-K8s-Backend/api/v1alpha1"
+	util "github.com/Lab-Topology-Builder/LTB-K8s-Backend/util"
+)
+
+var _ = Describe("LabInstance Teardown", func() {
+	var (
+		ctx                context.Context
+		r                  *LabInstanceReconciler
+		req                ctrl.Request
+		recorder           *record.FakeRecorder
+		deletedLabInstance *ltbv1alpha1.LabInstance
+		vm                 *kubevirtv1.VirtualMachine
+		vmi                *kubevirtv1.VirtualMachineInstance
+		pod                *corev1.Pod
+		service            *corev1.Service
+		configMap          *corev1.ConfigMap
+	)
+
+	// owned returns a copy of a resource, which is controlled by the deleted lab instance.
+	owned := func(resource client.Object) client.Object {
+		resource.SetNamespace(deletedLabInstance.Namespace)
+		Expect(ctrl.SetControllerReference(deletedLabInstance, resource, scheme.Scheme)).To(Succeed())
+		return resource
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		deletedLabInstance = testLabInstance.DeepCopy()
+		deletedLabInstance.UID = "test-uid"
+		deletedLabInstance.Finalizers = []string{labInstanceFinalizer}
+		deletedLabInstance.DeletionTimestamp = &metav1.Time{Time: metav1.Now().Time}
+		deletedLabInstance.Status.Network.Subnet = "10.10.0.0/24"
+		req = ctrl.Request{NamespacedName: types.NamespacedName{Namespace: deletedLabInstance.Namespace, Name: deletedLabInstance.Name}}
+		vm = owned(testVM.DeepCopy()).(*kubevirtv1.VirtualMachine)
+		running := true
+		vm.Spec.Running = &running
+		vmi = &kubevirtv1.VirtualMachineInstance{ObjectMeta: metav1.ObjectMeta{Name: vm.Name, Namespace: vm.Namespace}}
+		pod = owned(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: deletedLabInstance.Name + "-" + testPodNode.Name}}).(*corev1.Pod)
+		service = owned(&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: deletedLabInstance.Name + "-ttyd-service"}}).(*corev1.Service)
+		configMap = owned(&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: LinkConfigMapName(deletedLabInstance)}}).(*corev1.ConfigMap)
+		recorder = record.NewFakeRecorder(10)
+		r = &LabInstanceReconciler{Scheme: scheme.Scheme, Recorder: recorder}
+	})
+
+	Describe("Reconcile", func() {
+		It("should add the finalizer to a lab instance", func() {
+			r.Client = fake.NewClientBuilder().WithObjects(testLabInstance, testLabTemplateWithRenderedNodeSpec, testPodNodeType, testNodeVMType).Build()
+			_, err := r.Reconcile(ctx, req)
+			Expect(err).NotTo(HaveOccurred())
+			labInstance := &ltbv1alpha1.LabInstance{}
+			Expect(r.Get(ctx, req.NamespacedName, labInstance)).To(Succeed())
+			Expect(labInstance.Finalizers).To(ContainElement(labInstanceFinalizer))
+		})
+		It("should stop the VMs first and wait for their VM instances", func() {
+			r.Client = fake.NewClientBuilder().WithObjects(deletedLabInstance, vm, vmi, pod).Build()
+			result, err := r.Reconcile(ctx, req)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal(ctrl.Result{RequeueAfter: teardownInterval}))
+			Expect(r.Get(ctx, client.ObjectKeyFromObject(vm), vm)).To(Succeed())
+			Expect(*vm.Spec.Running).To(BeFalse())
+			Expect(r.Get(ctx, client.ObjectKeyFromObject(pod), pod)).To(Succeed())
+			labInstance := &ltbv1alpha1.LabInstance{}
+			Expect(r.Get(ctx, req.NamespacedName, labInstance)).To(Succeed())
+			Expect(labInstance.Status.Status).To(Equal("Terminating"))
+			terminating := meta.FindStatusCondition(labInstance.Status.Conditions, ltbv1alpha1.TerminatingCondition)
+			Expect(terminating).NotTo(BeNil())
+			Expect(terminating.Reason).To(Equal(StoppingVMsReason))
+			Expect(terminating.Message).To(ContainSubstring(vm.Name))
+			Expect(recorder.Events).To(Receive(HavePrefix("Normal " + StoppingVMsReason)))
+		})
+		It("should delete the lab nodes before the remote access and the network", func() {
+			r.Client = fake.NewClientBuilder().WithObjects(deletedLabInstance, vm, pod, service, configMap).Build()
+			result, err := r.Reconcile(ctx, req)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal(ctrl.Result{RequeueAfter: teardownInterval}))
+			Expect(apiErrors.IsNotFound(r.Get(ctx, client.ObjectKeyFromObject(vm), vm))).To(BeTrue())
+			Expect(apiErrors.IsNotFound(r.Get(ctx, client.ObjectKeyFromObject(pod), pod))).To(BeTrue())
+			Expect(r.Get(ctx, client.ObjectKeyFromObject(service), service)).To(Succeed())
+			Expect(r.Get(ctx, client.ObjectKeyFromObject(configMap), configMap)).To(Succeed())
+			labInstance := &ltbv1alpha1.LabInstance{}
+			Expect(r.Get(ctx, req.NamespacedName, labInstance)).To(Succeed())
+			Expect(meta.FindStatusCondition(labInstance.Status.Conditions, ltbv1alpha1.TerminatingCondition).Reason).To(Equal(DeletingNodesReason))
+		})
+		It("should remove the finalizer and release the subnet, once all resources are gone", func() {
+			r.Client = fake.NewClientBuilder().WithObjects(deletedLabInstance, vm, pod, service, configMap).Build()
+			r.SubnetAllocator, _ = util.NewSubnetAllocator("10.10.0.0/24", 24)
+			Expect(r.SubnetAllocator.Reserve(req.NamespacedName.String(), deletedLabInstance.Status.Network.Subnet)).To(Succeed())
+			for i := 0; i < len(teardownSteps); i++ {
+				result, err := r.Reconcile(ctx, req)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result).To(Equal(ctrl.Result{RequeueAfter: teardownInterval}))
+			}
+			result, err := r.Reconcile(ctx, req)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal(ctrl.Result{}))
+			Expect(apiErrors.IsNotFound(r.Get(ctx, client.ObjectKeyFromObject(configMap), configMap))).To(BeTrue())
+			Expect(apiErrors.IsNotFound(r.Get(ctx, req.NamespacedName, &ltbv1alpha1.LabInstance{}))).To(BeTrue())
+			Expect(recorder.Events).To(Receive(HavePrefix("Normal " + DeletingNodesReason)))
+			_, err = r.SubnetAllocator.Allocate(namespace + "/other")
+			Expect(err).NotTo(HaveOccurred())
+		})
+		It("should not touch the resources of a deleted lab instance without finalizer", func() {
+			deletedLabInstance.Finalizers = []string{"other"}
+			r.Client = fake.NewClientBuilder().WithObjects(deletedLabInstance, pod).Build()
+			result, err := r.Reconcile(ctx, req)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal(ctrl.Result{}))
+			Expect(r.Get(ctx, client.ObjectKeyFromObject(pod), pod)).To(Succeed())
+		})
+	})
+
+	Describe("StopVM", func() {
+		It("should stop a running VM", func() {
+			running := true
+			stoppedVM := &kubevirtv1.VirtualMachine{Spec: kubevirtv1.VirtualMachineSpec{Running: &running}}
+			Expect(StopVM(stoppedVM)).To(BeTrue())
+			Expect(*stoppedVM.Spec.Running).To(BeFalse())
+			Expect(StopVM(stoppedVM)).To(BeFalse())
+		})
+		It("should halt a VM with a run strategy", func() {
+			runStrategy := kubevirtv1.RunStrategyAlways
+			haltedVM := &kubevirtv1.VirtualMachine{Spec: kubevirtv1.VirtualMachineSpec{RunStrategy: &runStrategy}}
+			Expect(StopVM(haltedVM)).To(BeTrue())
+			Expect(*haltedVM.Spec.RunStrategy).To(Equal(kubevirtv1.RunStrategyHalted))
+			Expect(haltedVM.Spec.Running).To(BeNil())
+			Expect(StopVM(haltedVM)).To(BeFalse())
+		})
+	})
+
+	Describe("DeleteOwnedResources", func() {
+		It("should only delete the resources controlled by the lab instance", func() {
+			otherPod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "other-pod", Namespace: deletedLabInstance.Namespace}}
+			r.Client = fake.NewClientBuilder().WithObjects(pod, otherPod).Build()
+			remaining, err := r.DeleteOwnedResources(ctx, deletedLabInstance, []client.ObjectList{&corev1.PodList{}})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(remaining).To(Equal([]string{"Pod " + pod.Name}))
+			Expect(apiErrors.IsNotFound(r.Get(ctx, client.ObjectKeyFromObject(pod), pod))).To(BeTrue())
+			Expect(r.Get(ctx, client.ObjectKeyFromObject(otherPod), otherPod)).To(Succeed())
+		})
+	})
+})
@@ -0,0 +1,167 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+
+	ltbv1alpha1 "github.com/Lab-Topology-Builder/LTB-K8s-Backend/api/v1alpha1"
+)
+
+// LabInstanceDefaulter sets the DNS address of lab instances, which don't define one, and normalizes the node names in the link references of a lab instance.
+type LabInstanceDefaulter struct {
+	// DNS address of the lab instances, which don't define one, no DNS address is set if empty.
+	DefaultDNSAddress string
+}
+
+var _ webhook.CustomDefaulter = &LabInstanceDefaulter{}
+
+//+kubebuilder:webhook:path=/mutate-ltb-backend-ltb-v1alpha1-labinstance,mutating=true,failurePolicy=fail,sideEffects=None,groups=ltb-backend.ltb,resources=labinstances,verbs=create;update,versions=v1alpha1,name=mlabinstance.ltb-backend.ltb,admissionReviewVersions=v1
+
+func (d *LabInstanceDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	labInstance := obj.(*ltbv1alpha1.LabInstance)
+	if labInstance.Spec.DNSAddress == "" {
+		labInstance.Spec.DNSAddress = d.DefaultDNSAddress
+	}
+	// The node names of the lab template are normalized as well, so the references still match
+	for i := range labInstance.Spec.DownLinks {
+		labInstance.Spec.DownLinks[i].Node = NormalizeNodeName(labInstance.Spec.DownLinks[i].Node)
+	}
+	for i := range labInstance.Spec.LinkImpairments {
+		labInstance.Spec.LinkImpairments[i].Endpoint.Node = NormalizeNodeName(labInstance.Spec.LinkImpairments[i].Endpoint.Node)
+	}
+	for i := range labInstance.Spec.NodeOverrides {
+		labInstance.Spec.NodeOverrides[i].Node = NormalizeNodeName(labInstance.Spec.NodeOverrides[i].Node)
+	}
+	return nil
+}
+
+func (d *LabInstanceDefaulter) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&ltbv1alpha1.LabInstance{}).
+		WithDefaulter(d).
+		Complete()
+}
+
+// LabInstanceValidator rejects lab instances referencing an unknown lab template, lab instances whose web terminals can't be exposed under their DNS address,
+// and lab instances with invalid parameters, impaired or down links to VMs or overrides of unknown nodes.
+type LabInstanceValidator struct {
+	client.Client
+}
+
+var _ webhook.CustomValidator = &LabInstanceValidator{}
+
+//+kubebuilder:webhook:path=/validate-ltb-backend-ltb-v1alpha1-labinstance,mutating=false,failurePolicy=fail,sideEffects=None,groups=ltb-backend.ltb,resources=labinstances,verbs=create;update,versions=v1alpha1,name=vlabinstance.ltb-backend.ltb,admissionReviewVersions=v1
+
+func (v *LabInstanceValidator) ValidateCreate(ctx context.Context, obj runtime.Object) error {
+	return v.validateLabInstance(ctx, obj.(*ltbv1alpha1.LabInstance))
+}
+
+// ValidateUpdate only requires the lab template to exist, if its reference changed, so a lab instance stays editable after its lab template was deleted.
+func (v *LabInstanceValidator) ValidateUpdate(ctx context.Context, oldObj runtime.Object, newObj runtime.Object) error {
+	labInstance := newObj.(*ltbv1alpha1.LabInstance)
+	if !labInstance.DeletionTimestamp.IsZero() {
+		return nil
+	}
+	if labInstance.Spec.LabTemplateReference == oldObj.(*ltbv1alpha1.LabInstance).Spec.LabTemplateReference {
+		labTemplate := &ltbv1alpha1.LabTemplate{}
+		err := v.Get(ctx, types.NamespacedName{Name: labInstance.Spec.LabTemplateReference, Namespace: labInstance.Namespace}, labTemplate)
+		if errors.IsNotFound(err) {
+			return ValidateDNSNames(labInstance, nil)
+		}
+		if err != nil {
+			return err
+		}
+		return ValidateLabInstance(labInstance, labTemplate)
+	}
+	return v.validateLabInstance(ctx, labInstance)
+}
+
+func (v *LabInstanceValidator) ValidateDelete(ctx context.Context, obj runtime.Object) error {
+	return nil
+}
+
+func (v *LabInstanceValidator) validateLabInstance(ctx context.Context, labInstance *ltbv1alpha1.LabInstance) error {
+	labTemplate := &ltbv1alpha1.LabTemplate{}
+	err := v.Get(ctx, types.NamespacedName{Name: labInstance.Spec.LabTemplateReference, Namespace: labInstance.Namespace}, labTemplate)
+	if errors.IsNotFound(err) {
+		return errors.NewBadRequest(fmt.Sprintf("LabTemplate %s not found", labInstance.Spec.LabTemplateReference))
+	}
+	if err != nil {
+		return err
+	}
+	return ValidateLabInstance(labInstance, labTemplate)
+}
+
+// ValidateLabInstance checks the DNS names, the parameters, the impaired and down links to VMs and the node overrides of a lab instance against its lab template.
+func ValidateLabInstance(labInstance *ltbv1alpha1.LabInstance, labTemplate *ltbv1alpha1.LabTemplate) error {
+	if err := ValidateDNSNames(labInstance, labTemplate); err != nil {
+		return err
+	}
+	if _, err := GetParameters(labTemplate, labInstance); err != nil {
+		return errors.NewBadRequest(err.Error())
+	}
+	if err := ValidateSecretBindings(labInstance, labTemplate); err != nil {
+		return err
+	}
+	if err := ValidateVMLinks(labInstance, labTemplate, GetRenderedNodeKinds(labTemplate)); err != nil {
+		return err
+	}
+	return ValidateNodeOverrides(labInstance, labTemplate)
+}
+
+// ValidateNodeOverrides checks that every override references a node of the lab template, which isn't overridden more than once.
+func ValidateNodeOverrides(labInstance *ltbv1alpha1.LabInstance, labTemplate *ltbv1alpha1.LabTemplate) error {
+	nodeNames := map[string]bool{}
+	for _, node := range labTemplate.Spec.Nodes {
+		nodeNames[node.Name] = true
+	}
+	overriddenNodes := map[string]bool{}
+	for _, nodeOverride := range labInstance.Spec.NodeOverrides {
+		if !nodeNames[nodeOverride.Node] {
+			return errors.NewBadRequest(fmt.Sprintf("overridden node %s not found in LabTemplate %s", nodeOverride.Node, labTemplate.Name))
+		}
+		if overriddenNodes[nodeOverride.Node] {
+			return errors.NewBadRequest(fmt.Sprintf("node %s is overridden more than once", nodeOverride.Node))
+		}
+		overriddenNodes[nodeOverride.Node] = true
+	}
+	return nil
+}
+
+// ValidateDNSNames checks that the DNS address of a lab instance is a valid DNS name and that the names of the resources of its nodes are valid DNS labels.
+// The remote access service has the longest name of these resources. The nodes are only checked, if the lab template is given.
+func ValidateDNSNames(labInstance *ltbv1alpha1.LabInstance, labTemplate *ltbv1alpha1.LabTemplate) error {
+	if messages := validation.IsDNS1123Label(labInstance.Name); len(messages) > 0 {
+		return errors.NewBadRequest(fmt.Sprintf("name %q is not a valid DNS label: %s", labInstance.Name, strings.Join(messages, ", ")))
+	}
+	if messages := validation.IsDNS1123Subdomain(labInstance.Spec.DNSAddress); len(messages) > 0 {
+		return errors.NewBadRequest(fmt.Sprintf("dnsAddress %q is not a valid DNS name: %s", labInstance.Spec.DNSAddress, strings.Join(messages, ", ")))
+	}
+	if labTemplate == nil {
+		return nil
+	}
+	for _, node := range labTemplate.Spec.Nodes {
+		serviceName := labInstance.Name + "-" + node.Name + "-remote-access"
+		if messages := validation.IsDNS1123Label(serviceName); len(messages) > 0 {
+			return errors.NewBadRequest(fmt.Sprintf("name %q of the resources of node %s is not a valid DNS label, the name of the lab instance is too long: %s", serviceName, node.Name, strings.Join(messages, ", ")))
+		}
+	}
+	return nil
+}
+
+func (v *LabInstanceValidator) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	v.Client = mgr.GetClient()
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&ltbv1alpha1.LabInstance{}).
+		WithValidator(v).
+		Complete()
+}
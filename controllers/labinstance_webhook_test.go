@@ -0,0 +1,140 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+
+	ltbv1alpha1 "github.com/Lab-Topology-Builder/LTB-K8s-Backend/api/v1alpha1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("LabInstance Webhook", func() {
+	var (
+		v           *LabInstanceValidator
+		labInstance *ltbv1alpha1.LabInstance
+	)
+
+	BeforeEach(func() {
+		v = &LabInstanceValidator{Client: fake.NewClientBuilder().WithObjects(testLabTemplateWithRenderedNodeSpec).Build()}
+		labInstance = testLabInstance.DeepCopy()
+	})
+
+	Describe("ValidateCreate", func() {
+		It("should accept a valid lab instance", func() {
+			Expect(v.ValidateCreate(context.Background(), labInstance)).To(Succeed())
+		})
+		It("should reject an unknown lab template", func() {
+			labInstance.Spec.LabTemplateReference = "unknown"
+			err := v.ValidateCreate(context.Background(), labInstance)
+			Expect(apiErrors.IsBadRequest(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("LabTemplate unknown not found"))
+		})
+		It("should reject an invalid DNS address", func() {
+			labInstance.Spec.DNSAddress = "example_com"
+			Expect(apiErrors.IsBadRequest(v.ValidateCreate(context.Background(), labInstance))).To(BeTrue())
+		})
+		It("should reject a name, which makes the host names of the nodes too long", func() {
+			labInstance.Name = strings.Repeat("a", 55)
+			err := v.ValidateCreate(context.Background(), labInstance)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring(testVMNode.Name))
+		})
+		It("should reject parameters, which the lab template doesn't declare or whose values don't match their type", func() {
+			labTemplate := testLabTemplateWithRenderedNodeSpec.DeepCopy()
+			labTemplate.Spec.Parameters = []ltbv1alpha1.Parameter{{Name: "vlan", Type: ltbv1alpha1.IntParameterType, Required: true}}
+			v.Client = fake.NewClientBuilder().WithObjects(labTemplate).Build()
+			err := v.ValidateCreate(context.Background(), labInstance)
+			Expect(apiErrors.IsBadRequest(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("parameter vlan is required"))
+			labInstance.Spec.Parameters = []ltbv1alpha1.ParameterValue{{Name: "vlan", Value: "ten"}}
+			Expect(apiErrors.IsBadRequest(v.ValidateCreate(context.Background(), labInstance))).To(BeTrue())
+			labInstance.Spec.Parameters = []ltbv1alpha1.ParameterValue{{Name: "vlan", Value: "10"}, {Name: "unknown", Value: "10"}}
+			Expect(apiErrors.IsBadRequest(v.ValidateCreate(context.Background(), labInstance))).To(BeTrue())
+			labInstance.Spec.Parameters = []ltbv1alpha1.ParameterValue{{Name: "vlan", Value: "10"}}
+			Expect(v.ValidateCreate(context.Background(), labInstance)).To(Succeed())
+		})
+		It("should reject overrides of unknown or duplicate nodes", func() {
+			labInstance.Spec.NodeOverrides = []ltbv1alpha1.NodeOverride{{Node: "unknown", Image: "debian"}}
+			err := v.ValidateCreate(context.Background(), labInstance)
+			Expect(apiErrors.IsBadRequest(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("overridden node unknown not found"))
+			labInstance.Spec.NodeOverrides = []ltbv1alpha1.NodeOverride{{Node: testPodNode.Name, Image: "debian"}, {Node: testPodNode.Name, Version: "12"}}
+			Expect(apiErrors.IsBadRequest(v.ValidateCreate(context.Background(), labInstance))).To(BeTrue())
+		})
+		It("should reject impairments of links to VMs", func() {
+			labInstance.Spec.LinkImpairments = []ltbv1alpha1.LinkImpairment{{Endpoint: ltbv1alpha1.LinkEndpoint{Node: testPodNode.Name, Interface: "eth1"}, Impairment: ltbv1alpha1.Impairment{Loss: "10"}}}
+			err := v.ValidateCreate(context.Background(), labInstance)
+			Expect(apiErrors.IsBadRequest(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("link of VM " + testVMNode.Name))
+			// An empty impairment removes the impairment of the lab template
+			labInstance.Spec.LinkImpairments[0].Impairment = ltbv1alpha1.Impairment{}
+			Expect(v.ValidateCreate(context.Background(), labInstance)).To(Succeed())
+		})
+		It("should reject down links to VMs", func() {
+			labInstance.Spec.DownLinks = []ltbv1alpha1.LinkEndpoint{{Node: testPodNode.Name, Interface: "eth1"}}
+			err := v.ValidateCreate(context.Background(), labInstance)
+			Expect(apiErrors.IsBadRequest(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("can't be set down"))
+		})
+		It("should reject unreferenced secrets and secret references without a Secret", func() {
+			labTemplate := testLabTemplateWithRenderedNodeSpec.DeepCopy()
+			labTemplate.Spec.Nodes[1].Secrets = []ltbv1alpha1.SecretReference{{Name: "license"}}
+			v.Client = fake.NewClientBuilder().WithObjects(labTemplate).Build()
+			err := v.ValidateCreate(context.Background(), labInstance)
+			Expect(apiErrors.IsBadRequest(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("has to be set by the lab instance"))
+			labInstance.Spec.Secrets = []ltbv1alpha1.SecretBinding{{Name: "license", SecretName: "license"}, {Name: "unknown", SecretName: "unknown"}}
+			err = v.ValidateCreate(context.Background(), labInstance)
+			Expect(apiErrors.IsBadRequest(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("secret unknown is not referenced"))
+			labInstance.Spec.Secrets = []ltbv1alpha1.SecretBinding{{Name: "license", SecretName: "license"}}
+			Expect(v.ValidateCreate(context.Background(), labInstance)).To(Succeed())
+		})
+	})
+
+	Describe("ValidateUpdate", func() {
+		It("should accept a lab instance, whose lab template was deleted", func() {
+			v.Client = fake.NewClientBuilder().Build()
+			oldLabInstance := labInstance.DeepCopy()
+			labInstance.Spec.NetworkBackend = ltbv1alpha1.VXLANNetworkBackend
+			Expect(v.ValidateUpdate(context.Background(), oldLabInstance, labInstance)).To(Succeed())
+		})
+		It("should reject a changed reference to an unknown lab template", func() {
+			oldLabInstance := labInstance.DeepCopy()
+			labInstance.Spec.LabTemplateReference = "unknown"
+			Expect(v.ValidateUpdate(context.Background(), oldLabInstance, labInstance)).ToNot(Succeed())
+		})
+		It("should check the parameters and overrides against the existing lab template", func() {
+			oldLabInstance := labInstance.DeepCopy()
+			labInstance.Spec.NodeOverrides = []ltbv1alpha1.NodeOverride{{Node: "unknown", Image: "debian"}}
+			Expect(apiErrors.IsBadRequest(v.ValidateUpdate(context.Background(), oldLabInstance, labInstance))).To(BeTrue())
+		})
+	})
+})
+
+var _ = Describe("LabInstance Defaulting", func() {
+	It("should set the default DNS address", func() {
+		labInstance := testLabInstance.DeepCopy()
+		labInstance.Spec.DNSAddress = ""
+		Expect((&LabInstanceDefaulter{DefaultDNSAddress: "labs.example.com"}).Default(context.Background(), labInstance)).To(Succeed())
+		Expect(labInstance.Spec.DNSAddress).To(Equal("labs.example.com"))
+	})
+	It("should keep the DNS address of the lab instance", func() {
+		labInstance := testLabInstance.DeepCopy()
+		Expect((&LabInstanceDefaulter{DefaultDNSAddress: "labs.example.com"}).Default(context.Background(), labInstance)).To(Succeed())
+		Expect(labInstance.Spec.DNSAddress).To(Equal(testLabInstance.Spec.DNSAddress))
+	})
+	It("should normalize the node names of the link references", func() {
+		labInstance := testLabInstance.DeepCopy()
+		labInstance.Spec.DownLinks = []ltbv1alpha1.LinkEndpoint{{Node: "Router_1", Interface: "eth1"}}
+		labInstance.Spec.LinkImpairments = []ltbv1alpha1.LinkImpairment{{Endpoint: ltbv1alpha1.LinkEndpoint{Node: "Router_1", Interface: "eth1"}}}
+		labInstance.Spec.NodeOverrides = []ltbv1alpha1.NodeOverride{{Node: "Router_1", Image: "debian"}}
+		Expect((&LabInstanceDefaulter{}).Default(context.Background(), labInstance)).To(Succeed())
+		Expect(labInstance.Spec.DownLinks[0].Node).To(Equal("router-1"))
+		Expect(labInstance.Spec.LinkImpairments[0].Endpoint.Node).To(Equal("router-1"))
+		Expect(labInstance.Spec.NodeOverrides[0].Node).To(Equal("router-1"))
+	})
+})
@@ -2,21 +2,43 @@ package controllers
 
 import (
 	"context"
+	"fmt"
+	"reflect"
+	"sort"
 	"strings"
 	"time"
 
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	ltbv1alpha1 "github.com/Lab-Topology-Builder/LTB-K8s-Backend/api/v1alpha1"
 	util "github.com/Lab-Topology-Builder/LTB-K8s-Backend/util"
+	kubevirtv1 "kubevirt.io/api/core/v1"
 )
 
+// Field index of the node types, which are referenced by the nodes of a lab template.
+const nodeTypeRefField = ".spec.nodes.nodeTypeRef.type"
+
 type LabTemplateReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+	// Records events of the lab templates, no events are recorded if nil.
+	Recorder record.EventRecorder
 }
 
 //+kubebuilder:rbac:groups=ltb-backend.ltb,resources=labtemplates,verbs=get;list;watch;create;update;patch;delete
@@ -25,6 +47,8 @@ type LabTemplateReconciler struct {
 //+kubebuilder:rbac:groups=ltb-backend.ltb,resources=nodetypes,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=ltb-backend.ltb,resources=nodetypes/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=ltb-backend.ltb,resources=nodetypes/finalizers,verbs=update
+//+kubebuilder:rbac:groups=ltb-backend.ltb,resources=labinstances,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 func (r *LabTemplateReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	l := log.FromContext(ctx)
@@ -35,32 +59,331 @@ func (r *LabTemplateReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		l.Error(err, "Failed to get labtemplate, ignoring must have been deleted")
 		return ctrl.Result{Requeue: true, RequeueAfter: 2 * time.Second}, client.IgnoreNotFound(err)
 	}
-	nodes := &labTemplate.Spec.Nodes
-	for i := 0; i < len(*nodes); i++ {
-		nodetype := &ltbv1alpha1.NodeType{}
-		err := r.Get(ctx, client.ObjectKey{Namespace: labTemplate.Namespace, Name: (*nodes)[i].NodeTypeRef.Type}, nodetype)
-		if err != nil {
-			l.Error(err, "Failed to get nodetype")
-			return ctrl.Result{}, client.IgnoreNotFound(err)
+	var renderErr error
+	changed := false
+	// With the manual rollout policy, the nodes are only rendered again, when the lab template itself changed
+	if labTemplate.Spec.RolloutPolicy != ltbv1alpha1.ManualRolloutPolicy || labTemplate.Status.ObservedGeneration != labTemplate.Generation {
+		changed, renderErr = r.RenderNodes(ctx, labTemplate)
+	}
+	SummarizeRenderedNodes(labTemplate)
+	labInstances := &ltbv1alpha1.LabInstanceList{}
+	err = r.List(ctx, labInstances, client.MatchingFields{labTemplateRefField: labTemplate.Name})
+	if err != nil {
+		l.Error(err, "Failed to list LabInstances")
+		return ctrl.Result{}, err
+	}
+	usedBy := []string{}
+	for _, labInstance := range labInstances.Items {
+		usedBy = append(usedBy, labInstance.Namespace+"/"+labInstance.Name)
+	}
+	sort.Strings(usedBy)
+	labTemplate.Status.UsedBy = usedBy
+
+	err = r.Status().Update(ctx, labTemplate)
+	if err != nil {
+		l.Error(err, "Failed to update labtemplate status")
+		recordEvent(r.Recorder, labTemplate, corev1.EventTypeWarning, UpdateFailedReason, "Failed to store the rendered nodes: %s", err)
+		return ctrl.Result{}, err
+	}
+	if changed && meta.IsStatusConditionTrue(labTemplate.Status.Conditions, ltbv1alpha1.RenderedCondition) {
+		recordEvent(r.Recorder, labTemplate, corev1.EventTypeNormal, RenderedReason, "Rendered the specs of %d nodes", len(labTemplate.Status.Nodes))
+	}
+	return ctrl.Result{}, renderErr
+}
+
+// RenderNodes renders the node specs of all nodes of a lab template into its status and returns, whether a node spec changed.
+// It continues with the other nodes, if a node fails to render, and returns the last render error.
+func (r *LabTemplateReconciler) RenderNodes(ctx context.Context, labTemplate *ltbv1alpha1.LabTemplate) (bool, error) {
+	renderedNodes := []ltbv1alpha1.RenderedNode{}
+	failedNodes := []string{}
+	var renderErr error
+	changed := false
+	for _, node := range labTemplate.Spec.Nodes {
+		renderedNode := ltbv1alpha1.RenderedNode{Name: node.Name}
+		previousNode := GetRenderedNode(labTemplate, node.Name)
+		if previousNode != nil {
+			renderedNode.Conditions = previousNode.Conditions
+		}
+		if err := r.RenderNode(ctx, labTemplate, &node, &renderedNode); err != nil {
+			renderErr = err
 		}
-		var renderedNodeSpec strings.Builder
-		if err = util.ParseAndRenderTemplate(nodetype, &renderedNodeSpec, (*nodes)[i]); err != nil {
-			l.Error(err, "Failed to render template")
-			return ctrl.Result{}, err
+		if !meta.IsStatusConditionTrue(renderedNode.Conditions, ltbv1alpha1.RenderedCondition) {
+			failedNodes = append(failedNodes, node.Name)
 		}
-		(*nodes)[i].RenderedNodeSpec = renderedNodeSpec.String()
+		if previousNode == nil || previousNode.NodeSpec != renderedNode.NodeSpec || previousNode.RenderedNodeSpec != renderedNode.RenderedNodeSpec || !reflect.DeepEqual(previousNode.ConfigDelivery, renderedNode.ConfigDelivery) {
+			changed = true
+		}
+		renderedNodes = append(renderedNodes, renderedNode)
+	}
+
+	labTemplate.Status.Nodes = renderedNodes
+	labTemplate.Status.ObservedGeneration = labTemplate.Generation
+	if len(failedNodes) > 0 {
+		setRenderedCondition(labTemplate, &labTemplate.Status.Conditions, metav1.ConditionFalse, RenderFailedReason, "Failed to render: "+strings.Join(failedNodes, ", "))
+	} else {
+		setRenderedCondition(labTemplate, &labTemplate.Status.Conditions, metav1.ConditionTrue, RenderedReason, "")
 	}
+	return changed, renderErr
+}
 
-	err = r.Update(ctx, labTemplate)
+// RenderNode renders the node spec of a lab node from its node type and sets its Rendered condition.
+// It returns the errors, which should be retried, a missing node type isn't retried until the node type is created.
+func (r *LabTemplateReconciler) RenderNode(ctx context.Context, labTemplate *ltbv1alpha1.LabTemplate, node *ltbv1alpha1.LabInstanceNodes, renderedNode *ltbv1alpha1.RenderedNode) error {
+	l := log.FromContext(ctx)
+	nodetype := &ltbv1alpha1.NodeType{}
+	err := r.Get(ctx, client.ObjectKey{Namespace: labTemplate.Namespace, Name: node.NodeTypeRef.Type}, nodetype)
+	if errors.IsNotFound(err) {
+		l.Info("NodeType not found", "NodeType", node.NodeTypeRef.Type)
+		recordEvent(r.Recorder, labTemplate, corev1.EventTypeWarning, NodeTypeMissingReason, "NodeType %s of node %s not found", node.NodeTypeRef.Type, node.Name)
+		setRenderedCondition(labTemplate, &renderedNode.Conditions, metav1.ConditionFalse, NodeTypeMissingReason, fmt.Sprintf("NodeType %s not found", node.NodeTypeRef.Type))
+		return nil
+	}
 	if err != nil {
-		l.Error(err, "Failed to update labtemplate")
-		return ctrl.Result{}, err
+		l.Error(err, "Failed to get nodetype")
+		setRenderedCondition(labTemplate, &renderedNode.Conditions, metav1.ConditionFalse, RenderFailedReason, err.Error())
+		return err
 	}
-	return ctrl.Result{}, nil
+	renderedNode.Kind = nodetype.Spec.Kind
+	templateContext, err := GetTemplateContext(labTemplate, nil, node)
+	if err != nil {
+		l.Error(err, "Failed to get template context")
+		setRenderedCondition(labTemplate, &renderedNode.Conditions, metav1.ConditionFalse, RenderFailedReason, err.Error())
+		return err
+	}
+	var renderedNodeSpec strings.Builder
+	if err = util.ParseAndRenderTemplate(nodetype.Spec.NodeSpec, &renderedNodeSpec, templateContext); err != nil {
+		l.Error(err, "Failed to render template")
+		recordEvent(r.Recorder, labTemplate, corev1.EventTypeWarning, RenderFailedReason, "Failed to render node %s: %s", node.Name, err)
+		setRenderedCondition(labTemplate, &renderedNode.Conditions, metav1.ConditionFalse, RenderFailedReason, err.Error())
+		return err
+	}
+	requests, err := NodeRequests(nodetype.Spec.Kind, renderedNodeSpec.String())
+	if err != nil {
+		l.Error(err, "Failed to decode rendered node spec")
+		recordEvent(r.Recorder, labTemplate, corev1.EventTypeWarning, RenderFailedReason, "Failed to render node %s: %s", node.Name, err)
+		setRenderedCondition(labTemplate, &renderedNode.Conditions, metav1.ConditionFalse, RenderFailedReason, err.Error())
+		return err
+	}
+	renderedNode.NodeSpec = nodetype.Spec.NodeSpec
+	renderedNode.ConfigDelivery = nodetype.Spec.ConfigDelivery
+	renderedNode.RenderedNodeSpec = renderedNodeSpec.String()
+	renderedNode.Requests = requests
+	setRenderedCondition(labTemplate, &renderedNode.Conditions, metav1.ConditionTrue, RenderedReason, "")
+	return nil
+}
+
+// GetTemplateContext returns the template context, which a lab node of a lab template is rendered with.
+// The lab instance is nil, when the lab node is rendered into the status of the lab template.
+// Otherwise, the lab nodes are overridden by the lab instance, and the parameters have the values of the lab instance.
+func GetTemplateContext(labTemplate *ltbv1alpha1.LabTemplate, labInstance *ltbv1alpha1.LabInstance, node *ltbv1alpha1.LabInstanceNodes) (util.TemplateContext, error) {
+	parameters, err := GetParameters(labTemplate, labInstance)
+	if err != nil {
+		return util.TemplateContext{}, err
+	}
+	secrets, err := GetNodeSecrets(labInstance, node)
+	if err != nil {
+		return util.TemplateContext{}, err
+	}
+	templateContext := util.TemplateContext{
+		LabInstanceNodes: *node,
+		Lab:              util.LabContext{Name: labTemplate.Name, Nodes: []util.NodeContext{}},
+		Links:            []util.LinkContext{},
+		Peers:            []util.NodeContext{},
+		Params:           parameters,
+		Secrets:          secrets,
+	}
+	ApplyNodeOverride(&templateContext.LabInstanceNodes, GetNodeOverride(labInstance, node.Name))
+	nodes := map[string]util.NodeContext{}
+	for i, labNode := range labTemplate.Spec.Nodes {
+		ApplyNodeOverride(&labNode, GetNodeOverride(labInstance, labNode.Name))
+		nodeContext := util.NodeContext{LabInstanceNodes: labNode, Index: i}
+		nodeContext.Interfaces = GetNodeInterfaces(labTemplate, &labNode)
+		templateContext.Lab.Nodes = append(templateContext.Lab.Nodes, nodeContext)
+		nodes[labNode.Name] = nodeContext
+	}
+	templateContext.Node = nodes[node.Name]
+	if labInstance != nil {
+		templateContext.Instance = util.InstanceContext{
+			Name:       labInstance.Name,
+			Namespace:  labInstance.Namespace,
+			DNSAddress: labInstance.Spec.DNSAddress,
+		}
+	}
+	peers := map[string]bool{}
+	for _, link := range labTemplate.Spec.Links {
+		if len(link.Endpoints) != 2 {
+			continue
+		}
+		for i, endpoint := range link.Endpoints {
+			if endpoint.Node != node.Name {
+				continue
+			}
+			peerEndpoint := link.Endpoints[1-i]
+			templateContext.Links = append(templateContext.Links, util.LinkContext{
+				Name:          link.Name,
+				Interface:     getNodeInterface(nodes[endpoint.Node], endpoint.Interface),
+				Peer:          peerEndpoint.Node,
+				PeerInterface: getNodeInterface(nodes[peerEndpoint.Node], peerEndpoint.Interface),
+			})
+			if !peers[peerEndpoint.Node] {
+				peers[peerEndpoint.Node] = true
+				templateContext.Peers = append(templateContext.Peers, nodes[peerEndpoint.Node])
+			}
+		}
+	}
+	return templateContext, nil
+}
+
+// getNodeInterface returns the interface of a lab node with the given name, or an interface with only the name, if the lab node doesn't declare it.
+func getNodeInterface(node util.NodeContext, interfaceName string) ltbv1alpha1.NodeInterface {
+	for _, nodeInterface := range node.Interfaces {
+		if nodeInterface.Name == interfaceName {
+			return nodeInterface
+		}
+	}
+	return ltbv1alpha1.NodeInterface{Name: interfaceName}
+}
+
+// NodeRequests returns the CPU and memory requests of a rendered node spec of the given kind.
+// The CPU of a VM without requests is the number of its virtual CPUs, and its memory the guest memory.
+func NodeRequests(kind string, renderedNodeSpec string) (corev1.ResourceList, error) {
+	requests := corev1.ResourceList{}
+	if kind == "vm" {
+		vmSpec := kubevirtv1.VirtualMachineSpec{}
+		if err := yaml.Unmarshal([]byte(renderedNodeSpec), &vmSpec); err != nil {
+			return nil, fmt.Errorf("Failed to decode the rendered VM spec: %w", err)
+		}
+		if vmSpec.Template == nil {
+			return requests, nil
+		}
+		domain := vmSpec.Template.Spec.Domain
+		addRequests(requests, domain.Resources.Requests)
+		if _, ok := requests[corev1.ResourceCPU]; !ok && domain.CPU != nil {
+			vCPUs := int64(1)
+			for _, count := range []uint32{domain.CPU.Cores, domain.CPU.Sockets, domain.CPU.Threads} {
+				if count > 0 {
+					vCPUs *= int64(count)
+				}
+			}
+			requests[corev1.ResourceCPU] = *resource.NewQuantity(vCPUs, resource.DecimalSI)
+		}
+		if _, ok := requests[corev1.ResourceMemory]; !ok && domain.Memory != nil && domain.Memory.Guest != nil {
+			requests[corev1.ResourceMemory] = *domain.Memory.Guest
+		}
+		return requests, nil
+	}
+	podSpec := corev1.PodSpec{}
+	if err := yaml.Unmarshal([]byte(renderedNodeSpec), &podSpec); err != nil {
+		return nil, fmt.Errorf("Failed to decode the rendered pod spec: %w", err)
+	}
+	for _, container := range podSpec.Containers {
+		addRequests(requests, container.Resources.Requests)
+	}
+	return requests, nil
+}
+
+// addRequests adds the CPU and memory requests to the total requests.
+func addRequests(total corev1.ResourceList, requests corev1.ResourceList) {
+	for _, name := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+		quantity, ok := requests[name]
+		if !ok {
+			continue
+		}
+		sum := total[name]
+		sum.Add(quantity)
+		total[name] = sum
+	}
+}
+
+// SummarizeRenderedNodes counts the pods and VMs of a lab template and sums up the requests of its rendered nodes.
+func SummarizeRenderedNodes(labTemplate *ltbv1alpha1.LabTemplate) {
+	labTemplate.Status.NumPods = 0
+	labTemplate.Status.NumVMs = 0
+	requests := corev1.ResourceList{}
+	for _, node := range labTemplate.Status.Nodes {
+		switch node.Kind {
+		case "pod":
+			labTemplate.Status.NumPods++
+		case "vm":
+			labTemplate.Status.NumVMs++
+		}
+		addRequests(requests, node.Requests)
+	}
+	labTemplate.Status.Requests = nil
+	if len(requests) > 0 {
+		labTemplate.Status.Requests = requests
+	}
+}
+
+// GetRenderedNode returns the rendered node spec of a lab node from the status of the lab template, nil if the node hasn't been rendered yet.
+func GetRenderedNode(labTemplate *ltbv1alpha1.LabTemplate, nodeName string) *ltbv1alpha1.RenderedNode {
+	for i := range labTemplate.Status.Nodes {
+		if labTemplate.Status.Nodes[i].Name == nodeName {
+			return &labTemplate.Status.Nodes[i]
+		}
+	}
+	return nil
+}
+
+func setRenderedCondition(labTemplate *ltbv1alpha1.LabTemplate, conditions *[]metav1.Condition, status metav1.ConditionStatus, reason string, message string) {
+	meta.SetStatusCondition(conditions, metav1.Condition{
+		Type:               ltbv1alpha1.RenderedCondition,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: labTemplate.Generation,
+	})
+}
+
+// NodeTypeRefs returns the names of the node types, which are referenced by the nodes of a lab template.
+func NodeTypeRefs(object client.Object) []string {
+	labTemplate := object.(*ltbv1alpha1.LabTemplate)
+	nodeTypes := []string{}
+	seen := map[string]bool{}
+	for _, node := range labTemplate.Spec.Nodes {
+		if node.NodeTypeRef.Type != "" && !seen[node.NodeTypeRef.Type] {
+			seen[node.NodeTypeRef.Type] = true
+			nodeTypes = append(nodeTypes, node.NodeTypeRef.Type)
+		}
+	}
+	return nodeTypes
 }
 
 func (r *LabTemplateReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&ltbv1alpha1.LabTemplate{}).
+	err := ctrl.NewControllerManagedBy(mgr).
+		// Only changes of the spec are rendered, the controller updates the status itself
+		For(&ltbv1alpha1.LabTemplate{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		// A changed node type is rendered again into the lab templates, which use it
+		Watches(&source.Kind{Type: &ltbv1alpha1.NodeType{}}, handler.EnqueueRequestsFromMapFunc(r.labTemplatesOfNodeType), builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		// The lab instances, which use a lab template, are listed in its status
+		Watches(&source.Kind{Type: &ltbv1alpha1.LabInstance{}}, handler.EnqueueRequestsFromMapFunc(labTemplateOfLabInstance), builder.WithPredicates(predicate.GenerationChangedPredicate{})).
 		Complete(r)
+	if err != nil {
+		return err
+	}
+	return mgr.GetFieldIndexer().IndexField(context.Background(), &ltbv1alpha1.LabTemplate{}, nodeTypeRefField, NodeTypeRefs)
+}
+
+// labTemplateOfLabInstance returns the lab template, which is referenced by a lab instance.
+// On updates, it is called for the old and the new lab instance, so a lab template, which is no longer referenced, is requeued as well.
+func labTemplateOfLabInstance(object client.Object) []reconcile.Request {
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: object.(*ltbv1alpha1.LabInstance).Spec.LabTemplateReference}}}
+}
+
+// labTemplatesOfNodeType returns the lab templates, which use the node type and roll out its changes automatically.
+func (r *LabTemplateReconciler) labTemplatesOfNodeType(object client.Object) []reconcile.Request {
+	labTemplates := &ltbv1alpha1.LabTemplateList{}
+	err := r.List(context.Background(), labTemplates, client.MatchingFields{nodeTypeRefField: object.GetName()})
+	if err != nil {
+		log.Log.Error(err, "Failed to list LabTemplates", "NodeType", object.GetName())
+		return nil
+	}
+	requests := []reconcile.Request{}
+	for _, labTemplate := range labTemplates.Items {
+		if labTemplate.Spec.RolloutPolicy == ltbv1alpha1.ManualRolloutPolicy {
+			continue
+		}
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&labTemplate)})
+	}
+	return requests
 }
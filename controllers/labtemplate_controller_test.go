@@ -5,10 +5,14 @@ import (
 	"time"
 
 	ltbv1alpha1 "github.com/Lab-Topology-Builder/LTB-K8s-Backend/api/v1alpha1"
+	util "github.com/Lab-Topology-Builder/LTB-K8s-Backend/util"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
@@ -18,15 +22,16 @@ var _ = Describe("LabTemplate Controller", func() {
 
 	Describe("Reconcile", func() {
 		var (
-			ctx context.Context
-			req ctrl.Request
+			ctx      context.Context
+			req      ctrl.Request
+			recorder *record.FakeRecorder
 		)
 		BeforeEach(func() {
 			ctx = context.Background()
 			req = ctrl.Request{}
-			fakeClient = fake.NewClientBuilder().WithObjects(testLabTemplateWithoutRenderedNodeSpec).Build()
-			lr = &LabTemplateReconciler{Client: fakeClient, Scheme: scheme.Scheme}
-
+			fakeClient = fake.NewClientBuilder().WithObjects(testLabTemplateWithoutRenderedNodeSpec).WithIndex(&ltbv1alpha1.LabInstance{}, labTemplateRefField, LabTemplateRef).Build()
+			recorder = record.NewFakeRecorder(10)
+			lr = &LabTemplateReconciler{Client: fakeClient, Scheme: scheme.Scheme, Recorder: recorder}
 		})
 		Context("LabTemplate doesn't exists", func() {
 			BeforeEach(func() {
@@ -46,11 +51,25 @@ var _ = Describe("LabTemplate Controller", func() {
 				result, err := lr.Reconcile(ctx, req)
 				Expect(result).To(Equal(ctrl.Result{}))
 				Expect(err).To(BeNil())
+				Expect(recorder.Events).To(Receive(HavePrefix("Warning NodeTypeMissing")))
+			})
+			It("should report the nodes as not rendered", func() {
+				_, err := lr.Reconcile(ctx, req)
+				Expect(err).To(BeNil())
+				labtemplate := &ltbv1alpha1.LabTemplate{}
+				err = lr.Get(ctx, req.NamespacedName, labtemplate)
+				Expect(err).To(BeNil())
+				Expect(meta.IsStatusConditionFalse(labtemplate.Status.Conditions, ltbv1alpha1.RenderedCondition)).To(BeTrue())
+				Expect(labtemplate.Status.Nodes).To(HaveLen(2))
+				rendered := meta.FindStatusCondition(labtemplate.Status.Nodes[0].Conditions, ltbv1alpha1.RenderedCondition)
+				Expect(rendered.Status).To(Equal(metav1.ConditionFalse))
+				Expect(rendered.Reason).To(Equal(NodeTypeMissingReason))
+				Expect(labtemplate.Status.Nodes[0].RenderedNodeSpec).To(BeEmpty())
 			})
 		})
 		Context("All resources exist, and successfully renders", func() {
 			BeforeEach(func() {
-				lr.Client = fake.NewClientBuilder().WithObjects(testLabTemplateWithoutRenderedNodeSpec, testPodNodeType, testNodeVMType).Build()
+				lr.Client = fake.NewClientBuilder().WithObjects(testLabTemplateWithoutRenderedNodeSpec, testPodNodeType, testNodeVMType, testLabInstance).WithIndex(&ltbv1alpha1.LabInstance{}, labTemplateRefField, LabTemplateRef).Build()
 				req.NamespacedName = types.NamespacedName{Name: testLabTemplateWithoutRenderedNodeSpec.Name, Namespace: testLabTemplateWithoutRenderedNodeSpec.Namespace}
 			})
 			It("should return nil error and render nodespec", func() {
@@ -60,14 +79,80 @@ var _ = Describe("LabTemplate Controller", func() {
 				labtemplate := &ltbv1alpha1.LabTemplate{}
 				err = lr.Get(ctx, req.NamespacedName, labtemplate)
 				Expect(err).To(BeNil())
-				Expect(labtemplate.Spec.Nodes).To(HaveLen(2))
-				Expect(labtemplate.Spec.Nodes[0].RenderedNodeSpec).To(MatchYAML(testLabTemplateWithRenderedNodeSpec.Spec.Nodes[0].RenderedNodeSpec))
-				Expect(labtemplate.Spec.Nodes[1].RenderedNodeSpec).ToNot(MatchYAML(testLabTemplateWithRenderedNodeSpec.Spec.Nodes[1].RenderedNodeSpec))
+				Expect(labtemplate.Status.Nodes).To(HaveLen(2))
+				Expect(labtemplate.Status.Nodes[0].RenderedNodeSpec).To(MatchYAML(testLabTemplateWithRenderedNodeSpec.Spec.Nodes[0].RenderedNodeSpec))
+				Expect(labtemplate.Status.Nodes[1].RenderedNodeSpec).ToNot(MatchYAML(testLabTemplateWithRenderedNodeSpec.Spec.Nodes[1].RenderedNodeSpec))
+				Expect(meta.IsStatusConditionTrue(labtemplate.Status.Nodes[1].Conditions, ltbv1alpha1.RenderedCondition)).To(BeTrue())
+				Expect(meta.IsStatusConditionTrue(labtemplate.Status.Conditions, ltbv1alpha1.RenderedCondition)).To(BeTrue())
+				Expect(labtemplate.Status.ObservedGeneration).To(Equal(labtemplate.Generation))
+				Expect(recorder.Events).To(Receive(Equal("Normal Rendered Rendered the specs of 2 nodes")))
+			})
+			It("should report the nodes, their requests and the lab instances, which use the lab template", func() {
+				_, err := lr.Reconcile(ctx, req)
+				Expect(err).To(BeNil())
+				labtemplate := &ltbv1alpha1.LabTemplate{}
+				err = lr.Get(ctx, req.NamespacedName, labtemplate)
+				Expect(err).To(BeNil())
+				Expect(labtemplate.Status.Nodes[0].Kind).To(Equal("vm"))
+				Expect(labtemplate.Status.Nodes[1].Kind).To(Equal("pod"))
+				Expect(labtemplate.Status.NumPods).To(Equal(int32(1)))
+				Expect(labtemplate.Status.NumVMs).To(Equal(int32(1)))
+				Expect(labtemplate.Status.Requests.Cpu().String()).To(Equal("2"))
+				Expect(labtemplate.Status.Requests.Memory().String()).To(Equal("4096M"))
+				Expect(labtemplate.Status.UsedBy).To(Equal([]string{testLabInstance.Namespace + "/" + testLabInstance.Name}))
+			})
+			It("should store the config delivery of the node type with the rendered node", func() {
+				nodeType := testPodNodeType.DeepCopy()
+				nodeType.Spec.ConfigDelivery = &ltbv1alpha1.ConfigDelivery{Type: ltbv1alpha1.ConfigMapConfigDelivery, MountPath: "/etc/frr"}
+				lr.Client = fake.NewClientBuilder().WithObjects(testLabTemplateWithoutRenderedNodeSpec, nodeType, testNodeVMType).WithIndex(&ltbv1alpha1.LabInstance{}, labTemplateRefField, LabTemplateRef).Build()
+				_, err := lr.Reconcile(ctx, req)
+				Expect(err).To(BeNil())
+				labtemplate := &ltbv1alpha1.LabTemplate{}
+				Expect(lr.Get(ctx, req.NamespacedName, labtemplate)).To(Succeed())
+				Expect(labtemplate.Status.Nodes[0].ConfigDelivery).To(BeNil())
+				Expect(labtemplate.Status.Nodes[1].ConfigDelivery).To(Equal(nodeType.Spec.ConfigDelivery))
+			})
+			It("should not render the nodes again with the manual rollout policy", func() {
+				_, err := lr.Reconcile(ctx, req)
+				Expect(err).To(BeNil())
+				labtemplate := &ltbv1alpha1.LabTemplate{}
+				err = lr.Get(ctx, req.NamespacedName, labtemplate)
+				Expect(err).To(BeNil())
+				labtemplate.Spec.RolloutPolicy = ltbv1alpha1.ManualRolloutPolicy
+				Expect(lr.Update(ctx, labtemplate)).To(Succeed())
+				labtemplate.Status.ObservedGeneration = labtemplate.Generation
+				Expect(lr.Status().Update(ctx, labtemplate)).To(Succeed())
+				nodetype := testPodNodeType.DeepCopy()
+				Expect(lr.Get(ctx, types.NamespacedName{Name: testPodNodeType.Name}, nodetype)).To(Succeed())
+				nodetype.Spec.NodeSpec = "containers: []"
+				Expect(lr.Update(ctx, nodetype)).To(Succeed())
+				_, err = lr.Reconcile(ctx, req)
+				Expect(err).To(BeNil())
+				err = lr.Get(ctx, req.NamespacedName, labtemplate)
+				Expect(err).To(BeNil())
+				Expect(labtemplate.Status.Nodes[1].RenderedNodeSpec).ToNot(Equal("containers: []"))
+				Expect(labtemplate.Status.UsedBy).To(HaveLen(1))
+			})
+			It("should not modify the spec of the lab template", func() {
+				_, err := lr.Reconcile(ctx, req)
+				Expect(err).To(BeNil())
+				labtemplate := &ltbv1alpha1.LabTemplate{}
+				err = lr.Get(ctx, req.NamespacedName, labtemplate)
+				Expect(err).To(BeNil())
+				Expect(labtemplate.Spec).To(Equal(testLabTemplateWithoutRenderedNodeSpec.Spec))
+			})
+			It("should not record another event, if the rendered specs didn't change", func() {
+				_, err := lr.Reconcile(ctx, req)
+				Expect(err).To(BeNil())
+				Expect(recorder.Events).To(Receive())
+				_, err = lr.Reconcile(ctx, req)
+				Expect(err).To(BeNil())
+				Expect(recorder.Events).ToNot(Receive())
 			})
 		})
 		Context("All resources exist, but fails to render", func() {
 			BeforeEach(func() {
-				lr.Client = fake.NewClientBuilder().WithObjects(testLabTemplateWithoutRenderedNodeSpec, failingPodNodeType, testNodeVMType).Build()
+				lr.Client = fake.NewClientBuilder().WithObjects(testLabTemplateWithoutRenderedNodeSpec, failingPodNodeType, testNodeVMType).WithIndex(&ltbv1alpha1.LabInstance{}, labTemplateRefField, LabTemplateRef).Build()
 				req.NamespacedName = types.NamespacedName{Name: testLabTemplateWithoutRenderedNodeSpec.Name, Namespace: testLabTemplateWithoutRenderedNodeSpec.Namespace}
 			})
 			It("should return error", func() {
@@ -82,13 +167,20 @@ var _ = Describe("LabTemplate Controller", func() {
 
 		Context("Rendering template fails", func() {
 			BeforeEach(func() {
-				lr.Client = fake.NewClientBuilder().WithObjects(testLabTemplateWithoutRenderedNodeSpec2, renderInvalidNodeType, testPodRenderSpecProblem).Build()
+				lr.Client = fake.NewClientBuilder().WithObjects(testLabTemplateWithoutRenderedNodeSpec2, renderInvalidNodeType, testPodRenderSpecProblem).WithIndex(&ltbv1alpha1.LabInstance{}, labTemplateRefField, LabTemplateRef).Build()
 				req.NamespacedName = types.NamespacedName{Name: testLabTemplateWithoutRenderedNodeSpec2.Name}
 			})
 			It("should return error", func() {
 				result, err := lr.Reconcile(ctx, req)
 				Expect(result).To(Equal(ctrl.Result{}))
 				Expect(err).ToNot((BeNil()))
+				Expect(recorder.Events).To(Receive(HavePrefix("Warning RenderFailed")))
+				labtemplate := &ltbv1alpha1.LabTemplate{}
+				err = lr.Get(ctx, req.NamespacedName, labtemplate)
+				Expect(err).To(BeNil())
+				rendered := meta.FindStatusCondition(labtemplate.Status.Nodes[0].Conditions, ltbv1alpha1.RenderedCondition)
+				Expect(rendered.Status).To(Equal(metav1.ConditionFalse))
+				Expect(rendered.Reason).To(Equal(RenderFailedReason))
 			})
 		})
 		AfterEach(func() {
@@ -96,6 +188,133 @@ var _ = Describe("LabTemplate Controller", func() {
 		})
 	})
 
+	Describe("GetRenderedNode", func() {
+		It("should return the rendered node", func() {
+			renderedNode := GetRenderedNode(testLabTemplateWithRenderedNodeSpec, testPodNode.Name)
+			Expect(renderedNode).ToNot(BeNil())
+			Expect(renderedNode.RenderedNodeSpec).To(Equal(testPodNode.RenderedNodeSpec))
+		})
+		It("should return nil for a node, which hasn't been rendered", func() {
+			Expect(GetRenderedNode(testLabTemplateWithoutRenderedNodeSpec, testPodNode.Name)).To(BeNil())
+		})
+	})
+
+	Describe("GetTemplateContext", func() {
+		It("should provide the lab, the instance, the links and the peers of the node", func() {
+			labTemplate := testLabTemplateWithRenderedNodeSpec.DeepCopy()
+			labTemplate.Spec.Nodes[1].Interfaces = []ltbv1alpha1.NodeInterface{{Name: "eth1", IPv4: "10.0.0.2/24"}}
+			templateContext, err := GetTemplateContext(labTemplate, testLabInstance, &labTemplate.Spec.Nodes[0])
+			Expect(err).To(BeNil())
+			Expect(templateContext.Name).To(Equal(testVMNode.Name))
+			Expect(templateContext.Node.Index).To(Equal(0))
+			Expect(templateContext.Lab.Name).To(Equal(labTemplate.Name))
+			Expect(templateContext.Lab.Nodes).To(HaveLen(2))
+			Expect(templateContext.Instance).To(Equal(util.InstanceContext{Name: testLabInstance.Name, Namespace: testLabInstance.Namespace, DNSAddress: testLabInstance.Spec.DNSAddress}))
+			Expect(templateContext.Links).To(HaveLen(1))
+			Expect(templateContext.Links[0].Interface.Name).To(Equal("eth1"))
+			Expect(templateContext.Links[0].Peer).To(Equal(testPodNode.Name))
+			Expect(templateContext.Links[0].PeerInterface.IPv4).To(Equal("10.0.0.2/24"))
+			Expect(templateContext.Peers).To(HaveLen(1))
+			Expect(templateContext.Peers[0].Index).To(Equal(1))
+		})
+		It("should leave the instance empty for the lab template", func() {
+			templateContext, err := GetTemplateContext(testLabTemplateWithRenderedNodeSpec, nil, testPodNode)
+			Expect(err).To(BeNil())
+			Expect(templateContext.Instance).To(Equal(util.InstanceContext{}))
+			Expect(templateContext.Node.Interfaces).To(Equal(GetNodeInterfaces(testLabTemplateWithRenderedNodeSpec, testPodNode)))
+		})
+		It("should override the nodes and set the parameters of the lab instance", func() {
+			labTemplate := testLabTemplateWithRenderedNodeSpec.DeepCopy()
+			labTemplate.Spec.Parameters = []ltbv1alpha1.Parameter{{Name: "hosts", Type: ltbv1alpha1.IntParameterType, Default: "2"}}
+			labInstance := testLabInstance.DeepCopy()
+			labInstance.Spec.NodeOverrides = []ltbv1alpha1.NodeOverride{{Node: testPodNode.Name, Image: "debian", Config: "override"}}
+			templateContext, err := GetTemplateContext(labTemplate, labInstance, testPodNode)
+			Expect(err).To(BeNil())
+			Expect(templateContext.NodeTypeRef.Image).To(Equal("debian"))
+			Expect(templateContext.NodeTypeRef.Version).To(Equal(testPodNode.NodeTypeRef.Version))
+			Expect(templateContext.Config).To(Equal("override"))
+			Expect(templateContext.Lab.Nodes[1].NodeTypeRef.Image).To(Equal("debian"))
+			Expect(templateContext.Params).To(Equal(map[string]interface{}{"hosts": 2}))
+			labInstance.Spec.Parameters = []ltbv1alpha1.ParameterValue{{Name: "hosts", Value: "many"}}
+			_, err = GetTemplateContext(labTemplate, labInstance, testPodNode)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("NodeRequests", func() {
+		It("should sum up the requests of the containers of a pod", func() {
+			requests, err := NodeRequests("pod", `
+containers:
+  - name: a
+    resources:
+      requests:
+        cpu: 500m
+        memory: 1Gi
+  - name: b
+    resources:
+      requests:
+        cpu: 250m
+`)
+			Expect(err).To(BeNil())
+			Expect(requests.Cpu().String()).To(Equal("750m"))
+			Expect(requests.Memory().String()).To(Equal("1Gi"))
+		})
+		It("should use the virtual CPUs and guest memory of a VM without requests", func() {
+			requests, err := NodeRequests("vm", `
+template:
+  spec:
+    domain:
+      cpu:
+        cores: 2
+        sockets: 2
+      memory:
+        guest: 2Gi
+`)
+			Expect(err).To(BeNil())
+			Expect(requests.Cpu().String()).To(Equal("4"))
+			Expect(requests.Memory().String()).To(Equal("2Gi"))
+		})
+		It("should return error, if the spec doesn't decode", func() {
+			_, err := NodeRequests("pod", "containers: 1")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("labTemplateOfLabInstance", func() {
+		It("should requeue the referenced lab template", func() {
+			requests := labTemplateOfLabInstance(testLabInstance)
+			Expect(requests).To(HaveLen(1))
+			Expect(requests[0].Name).To(Equal(testLabInstance.Spec.LabTemplateReference))
+			Expect(requests[0].Namespace).To(BeEmpty())
+		})
+	})
+
+	Describe("NodeTypeRefs", func() {
+		It("should return every referenced node type once", func() {
+			labTemplate := testLabTemplateWithoutRenderedNodeSpec.DeepCopy()
+			labTemplate.Spec.Nodes = append(labTemplate.Spec.Nodes, labTemplate.Spec.Nodes[0])
+			Expect(NodeTypeRefs(labTemplate)).To(Equal([]string{testNodeVMType.Name, testPodNodeType.Name}))
+		})
+	})
+
+	Describe("labTemplatesOfNodeType", func() {
+		BeforeEach(func() {
+			manualLabTemplate := testLabTemplateWithoutRenderedNodeSpec.DeepCopy()
+			manualLabTemplate.Name = "manual-labtemplate"
+			manualLabTemplate.Spec.RolloutPolicy = ltbv1alpha1.ManualRolloutPolicy
+			fakeClient = fake.NewClientBuilder().WithObjects(testLabTemplateWithoutRenderedNodeSpec, manualLabTemplate).WithIndex(&ltbv1alpha1.LabTemplate{}, nodeTypeRefField, NodeTypeRefs).Build()
+			lr = &LabTemplateReconciler{Client: fakeClient, Scheme: scheme.Scheme}
+		})
+		It("should requeue the lab templates, which use the node type automatically", func() {
+			requests := lr.labTemplatesOfNodeType(testPodNodeType)
+			Expect(requests).To(HaveLen(1))
+			Expect(requests[0].Name).To(Equal(testLabTemplateWithoutRenderedNodeSpec.Name))
+		})
+		It("should not requeue any lab template for an unused node type", func() {
+			Expect(lr.labTemplatesOfNodeType(invalidKindNodeType)).To(BeEmpty())
+		})
+	})
+
 	Describe("SetupWithManager", func() {
 		It("should return error", func() {
 			err := lr.SetupWithManager(nil)
@@ -0,0 +1,310 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+
+	ltbv1alpha1 "github.com/Lab-Topology-Builder/LTB-K8s-Backend/api/v1alpha1"
+)
+
+const (
+	// Maximum length of a node name, so the names of the resources of a lab node don't exceed the limits of Kubernetes.
+	MaxNodeNameLength = 24
+	// Version of a node type, if a node doesn't define one.
+	DefaultNodeTypeVersion = "latest"
+	// Prefix of the default interface names, the first interface of a lab node is eth1, as eth0 is attached to the pod network.
+	defaultInterfacePrefix = "eth"
+)
+
+// LabTemplateDefaulter sets the defaults of the nodes, ports, interfaces and links of a lab template and normalizes the names of its nodes.
+type LabTemplateDefaulter struct{}
+
+var _ webhook.CustomDefaulter = &LabTemplateDefaulter{}
+
+//+kubebuilder:webhook:path=/mutate-ltb-backend-ltb-v1alpha1-labtemplate,mutating=true,failurePolicy=fail,sideEffects=None,groups=ltb-backend.ltb,resources=labtemplates,verbs=create;update,versions=v1alpha1,name=mlabtemplate.ltb-backend.ltb,admissionReviewVersions=v1
+
+func (d *LabTemplateDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	DefaultLabTemplate(obj.(*ltbv1alpha1.LabTemplate))
+	return nil
+}
+
+func (d *LabTemplateDefaulter) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&ltbv1alpha1.LabTemplate{}).
+		WithDefaulter(d).
+		Complete()
+}
+
+// DefaultLabTemplate normalizes the node names of a lab template and sets the defaults of the fields, which are left empty.
+// Interfaces and link endpoints without a name get the next free interface name of their node, starting with eth1.
+func DefaultLabTemplate(labTemplate *ltbv1alpha1.LabTemplate) {
+	nodes := labTemplate.Spec.Nodes
+	links := labTemplate.Spec.Links
+	usedInterfaces := map[string]map[string]bool{}
+	useInterface := func(nodeName string, interfaceName string) {
+		if usedInterfaces[nodeName] == nil {
+			usedInterfaces[nodeName] = map[string]bool{}
+		}
+		usedInterfaces[nodeName][interfaceName] = true
+	}
+	nextInterface := func(nodeName string) string {
+		for i := 1; ; i++ {
+			interfaceName := fmt.Sprintf("%s%d", defaultInterfacePrefix, i)
+			if !usedInterfaces[nodeName][interfaceName] {
+				useInterface(nodeName, interfaceName)
+				return interfaceName
+			}
+		}
+	}
+
+	for i := range nodes {
+		nodes[i].Name = NormalizeNodeName(nodes[i].Name)
+		if nodes[i].NodeTypeRef.Version == "" {
+			nodes[i].NodeTypeRef.Version = DefaultNodeTypeVersion
+		}
+		for j := range nodes[i].Ports {
+			if nodes[i].Ports[j].Protocol == "" {
+				nodes[i].Ports[j].Protocol = corev1.ProtocolTCP
+			}
+		}
+		for _, nodeInterface := range nodes[i].Interfaces {
+			if nodeInterface.Name != "" {
+				useInterface(nodes[i].Name, nodeInterface.Name)
+			}
+		}
+	}
+	for i := range links {
+		for j := range links[i].Endpoints {
+			links[i].Endpoints[j].Node = NormalizeNodeName(links[i].Endpoints[j].Node)
+			if links[i].Endpoints[j].Interface != "" {
+				useInterface(links[i].Endpoints[j].Node, links[i].Endpoints[j].Interface)
+			}
+		}
+	}
+
+	for i := range nodes {
+		for j := range nodes[i].Interfaces {
+			if nodes[i].Interfaces[j].Name == "" {
+				nodes[i].Interfaces[j].Name = nextInterface(nodes[i].Name)
+			}
+		}
+	}
+	for i := range links {
+		for j := range links[i].Endpoints {
+			if links[i].Endpoints[j].Interface == "" {
+				links[i].Endpoints[j].Interface = nextInterface(links[i].Endpoints[j].Node)
+			}
+		}
+		if links[i].Name == "" {
+			links[i].Name = DefaultLinkName(&links[i])
+		}
+	}
+}
+
+// NormalizeNodeName converts a node name into a lowercase DNS label of at most MaxNodeNameLength characters.
+func NormalizeNodeName(name string) string {
+	name = sanitizeName(name)
+	if len(name) > MaxNodeNameLength {
+		name = strings.TrimRight(name[:MaxNodeNameLength], "-")
+	}
+	return name
+}
+
+// DefaultLinkName returns the name of a link, which is derived from the names of its endpoints.
+func DefaultLinkName(link *ltbv1alpha1.Link) string {
+	names := []string{}
+	for _, endpoint := range link.Endpoints {
+		names = append(names, endpoint.Node, endpoint.Interface)
+	}
+	return sanitizeName(strings.Join(names, "-"))
+}
+
+// LabTemplateValidator rejects lab templates with invalid nodes, ports, interfaces or links, impaired links to VMs, and lab templates referencing unknown node types.
+type LabTemplateValidator struct {
+	client.Client
+}
+
+var _ webhook.CustomValidator = &LabTemplateValidator{}
+
+//+kubebuilder:webhook:path=/validate-ltb-backend-ltb-v1alpha1-labtemplate,mutating=false,failurePolicy=fail,sideEffects=None,groups=ltb-backend.ltb,resources=labtemplates,verbs=create;update,versions=v1alpha1,name=vlabtemplate.ltb-backend.ltb,admissionReviewVersions=v1
+
+func (v *LabTemplateValidator) ValidateCreate(ctx context.Context, obj runtime.Object) error {
+	labTemplate := obj.(*ltbv1alpha1.LabTemplate)
+	if err := ValidateLabTemplate(labTemplate); err != nil {
+		return err
+	}
+	if err := v.validateNodeTypeRefs(ctx, labTemplate, nil); err != nil {
+		return err
+	}
+	return v.validateVMLinks(ctx, labTemplate)
+}
+
+// ValidateUpdate only checks the node types, which are newly referenced, so a lab template stays editable after one of its node types was deleted.
+func (v *LabTemplateValidator) ValidateUpdate(ctx context.Context, oldObj runtime.Object, newObj runtime.Object) error {
+	labTemplate := newObj.(*ltbv1alpha1.LabTemplate)
+	if !labTemplate.DeletionTimestamp.IsZero() {
+		return nil
+	}
+	if err := ValidateLabTemplate(labTemplate); err != nil {
+		return err
+	}
+	if err := v.validateNodeTypeRefs(ctx, labTemplate, oldObj.(*ltbv1alpha1.LabTemplate)); err != nil {
+		return err
+	}
+	return v.validateVMLinks(ctx, labTemplate)
+}
+
+func (v *LabTemplateValidator) ValidateDelete(ctx context.Context, obj runtime.Object) error {
+	return nil
+}
+
+// validateNodeTypeRefs checks that the node types referenced by a lab template exist, apart from those already referenced by the old lab template.
+func (v *LabTemplateValidator) validateNodeTypeRefs(ctx context.Context, labTemplate *ltbv1alpha1.LabTemplate, oldLabTemplate *ltbv1alpha1.LabTemplate) error {
+	knownNodeTypes := map[string]bool{}
+	if oldLabTemplate != nil {
+		for _, nodeType := range NodeTypeRefs(oldLabTemplate) {
+			knownNodeTypes[nodeType] = true
+		}
+	}
+	for _, nodeType := range NodeTypeRefs(labTemplate) {
+		if knownNodeTypes[nodeType] {
+			continue
+		}
+		err := v.Get(ctx, client.ObjectKey{Name: nodeType}, &ltbv1alpha1.NodeType{})
+		if errors.IsNotFound(err) {
+			return errors.NewBadRequest(fmt.Sprintf("NodeType %s not found", nodeType))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateVMLinks checks the links to VMs with the kinds of the node types, which exist, as the lab template isn't rendered yet.
+func (v *LabTemplateValidator) validateVMLinks(ctx context.Context, labTemplate *ltbv1alpha1.LabTemplate) error {
+	nodeKinds := map[string]string{}
+	for _, node := range labTemplate.Spec.Nodes {
+		nodeType := &ltbv1alpha1.NodeType{}
+		err := v.Get(ctx, client.ObjectKey{Name: node.NodeTypeRef.Type}, nodeType)
+		if errors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		nodeKinds[node.Name] = nodeType.Spec.Kind
+	}
+	return ValidateVMLinks(nil, labTemplate, nodeKinds)
+}
+
+// ValidateLabTemplate checks the nodes, their ports and interfaces, the links and the parameters of a lab template.
+func ValidateLabTemplate(labTemplate *ltbv1alpha1.LabTemplate) error {
+	if err := ValidateNodes(labTemplate); err != nil {
+		return err
+	}
+	if err := ValidateInterfaces(labTemplate); err != nil {
+		return err
+	}
+	if err := ValidateLinks(labTemplate); err != nil {
+		return err
+	}
+	return ValidateParameters(labTemplate)
+}
+
+// ValidateParameters checks that every parameter has a unique name, which can be used in a template, a valid type and a default of its type.
+func ValidateParameters(labTemplate *ltbv1alpha1.LabTemplate) error {
+	parameterNames := map[string]bool{}
+	for _, parameter := range labTemplate.Spec.Parameters {
+		if !parameterNamePattern.MatchString(parameter.Name) {
+			return errors.NewBadRequest(fmt.Sprintf("parameter name %q is invalid, must start with a letter or underscore, followed by letters, digits or underscores", parameter.Name))
+		}
+		if parameterNames[parameter.Name] {
+			return errors.NewBadRequest(fmt.Sprintf("parameter %s is declared more than once", parameter.Name))
+		}
+		parameterNames[parameter.Name] = true
+		switch parameter.Type {
+		case "", ltbv1alpha1.StringParameterType, ltbv1alpha1.IntParameterType, ltbv1alpha1.BoolParameterType, ltbv1alpha1.CIDRParameterType:
+		default:
+			return errors.NewBadRequest(fmt.Sprintf("parameter %s has an invalid type %q, must be string, int, bool or cidr", parameter.Name, parameter.Type))
+		}
+		if parameter.Default == "" {
+			continue
+		}
+		if _, err := ParseParameterValue(parameter.Type, parameter.Default); err != nil {
+			return errors.NewBadRequest(fmt.Sprintf("default of parameter %s is invalid: %s", parameter.Name, err))
+		}
+	}
+	return nil
+}
+
+// ValidateNodes checks that every node has a unique name, which can be used in the names of its resources, a node type, valid ports and valid secret references.
+func ValidateNodes(labTemplate *ltbv1alpha1.LabTemplate) error {
+	nodeNames := map[string]bool{}
+	for _, node := range labTemplate.Spec.Nodes {
+		if messages := validation.IsDNS1123Label(node.Name); len(messages) > 0 {
+			return errors.NewBadRequest(fmt.Sprintf("node name %q is invalid: %s", node.Name, strings.Join(messages, ", ")))
+		}
+		if len(node.Name) > MaxNodeNameLength {
+			return errors.NewBadRequest(fmt.Sprintf("node name %q is longer than %d characters", node.Name, MaxNodeNameLength))
+		}
+		if nodeNames[node.Name] {
+			return errors.NewBadRequest(fmt.Sprintf("node %s is declared more than once", node.Name))
+		}
+		nodeNames[node.Name] = true
+		if node.NodeTypeRef.Type == "" {
+			return errors.NewBadRequest(fmt.Sprintf("node %s has no node type", node.Name))
+		}
+		if err := validatePorts(&node); err != nil {
+			return err
+		}
+		if err := validateSecrets(&node); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validatePorts checks that the ports of a node are valid service ports with unique names and numbers.
+func validatePorts(node *ltbv1alpha1.LabInstanceNodes) error {
+	portNames := map[string]bool{}
+	portNumbers := map[string]bool{}
+	for _, port := range node.Ports {
+		if messages := validation.IsDNS1123Label(port.Name); len(messages) > 0 {
+			return errors.NewBadRequest(fmt.Sprintf("port name %q of node %s is invalid: %s", port.Name, node.Name, strings.Join(messages, ", ")))
+		}
+		if portNames[port.Name] {
+			return errors.NewBadRequest(fmt.Sprintf("port %s of node %s is declared more than once", port.Name, node.Name))
+		}
+		portNames[port.Name] = true
+		if messages := validation.IsValidPortNum(int(port.Port)); len(messages) > 0 {
+			return errors.NewBadRequest(fmt.Sprintf("port %s of node %s has an invalid number %d: %s", port.Name, node.Name, port.Port, strings.Join(messages, ", ")))
+		}
+		if port.Protocol != corev1.ProtocolTCP && port.Protocol != corev1.ProtocolUDP {
+			return errors.NewBadRequest(fmt.Sprintf("port %s of node %s has an invalid protocol %q, must be TCP or UDP", port.Name, node.Name, port.Protocol))
+		}
+		key := fmt.Sprintf("%d/%s", port.Port, port.Protocol)
+		if portNumbers[key] {
+			return errors.NewBadRequest(fmt.Sprintf("port %s of node %s is exposed more than once", key, node.Name))
+		}
+		portNumbers[key] = true
+	}
+	return nil
+}
+
+func (v *LabTemplateValidator) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	v.Client = mgr.GetClient()
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&ltbv1alpha1.LabTemplate{}).
+		WithValidator(v).
+		Complete()
+}
@@ -0,0 +1,189 @@
+package controllers
+
+import (
+	"context"
+
+	ltbv1alpha1 "github.com/Lab-Topology-Builder/LTB-K8s-Backend/api/v1alpha1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("LabTemplate Webhook", func() {
+	var (
+		v           *LabTemplateValidator
+		labTemplate *ltbv1alpha1.LabTemplate
+	)
+
+	BeforeEach(func() {
+		v = &LabTemplateValidator{Client: fake.NewClientBuilder().WithObjects(testNodeVMType, testPodNodeType).Build()}
+		labTemplate = testLabTemplateWithRenderedNodeSpec.DeepCopy()
+		// The link connects a VM, which can't be impaired
+		labTemplate.Spec.Links[0].Impairment = nil
+	})
+
+	Describe("ValidateCreate", func() {
+		It("should accept a valid lab template", func() {
+			Expect(v.ValidateCreate(context.Background(), labTemplate)).To(Succeed())
+		})
+		It("should reject an unknown node type", func() {
+			labTemplate.Spec.Nodes = append(labTemplate.Spec.Nodes, *nodeWithUndefinedNodeType)
+			err := v.ValidateCreate(context.Background(), labTemplate)
+			Expect(apiErrors.IsBadRequest(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("NodeType " + nodeWithUndefinedNodeType.NodeTypeRef.Type + " not found"))
+		})
+		It("should reject an impaired link to a VM", func() {
+			labTemplate.Spec.Links[0].Impairment = &ltbv1alpha1.Impairment{Delay: "10ms"}
+			err := v.ValidateCreate(context.Background(), labTemplate)
+			Expect(apiErrors.IsBadRequest(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("link of VM " + testVMNode.Name))
+		})
+		It("should accept an empty impairment of a link to a VM", func() {
+			labTemplate.Spec.Links[0].Impairment = &ltbv1alpha1.Impairment{}
+			Expect(v.ValidateCreate(context.Background(), labTemplate)).To(Succeed())
+		})
+		It("should reject a link to a missing node", func() {
+			labTemplate.Spec.Links = testLabTemplateWithInvalidLink.Spec.Links
+			Expect(apiErrors.IsBadRequest(v.ValidateCreate(context.Background(), labTemplate))).To(BeTrue())
+		})
+	})
+
+	Describe("ValidateUpdate", func() {
+		It("should accept a node type, which was already referenced", func() {
+			oldLabTemplate := labTemplate.DeepCopy()
+			oldLabTemplate.Spec.Nodes = append(oldLabTemplate.Spec.Nodes, *nodeWithUndefinedNodeType)
+			labTemplate.Spec.Nodes = append(labTemplate.Spec.Nodes, *nodeWithUndefinedNodeType)
+			Expect(v.ValidateUpdate(context.Background(), oldLabTemplate, labTemplate)).To(Succeed())
+		})
+		It("should reject a newly referenced unknown node type", func() {
+			oldLabTemplate := labTemplate.DeepCopy()
+			labTemplate.Spec.Nodes = append(labTemplate.Spec.Nodes, *nodeWithUndefinedNodeType)
+			Expect(v.ValidateUpdate(context.Background(), oldLabTemplate, labTemplate)).ToNot(Succeed())
+		})
+	})
+
+	Describe("ValidateNodes", func() {
+		It("should reject duplicate node names", func() {
+			labTemplate.Spec.Nodes[1].Name = labTemplate.Spec.Nodes[0].Name
+			err := ValidateNodes(labTemplate)
+			Expect(apiErrors.IsBadRequest(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("declared more than once"))
+		})
+		It("should reject node names, which aren't DNS labels", func() {
+			labTemplate.Spec.Nodes[0].Name = "Node_1"
+			Expect(ValidateNodes(labTemplate)).ToNot(Succeed())
+		})
+		It("should reject duplicate ports", func() {
+			labTemplate.Spec.Nodes[0].Ports = append(labTemplate.Spec.Nodes[0].Ports, ltbv1alpha1.Port{Name: "other-ssh-port", Protocol: "TCP", Port: 22})
+			err := ValidateNodes(labTemplate)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("22/TCP"))
+		})
+		It("should reject invalid port numbers and protocols", func() {
+			labTemplate.Spec.Nodes[0].Ports[0].Port = 0
+			Expect(ValidateNodes(labTemplate)).ToNot(Succeed())
+			labTemplate.Spec.Nodes[0].Ports[0].Port = 22
+			labTemplate.Spec.Nodes[0].Ports[0].Protocol = "ICMP"
+			Expect(ValidateNodes(labTemplate)).ToNot(Succeed())
+		})
+		It("should reject invalid and duplicate secret references", func() {
+			labTemplate.Spec.Nodes[0].Secrets = []ltbv1alpha1.SecretReference{{Name: "admin", Generate: true}, {Name: "admin", SecretName: "admin-credentials"}}
+			err := ValidateNodes(labTemplate)
+			Expect(apiErrors.IsBadRequest(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("referenced more than once"))
+			labTemplate.Spec.Nodes[0].Secrets = []ltbv1alpha1.SecretReference{{Name: "admin-password"}}
+			Expect(ValidateNodes(labTemplate)).ToNot(Succeed())
+			labTemplate.Spec.Nodes[0].Secrets = []ltbv1alpha1.SecretReference{{Name: "admin", SecretName: "admin-credentials", Generate: true}}
+			Expect(ValidateNodes(labTemplate)).ToNot(Succeed())
+			labTemplate.Spec.Nodes[0].Secrets = []ltbv1alpha1.SecretReference{{Name: "admin", Generate: true, MountPath: "run/secrets"}}
+			Expect(ValidateNodes(labTemplate)).ToNot(Succeed())
+			labTemplate.Spec.Nodes[0].Secrets = []ltbv1alpha1.SecretReference{{Name: "admin", Generate: true, MountPath: "/run/secrets"}}
+			Expect(ValidateNodes(labTemplate)).To(Succeed())
+		})
+	})
+
+	Describe("ValidateParameters", func() {
+		It("should accept parameters with defaults of their type", func() {
+			labTemplate.Spec.Parameters = []ltbv1alpha1.Parameter{
+				{Name: "subnet", Type: ltbv1alpha1.CIDRParameterType, Default: "10.0.0.0/24"},
+				{Name: "vlan", Type: ltbv1alpha1.IntParameterType, Required: true},
+				{Name: "image"},
+			}
+			Expect(ValidateParameters(labTemplate)).To(Succeed())
+		})
+		It("should reject duplicate or invalid names", func() {
+			labTemplate.Spec.Parameters = []ltbv1alpha1.Parameter{{Name: "vlan"}, {Name: "vlan"}}
+			err := ValidateParameters(labTemplate)
+			Expect(apiErrors.IsBadRequest(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("declared more than once"))
+			labTemplate.Spec.Parameters = []ltbv1alpha1.Parameter{{Name: "vlan-id"}}
+			Expect(apiErrors.IsBadRequest(ValidateParameters(labTemplate))).To(BeTrue())
+		})
+		It("should reject invalid types and defaults", func() {
+			labTemplate.Spec.Parameters = []ltbv1alpha1.Parameter{{Name: "vlan", Type: "float"}}
+			Expect(apiErrors.IsBadRequest(ValidateParameters(labTemplate))).To(BeTrue())
+			labTemplate.Spec.Parameters = []ltbv1alpha1.Parameter{{Name: "subnet", Type: ltbv1alpha1.CIDRParameterType, Default: "10.0.0.0"}}
+			err := ValidateParameters(labTemplate)
+			Expect(apiErrors.IsBadRequest(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("default of parameter subnet"))
+		})
+	})
+})
+
+var _ = Describe("LabTemplate Defaulting", func() {
+	var labTemplate *ltbv1alpha1.LabTemplate
+
+	BeforeEach(func() {
+		labTemplate = testLabTemplateWithRenderedNodeSpec.DeepCopy()
+	})
+
+	Describe("Default", func() {
+		It("should set the defaults of a lab template", func() {
+			labTemplate.Spec.Nodes[0].NodeTypeRef.Version = ""
+			labTemplate.Spec.Nodes[0].Ports[0].Protocol = ""
+			Expect((&LabTemplateDefaulter{}).Default(context.Background(), labTemplate)).To(Succeed())
+			Expect(labTemplate.Spec.Nodes[0].NodeTypeRef.Version).To(Equal(DefaultNodeTypeVersion))
+			Expect(labTemplate.Spec.Nodes[0].Ports[0].Protocol).To(BeEquivalentTo("TCP"))
+			Expect(labTemplate.Spec.Links[0].Name).To(Equal(testVMNode.Name + "-eth1-" + testPodNode.Name + "-eth1"))
+		})
+		It("should keep a valid lab template", func() {
+			DefaultLabTemplate(labTemplate)
+			defaultedLabTemplate := labTemplate.DeepCopy()
+			DefaultLabTemplate(labTemplate)
+			Expect(labTemplate).To(Equal(defaultedLabTemplate))
+			Expect(ValidateLabTemplate(labTemplate)).To(Succeed())
+		})
+	})
+
+	Describe("DefaultLabTemplate", func() {
+		It("should name interfaces and link endpoints with the next free interface name", func() {
+			labTemplate.Spec.Nodes[1].Interfaces = []ltbv1alpha1.NodeInterface{{IPv4: "10.0.0.1/24"}, {Name: "eth1"}}
+			labTemplate.Spec.Links = append(labTemplate.Spec.Links, ltbv1alpha1.Link{
+				Endpoints: []ltbv1alpha1.LinkEndpoint{{Node: testVMNode.Name}, {Node: testPodNode.Name}},
+			})
+			DefaultLabTemplate(labTemplate)
+			Expect(labTemplate.Spec.Nodes[1].Interfaces[0].Name).To(Equal("eth2"))
+			Expect(labTemplate.Spec.Links[1].Endpoints).To(Equal([]ltbv1alpha1.LinkEndpoint{{Node: testVMNode.Name, Interface: "eth2"}, {Node: testPodNode.Name, Interface: "eth3"}}))
+			Expect(ValidateLabTemplate(labTemplate)).To(Succeed())
+		})
+		It("should normalize the node names in the nodes and links", func() {
+			labTemplate.Spec.Nodes[0].Name = "Router_1"
+			labTemplate.Spec.Links[0].Endpoints[0].Node = "Router_1"
+			DefaultLabTemplate(labTemplate)
+			Expect(labTemplate.Spec.Nodes[0].Name).To(Equal("router-1"))
+			Expect(labTemplate.Spec.Links[0].Endpoints[0].Node).To(Equal("router-1"))
+		})
+	})
+
+	Describe("NormalizeNodeName", func() {
+		It("should convert a node name into a DNS label", func() {
+			Expect(NormalizeNodeName("Core Switch.1")).To(Equal("core-switch-1"))
+		})
+		It("should shorten long node names", func() {
+			Expect(NormalizeNodeName("a-very-long-node-name-of-a-router")).To(Equal("a-very-long-node-name-of"))
+			Expect(NormalizeNodeName("a-very-long-node-name-of-a-router")).To(HaveLen(MaxNodeNameLength))
+			Expect(NormalizeNodeName("a-very-long-node-name-o--router")).To(Equal("a-very-long-node-name-o"))
+		})
+	})
+})
@@ -2,17 +2,30 @@ package controllers
 
 import (
 	"context"
-
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
 	"strings"
 
 	corev1 "k8s.io/api/core/v1"
 
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	ltbv1alpha1 "github.com/Lab-Topology-Builder/LTB-K8s-Backend/api/v1alpha1"
 	util "github.com/Lab-Topology-Builder/LTB-K8s-Backend/util"
@@ -22,6 +35,8 @@ import (
 type NodeTypeReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+	// Records events of the node types, no events are recorded if nil.
+	Recorder record.EventRecorder
 }
 
 var (
@@ -45,11 +60,31 @@ var (
 			},
 		},
 	}
+	// Peer of the test data, which is connected to its first interface
+	testPeerNode = util.NodeContext{
+		LabInstanceNodes: ltbv1alpha1.LabInstanceNodes{
+			Name:        "peer",
+			NodeTypeRef: TestNodeData.NodeTypeRef,
+			Interfaces:  []ltbv1alpha1.NodeInterface{{Name: "eth1", IPv4: "192.168.0.2/24"}},
+		},
+		Index: 1,
+	}
+	// Template context of the test data for Template rendering
+	TestTemplateContext = util.TemplateContext{
+		LabInstanceNodes: TestNodeData,
+		Node:             util.NodeContext{LabInstanceNodes: TestNodeData},
+		Lab:              util.LabContext{Name: "test", Nodes: []util.NodeContext{{LabInstanceNodes: TestNodeData}, testPeerNode}},
+		Instance:         util.InstanceContext{Name: "test", Namespace: "test", DNSAddress: "example.com"},
+		Links:            []util.LinkContext{{Name: "test-peer", Interface: TestNodeData.Interfaces[0], Peer: testPeerNode.Name, PeerInterface: testPeerNode.Interfaces[0]}},
+		Peers:            []util.NodeContext{testPeerNode},
+	}
 )
 
 //+kubebuilder:rbac:groups=ltb-backend.ltb,resources=nodetypes,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=ltb-backend.ltb,resources=nodetypes/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=ltb-backend.ltb,resources=nodetypes/finalizers,verbs=update
+//+kubebuilder:rbac:groups=ltb-backend.ltb,resources=labtemplates,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 func (r *NodeTypeReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	l := log.FromContext(ctx)
@@ -64,48 +99,182 @@ func (r *NodeTypeReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 		l.Error(err, "Failed to get NodeType")
 		return ctrl.Result{}, err
 	}
-	var renderedNodeSpec strings.Builder
-	if err = util.ParseAndRenderTemplate(nodetype, &renderedNodeSpec, TestNodeData); err != nil {
-		l.Error(err, "Failed to render template")
+	previousValid := meta.FindStatusCondition(nodetype.Status.Conditions, ltbv1alpha1.ValidCondition)
+	labTemplates := &ltbv1alpha1.LabTemplateList{}
+	err = r.List(ctx, labTemplates, client.MatchingFields{nodeTypeRefField: nodetype.Name})
+	if err != nil {
+		l.Error(err, "Failed to list LabTemplates")
+		return ctrl.Result{}, err
+	}
+	sort.Slice(labTemplates.Items, func(i, j int) bool { return labTemplates.Items[i].Name < labTemplates.Items[j].Name })
+	usedBy := []string{}
+	for _, labTemplate := range labTemplates.Items {
+		usedBy = append(usedBy, labTemplate.Name)
+	}
+
+	validationErr := ValidateNodeType(nodetype)
+	parameters, _ := util.ReferencedParameters(nodetype.Spec.NodeSpec)
+	if validationErr == nil && len(parameters) > 0 {
+		for i := range labTemplates.Items {
+			err = ValidateNodeTypeWithParameters(nodetype, &labTemplates.Items[i])
+			if err != nil {
+				validationErr = fmt.Errorf("Failed to render the node spec with the parameters of LabTemplate %s: %w", labTemplates.Items[i].Name, err)
+				break
+			}
+		}
+	}
+	switch {
+	case validationErr != nil:
+		l.Error(validationErr, "Invalid NodeType")
+		setValidCondition(nodetype, metav1.ConditionFalse, InvalidReason, validationErr.Error())
+	case len(parameters) > 0 && len(usedBy) == 0:
+		setValidCondition(nodetype, metav1.ConditionUnknown, NotValidatedReason, fmt.Sprintf("The node spec is a valid template, it is rendered and validated, once a lab template declares the types of the parameters %s", strings.Join(parameters, ", ")))
+	case len(parameters) > 0:
+		setValidCondition(nodetype, metav1.ConditionTrue, ValidReason, fmt.Sprintf("The node spec renders to a valid %s spec with the parameters %s of the lab templates %s", nodetype.Spec.Kind, strings.Join(parameters, ", "), strings.Join(usedBy, ", ")))
+	default:
+		setValidCondition(nodetype, metav1.ConditionTrue, ValidReason, fmt.Sprintf("The node spec renders to a valid %s spec", nodetype.Spec.Kind))
+	}
+
+	nodetype.Status.UsedBy = usedBy
+	nodetype.Status.SpecHash, err = NodeTypeSpecHash(nodetype)
+	if err != nil {
 		return ctrl.Result{}, err
 	}
+	nodetype.Status.ObservedGeneration = nodetype.Generation
+	err = r.Status().Update(ctx, nodetype)
+	if err != nil {
+		l.Error(err, "Failed to update NodeType status")
+		return ctrl.Result{}, err
+	}
+
+	valid := meta.FindStatusCondition(nodetype.Status.Conditions, ltbv1alpha1.ValidCondition)
+	if previousValid == nil || previousValid.Status != valid.Status || previousValid.Message != valid.Message {
+		if validationErr != nil {
+			recordEvent(r.Recorder, nodetype, corev1.EventTypeWarning, InvalidReason, "%s", validationErr)
+		} else {
+			recordEvent(r.Recorder, nodetype, corev1.EventTypeNormal, valid.Reason, "%s", valid.Message)
+		}
+	}
+	// An invalid node spec only becomes valid with a new generation, so it isn't retried
+	return ctrl.Result{}, nil
+}
+
+func setValidCondition(nodetype *ltbv1alpha1.NodeType, status metav1.ConditionStatus, reason string, message string) {
+	meta.SetStatusCondition(&nodetype.Status.Conditions, metav1.Condition{
+		Type:               ltbv1alpha1.ValidCondition,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: nodetype.Generation,
+	})
+}
+
+// NodeTypeSpecHash returns the SHA-256 hash of the spec of a node type.
+func NodeTypeSpecHash(nodetype *ltbv1alpha1.NodeType) (string, error) {
+	spec, err := json.Marshal(nodetype.Spec)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.Sum256(spec)
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// ValidateNodeType checks the kind and the config delivery of a node type and that its node spec is a valid template.
+// A node spec without parameters is rendered with test data and has to decode into a valid spec of its kind.
+// The types of the parameters are declared by the lab templates, so a node spec with parameters is only parsed here,
+// and rendered by ValidateNodeTypeWithParameters with the parameters of every lab template using it.
+// Referenced secrets are rendered with test names, as the lab templates name their Secrets.
+func ValidateNodeType(nodetype *ltbv1alpha1.NodeType) error {
+	if nodetype.Spec.Kind != "vm" && nodetype.Spec.Kind != "pod" {
+		return errors.NewBadRequest(fmt.Sprintf("Invalid kind %q, must be pod or vm", nodetype.Spec.Kind))
+	}
+	if err := ValidateConfigDelivery(nodetype); err != nil {
+		return err
+	}
+	parameters, err := util.ReferencedParameters(nodetype.Spec.NodeSpec)
+	if err != nil {
+		return err
+	}
+	if len(parameters) > 0 {
+		return nil
+	}
+	templateContext, err := nodeTypeTestContext(nodetype)
+	if err != nil {
+		return err
+	}
+	return validateNodeSpec(nodetype, templateContext)
+}
+
+// ValidateNodeTypeWithParameters renders the node spec of a node type with the parameters declared by a lab template,
+// which have their defaults or the zero values of their types, and checks, that it decodes into a valid spec of its kind.
+func ValidateNodeTypeWithParameters(nodetype *ltbv1alpha1.NodeType, labTemplate *ltbv1alpha1.LabTemplate) error {
+	templateContext, err := nodeTypeTestContext(nodetype)
+	if err != nil {
+		return err
+	}
+	templateContext.Params, err = GetParameters(labTemplate, nil)
+	if err != nil {
+		return err
+	}
+	return validateNodeSpec(nodetype, templateContext)
+}
+
+// nodeTypeTestContext returns the test data to render the node spec of a node type with, every referenced secret gets a test name.
+func nodeTypeTestContext(nodetype *ltbv1alpha1.NodeType) (util.TemplateContext, error) {
+	templateContext := TestTemplateContext
+	secrets, err := util.ReferencedSecrets(nodetype.Spec.NodeSpec)
+	if err != nil {
+		return templateContext, err
+	}
+	templateContext.Secrets = map[string]string{}
+	for _, secret := range secrets {
+		templateContext.Secrets[secret] = "test-" + sanitizeName(secret)
+	}
+	return templateContext, nil
+}
+
+// validateNodeSpec renders the node spec of a node type with a template context and checks, that it decodes into a valid spec of its kind.
+func validateNodeSpec(nodetype *ltbv1alpha1.NodeType, templateContext util.TemplateContext) error {
+	var renderedNodeSpec strings.Builder
+	if err := util.ParseAndRenderTemplate(nodetype.Spec.NodeSpec, &renderedNodeSpec, templateContext); err != nil {
+		return err
+	}
 	nodeSpecBytes := []byte(renderedNodeSpec.String())
 	if nodetype.Spec.Kind == "vm" {
 		vmSpec := kubevirtv1.VirtualMachineSpec{}
-		err := yaml.Unmarshal(nodeSpecBytes, &vmSpec)
-		if err != nil {
-			l.Error(err, "Failed to unmarshal NodeSpec YAML")
-			return ctrl.Result{}, err
+		if err := yaml.Unmarshal(nodeSpecBytes, &vmSpec); err != nil {
+			return fmt.Errorf("Failed to decode the rendered VM spec: %w", err)
 		}
 		if vmSpec.Template == nil {
-			err := errors.NewBadRequest("Invalid VM Spec")
-			l.Error(err, "Template field is missing")
-			return ctrl.Result{}, err
-		}
-		l.Info("Decoded VM Spec", "Spec", vmSpec)
-	} else if nodetype.Spec.Kind == "pod" {
-		podSpec := corev1.PodSpec{}
-		err := yaml.Unmarshal(nodeSpecBytes, &podSpec)
-		if err != nil {
-			l.Error(err, "Failed to unmarshal NodeSpec YAML")
-			return ctrl.Result{}, err
-		}
-		if podSpec.Containers == nil {
-			err := errors.NewBadRequest("Invalid Pod Spec")
-			l.Error(err, "Containers field is missing")
-			return ctrl.Result{}, err
+			return errors.NewBadRequest("Invalid VM Spec, the template field is missing")
 		}
-		l.Info("Decoded Pod Spec", "Spec", podSpec)
-	} else {
-		// invalid kind
-		return ctrl.Result{}, errors.NewBadRequest("Invalid Kind")
+		return nil
 	}
-
-	return ctrl.Result{}, nil
+	podSpec := corev1.PodSpec{}
+	if err := yaml.Unmarshal(nodeSpecBytes, &podSpec); err != nil {
+		return fmt.Errorf("Failed to decode the rendered pod spec: %w", err)
+	}
+	if podSpec.Containers == nil {
+		return errors.NewBadRequest("Invalid Pod Spec, the containers field is missing")
+	}
+	return nil
 }
 
 func (r *NodeTypeReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&ltbv1alpha1.NodeType{}).
+		// Only changes of the spec are validated, the controller updates the status itself
+		For(&ltbv1alpha1.NodeType{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		// The lab templates, which use a node type, are listed in its status
+		Watches(&source.Kind{Type: &ltbv1alpha1.LabTemplate{}}, handler.EnqueueRequestsFromMapFunc(nodeTypesOfLabTemplate), builder.WithPredicates(predicate.GenerationChangedPredicate{})).
 		Complete(r)
 }
+
+// nodeTypesOfLabTemplate returns the node types, which are referenced by a lab template.
+// On updates, it is called for the old and the new lab template, so node types, which are no longer referenced, are requeued as well.
+func nodeTypesOfLabTemplate(object client.Object) []reconcile.Request {
+	requests := []reconcile.Request{}
+	for _, nodeType := range NodeTypeRefs(object) {
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: nodeType}})
+	}
+	return requests
+}
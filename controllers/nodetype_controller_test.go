@@ -6,24 +6,31 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	ltbv1alpha1 "github.com/Lab-Topology-Builder/LTB-K8s-Backend/api/v1alpha1"
 )
 
 var _ = Describe("NodeTye Controller", func() {
 	var (
-		ctx context.Context
-		req ctrl.Request
-		ln  *NodeTypeReconciler
+		ctx      context.Context
+		req      ctrl.Request
+		ln       *NodeTypeReconciler
+		recorder *record.FakeRecorder
 	)
 
 	Describe("Reconcile", func() {
 		BeforeEach(func() {
 			req = ctrl.Request{}
-			fakeClient = fake.NewClientBuilder().WithObjects(testPodNodeType, testVM2).Build()
-			ln = &NodeTypeReconciler{Client: fakeClient, Scheme: scheme.Scheme}
+			fakeClient = fake.NewClientBuilder().WithObjects(testPodNodeType, testVM2).WithIndex(&ltbv1alpha1.LabTemplate{}, nodeTypeRefField, NodeTypeRefs).Build()
+			recorder = record.NewFakeRecorder(10)
+			ln = &NodeTypeReconciler{Client: fakeClient, Scheme: scheme.Scheme, Recorder: recorder}
 		})
 		Context("NodeType doesn't exists", func() {
 			BeforeEach(func() {
@@ -37,48 +44,65 @@ var _ = Describe("NodeTye Controller", func() {
 		})
 		Context("NodeType exists, but VM YAML is invalid", func() {
 			BeforeEach(func() {
-				ln.Client = fake.NewClientBuilder().WithObjects(invalidNodeSpecVMNodeType).Build()
+				ln.Client = fake.NewClientBuilder().WithObjects(invalidNodeSpecVMNodeType).WithIndex(&ltbv1alpha1.LabTemplate{}, nodeTypeRefField, NodeTypeRefs).Build()
 				req.NamespacedName = types.NamespacedName{Name: invalidNodeSpecVMNodeType.Name}
 			})
-			It("should return error while unmarshaling, YAML is invalid", func() {
+			It("should show the error while unmarshaling in the status, YAML is invalid", func() {
 				result, err := ln.Reconcile(ctx, req)
 				Expect(result).To(Equal(ctrl.Result{}))
-				Expect(err).ToNot(BeNil())
-				Expect(err.Error()).To(ContainSubstring("error converting YAML to JSON"))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(recorder.Events).To(Receive(HavePrefix("Warning Invalid")))
+				nodeType := &ltbv1alpha1.NodeType{}
+				Expect(ln.Get(ctx, req.NamespacedName, nodeType)).To(Succeed())
+				valid := meta.FindStatusCondition(nodeType.Status.Conditions, ltbv1alpha1.ValidCondition)
+				Expect(valid.Status).To(Equal(metav1.ConditionFalse))
+				Expect(valid.Message).To(ContainSubstring("error converting YAML to JSON"))
 			})
 		})
 		Context("NodeType exists, but Pod YAML is invalid", func() {
 			BeforeEach(func() {
-				ln.Client = fake.NewClientBuilder().WithObjects(invalidNodeSpecPodNodeType).Build()
+				ln.Client = fake.NewClientBuilder().WithObjects(invalidNodeSpecPodNodeType).WithIndex(&ltbv1alpha1.LabTemplate{}, nodeTypeRefField, NodeTypeRefs).Build()
 				req.NamespacedName = types.NamespacedName{Name: invalidNodeSpecPodNodeType.Name}
 			})
-			It("should return error while unmarshaling, YAML is invalid", func() {
+			It("should show the error while unmarshaling in the status, YAML is invalid", func() {
 				result, err := ln.Reconcile(ctx, req)
 				Expect(result).To(Equal(ctrl.Result{}))
-				Expect(err).ToNot(BeNil())
-				Expect(err.Error()).To(ContainSubstring("error converting YAML to JSON"))
+				Expect(err).ToNot(HaveOccurred())
+				nodeType := &ltbv1alpha1.NodeType{}
+				Expect(ln.Get(ctx, req.NamespacedName, nodeType)).To(Succeed())
+				valid := meta.FindStatusCondition(nodeType.Status.Conditions, ltbv1alpha1.ValidCondition)
+				Expect(valid.Status).To(Equal(metav1.ConditionFalse))
+				Expect(valid.Message).To(ContainSubstring("error converting YAML to JSON"))
 			})
 		})
 		Context("NodeType exists, with correct YAML but wrong content", func() {
 			BeforeEach(func() {
-				ln.Client = fake.NewClientBuilder().WithObjects(failingVMNodeType, failingPodNodeType).Build()
+				ln.Client = fake.NewClientBuilder().WithObjects(failingVMNodeType, failingPodNodeType).WithIndex(&ltbv1alpha1.LabTemplate{}, nodeTypeRefField, NodeTypeRefs).Build()
 				req.NamespacedName = types.NamespacedName{Name: failingVMNodeType.Name}
 			})
-			It("should return error while unmarshaling to VMSpec", func() {
+			It("should show the error while unmarshaling to VMSpec in the status", func() {
 				result, err := ln.Reconcile(ctx, req)
 				Expect(result).To(Equal(ctrl.Result{}))
-				Expect(err).ToNot(BeNil())
+				Expect(err).ToNot(HaveOccurred())
+				nodeType := &ltbv1alpha1.NodeType{}
+				Expect(ln.Get(ctx, req.NamespacedName, nodeType)).To(Succeed())
+				valid := meta.FindStatusCondition(nodeType.Status.Conditions, ltbv1alpha1.ValidCondition)
+				Expect(valid.Status).To(Equal(metav1.ConditionFalse))
 			})
-			It("should return error while unmarshaling to PodSpec", func() {
+			It("should show the error while unmarshaling to PodSpec in the status", func() {
 				req.NamespacedName = types.NamespacedName{Name: failingPodNodeType.Name}
 				result, err := ln.Reconcile(ctx, req)
 				Expect(result).To(Equal(ctrl.Result{}))
-				Expect(err).ToNot(BeNil())
+				Expect(err).ToNot(HaveOccurred())
+				nodeType := &ltbv1alpha1.NodeType{}
+				Expect(ln.Get(ctx, req.NamespacedName, nodeType)).To(Succeed())
+				valid := meta.FindStatusCondition(nodeType.Status.Conditions, ltbv1alpha1.ValidCondition)
+				Expect(valid.Status).To(Equal(metav1.ConditionFalse))
 			})
 		})
 		Context("Rendering NodeSpec for VM works", func() {
 			BeforeEach(func() {
-				ln.Client = fake.NewClientBuilder().WithObjects(testNodeVMType).Build()
+				ln.Client = fake.NewClientBuilder().WithObjects(testNodeVMType).WithIndex(&ltbv1alpha1.LabTemplate{}, nodeTypeRefField, NodeTypeRefs).Build()
 				req.NamespacedName = types.NamespacedName{Name: testNodeVMType.Name}
 			})
 			It("should render the VMSpec successfully", func() {
@@ -89,28 +113,123 @@ var _ = Describe("NodeTye Controller", func() {
 		})
 		Context("Rendering NodeSpec for pod works", func() {
 			BeforeEach(func() {
-				ln.Client = fake.NewClientBuilder().WithObjects(testPodNodeType).Build()
+				ln.Client = fake.NewClientBuilder().WithObjects(testPodNodeType).WithIndex(&ltbv1alpha1.LabTemplate{}, nodeTypeRefField, NodeTypeRefs).Build()
 				req.NamespacedName = types.NamespacedName{Name: testPodNodeType.Name}
 			})
 			It("should render the PodSpec successfully", func() {
 				result, err := ln.Reconcile(ctx, req)
 				Expect(result).To(Equal(ctrl.Result{}))
 				Expect(err).ToNot(HaveOccurred())
+				Expect(recorder.Events).To(Receive(Equal("Normal Valid The node spec renders to a valid pod spec")))
+			})
+			It("should show the validation result, the lab templates using it and the hash of the spec in the status", func() {
+				otherLabTemplate := testLabTemplateWithoutRenderedNodeSpec.DeepCopy()
+				otherLabTemplate.Name = "other-labtemplate"
+				ln.Client = fake.NewClientBuilder().WithObjects(testPodNodeType, otherLabTemplate, testLabTemplateWithoutRenderedNodeSpec2).WithIndex(&ltbv1alpha1.LabTemplate{}, nodeTypeRefField, NodeTypeRefs).Build()
+				_, err := ln.Reconcile(ctx, req)
+				Expect(err).ToNot(HaveOccurred())
+				nodeType := &ltbv1alpha1.NodeType{}
+				Expect(ln.Get(ctx, req.NamespacedName, nodeType)).To(Succeed())
+				Expect(meta.IsStatusConditionTrue(nodeType.Status.Conditions, ltbv1alpha1.ValidCondition)).To(BeTrue())
+				Expect(nodeType.Status.UsedBy).To(Equal([]string{otherLabTemplate.Name}))
+				Expect(nodeType.Status.SpecHash).To(HaveLen(64))
+			})
+			It("should not record another event, if the validation result didn't change", func() {
+				_, err := ln.Reconcile(ctx, req)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(recorder.Events).To(Receive())
+				_, err = ln.Reconcile(ctx, req)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(recorder.Events).ToNot(Receive())
+			})
+		})
+		Context("NodeType uses parameters", func() {
+			var (
+				nodeType    *ltbv1alpha1.NodeType
+				labTemplate *ltbv1alpha1.LabTemplate
+			)
+			BeforeEach(func() {
+				nodeType = testPodNodeType.DeepCopy()
+				nodeType.Spec.NodeSpec = "containers:\n  - name: {{ .Params.name }}\n    image: {{ cidrhost .Params.subnet .Params.host }}"
+				labTemplate = testLabTemplateWithoutRenderedNodeSpec.DeepCopy()
+				labTemplate.Spec.Parameters = []ltbv1alpha1.Parameter{
+					{Name: "name", Type: ltbv1alpha1.StringParameterType},
+					{Name: "subnet", Type: ltbv1alpha1.CIDRParameterType},
+					{Name: "host", Type: ltbv1alpha1.IntParameterType},
+				}
+				req.NamespacedName = types.NamespacedName{Name: nodeType.Name}
+			})
+			It("should leave the validation unknown, while no lab template declares the parameters", func() {
+				ln.Client = fake.NewClientBuilder().WithObjects(nodeType).WithIndex(&ltbv1alpha1.LabTemplate{}, nodeTypeRefField, NodeTypeRefs).Build()
+				_, err := ln.Reconcile(ctx, req)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(recorder.Events).To(Receive(HavePrefix("Normal " + NotValidatedReason)))
+				Expect(ln.Get(ctx, req.NamespacedName, nodeType)).To(Succeed())
+				valid := meta.FindStatusCondition(nodeType.Status.Conditions, ltbv1alpha1.ValidCondition)
+				Expect(valid.Status).To(Equal(metav1.ConditionUnknown))
+				Expect(valid.Message).To(ContainSubstring("parameters host, name, subnet"))
+			})
+			It("should render the node spec with the types of the parameters declared by the lab templates", func() {
+				ln.Client = fake.NewClientBuilder().WithObjects(nodeType, labTemplate).WithIndex(&ltbv1alpha1.LabTemplate{}, nodeTypeRefField, NodeTypeRefs).Build()
+				_, err := ln.Reconcile(ctx, req)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(ln.Get(ctx, req.NamespacedName, nodeType)).To(Succeed())
+				valid := meta.FindStatusCondition(nodeType.Status.Conditions, ltbv1alpha1.ValidCondition)
+				Expect(valid.Status).To(Equal(metav1.ConditionTrue))
+				Expect(valid.Message).To(ContainSubstring(labTemplate.Name))
+			})
+			It("should show the error, if the node spec doesn't render with the parameters of a lab template", func() {
+				labTemplate.Spec.Parameters[1].Type = ltbv1alpha1.StringParameterType
+				ln.Client = fake.NewClientBuilder().WithObjects(nodeType, labTemplate).WithIndex(&ltbv1alpha1.LabTemplate{}, nodeTypeRefField, NodeTypeRefs).Build()
+				_, err := ln.Reconcile(ctx, req)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(recorder.Events).To(Receive(HavePrefix("Warning Invalid")))
+				Expect(ln.Get(ctx, req.NamespacedName, nodeType)).To(Succeed())
+				valid := meta.FindStatusCondition(nodeType.Status.Conditions, ltbv1alpha1.ValidCondition)
+				Expect(valid.Status).To(Equal(metav1.ConditionFalse))
+				Expect(valid.Message).To(ContainSubstring("parameters of LabTemplate " + labTemplate.Name))
 			})
 		})
 		Context("Invalid nodetype kind", func() {
 			BeforeEach(func() {
-				ln.Client = fake.NewClientBuilder().WithObjects(invalidKindNodeType).Build()
+				ln.Client = fake.NewClientBuilder().WithObjects(invalidKindNodeType).WithIndex(&ltbv1alpha1.LabTemplate{}, nodeTypeRefField, NodeTypeRefs).Build()
 				req.NamespacedName = types.NamespacedName{Name: invalidKindNodeType.Name}
 			})
-			It("should return error", func() {
+			It("should show the error in the status without retrying", func() {
 				result, err := ln.Reconcile(ctx, req)
 				Expect(result).To(Equal(ctrl.Result{}))
-				Expect(err).ToNot(BeNil())
+				Expect(err).ToNot(HaveOccurred())
+				Expect(recorder.Events).To(Receive(HavePrefix("Warning Invalid Invalid kind")))
+				nodeType := &ltbv1alpha1.NodeType{}
+				Expect(ln.Get(ctx, req.NamespacedName, nodeType)).To(Succeed())
+				valid := meta.FindStatusCondition(nodeType.Status.Conditions, ltbv1alpha1.ValidCondition)
+				Expect(valid.Status).To(Equal(metav1.ConditionFalse))
+				Expect(valid.Message).To(ContainSubstring("Invalid kind"))
 			})
 		})
 	})
 
+	Describe("NodeTypeSpecHash", func() {
+		It("should only change with the spec", func() {
+			hash, err := NodeTypeSpecHash(testPodNodeType)
+			Expect(err).ToNot(HaveOccurred())
+			nodeType := testPodNodeType.DeepCopy()
+			nodeType.Labels = map[string]string{"app": "ltb"}
+			Expect(NodeTypeSpecHash(nodeType)).To(Equal(hash))
+			nodeType.Spec.NodeSpec += "\n"
+			Expect(NodeTypeSpecHash(nodeType)).ToNot(Equal(hash))
+		})
+	})
+
+	Describe("nodeTypesOfLabTemplate", func() {
+		It("should requeue every node type of the lab template", func() {
+			requests := nodeTypesOfLabTemplate(testLabTemplateWithoutRenderedNodeSpec)
+			Expect(requests).To(HaveLen(2))
+			Expect(requests[0].Name).To(Equal(testNodeVMType.Name))
+			Expect(requests[1].Name).To(Equal(testPodNodeType.Name))
+		})
+	})
+
 	Describe("SetupWithManager", func() {
 		It("should return error", func() {
 			err := ln.SetupWithManager(nil)
@@ -0,0 +1,41 @@
+package controllers
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+
+	ltbv1alpha1 "github.com/Lab-Topology-Builder/LTB-K8s-Backend/api/v1alpha1"
+)
+
+// NodeTypeValidator rejects node types with an invalid kind or a node spec, which doesn't render and decode into a spec of its kind.
+type NodeTypeValidator struct{}
+
+var _ webhook.CustomValidator = &NodeTypeValidator{}
+
+//+kubebuilder:webhook:path=/validate-ltb-backend-ltb-v1alpha1-nodetype,mutating=false,failurePolicy=fail,sideEffects=None,groups=ltb-backend.ltb,resources=nodetypes,verbs=create;update,versions=v1alpha1,name=vnodetype.ltb-backend.ltb,admissionReviewVersions=v1
+
+func (v *NodeTypeValidator) ValidateCreate(ctx context.Context, obj runtime.Object) error {
+	return ValidateNodeType(obj.(*ltbv1alpha1.NodeType))
+}
+
+func (v *NodeTypeValidator) ValidateUpdate(ctx context.Context, oldObj runtime.Object, newObj runtime.Object) error {
+	nodeType := newObj.(*ltbv1alpha1.NodeType)
+	if !nodeType.DeletionTimestamp.IsZero() {
+		return nil
+	}
+	return ValidateNodeType(nodeType)
+}
+
+func (v *NodeTypeValidator) ValidateDelete(ctx context.Context, obj runtime.Object) error {
+	return nil
+}
+
+func (v *NodeTypeValidator) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&ltbv1alpha1.NodeType{}).
+		WithValidator(v).
+		Complete()
+}
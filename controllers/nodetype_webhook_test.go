@@ -0,0 +1,66 @@
+package controllers
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("NodeType Webhook", func() {
+	var v *NodeTypeValidator
+
+	BeforeEach(func() {
+		v = &NodeTypeValidator{}
+	})
+
+	Describe("ValidateCreate", func() {
+		It("should accept a valid pod node type", func() {
+			Expect(v.ValidateCreate(context.Background(), testPodNodeType.DeepCopy())).To(Succeed())
+		})
+		It("should accept a valid VM node type", func() {
+			Expect(v.ValidateCreate(context.Background(), testNodeVMType.DeepCopy())).To(Succeed())
+		})
+		It("should reject an invalid kind", func() {
+			err := v.ValidateCreate(context.Background(), invalidKindNodeType.DeepCopy())
+			Expect(apiErrors.IsBadRequest(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("must be pod or vm"))
+		})
+		It("should reject a node spec, which doesn't decode", func() {
+			err := v.ValidateCreate(context.Background(), invalidNodeSpecPodNodeType.DeepCopy())
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("error converting YAML to JSON"))
+		})
+		It("should reject a node spec without containers", func() {
+			Expect(v.ValidateCreate(context.Background(), failingPodNodeType.DeepCopy())).ToNot(Succeed())
+		})
+		It("should only parse a node spec, which uses parameters, as the lab templates declare their types", func() {
+			nodeType := testPodNodeType.DeepCopy()
+			nodeType.Spec.NodeSpec = "containers: {{ .Params.name }}"
+			Expect(v.ValidateCreate(context.Background(), nodeType)).To(Succeed())
+			nodeType.Spec.NodeSpec = "containers: {{ .Params.name"
+			Expect(v.ValidateCreate(context.Background(), nodeType)).ToNot(Succeed())
+		})
+		It("should render a node spec, which uses secrets, with test names", func() {
+			nodeType := testPodNodeType.DeepCopy()
+			nodeType.Spec.NodeSpec = "containers:\n  - name: test\n    image: ubuntu\n    envFrom:\n      - secretRef:\n          name: {{ .Secrets.admin }}"
+			Expect(v.ValidateCreate(context.Background(), nodeType)).To(Succeed())
+			nodeType.Spec.NodeSpec = "containers: {{ .Secrets.admin }}"
+			Expect(v.ValidateCreate(context.Background(), nodeType)).ToNot(Succeed())
+		})
+	})
+
+	Describe("ValidateUpdate", func() {
+		It("should reject an invalid node spec", func() {
+			Expect(v.ValidateUpdate(context.Background(), testNodeVMType.DeepCopy(), failingVMNodeType.DeepCopy())).ToNot(Succeed())
+		})
+		It("should accept a node type, which is deleted", func() {
+			nodeType := failingVMNodeType.DeepCopy()
+			now := metav1.Now()
+			nodeType.DeletionTimestamp = &now
+			Expect(v.ValidateUpdate(context.Background(), testNodeVMType.DeepCopy(), nodeType)).To(Succeed())
+		})
+	})
+})
@@ -0,0 +1,243 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	ltbv1alpha1 "github.com/Lab-Topology-Builder/LTB-K8s-Backend/api/v1alpha1"
+	"github.com/Lab-Topology-Builder/LTB-K8s-Backend/labnet"
+)
+
+// Interval to check again, while the lab instance or the lab node of a packet capture doesn't exist yet.
+const packetCaptureRetryInterval = 10 * time.Second
+
+type PacketCaptureReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+//+kubebuilder:rbac:groups=ltb-backend.ltb,resources=packetcaptures,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=ltb-backend.ltb,resources=packetcaptures/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=ltb-backend.ltb,resources=packetcaptures/finalizers,verbs=update
+//+kubebuilder:rbac:groups="",resources=pods/ephemeralcontainers,verbs=get;update;patch
+//+kubebuilder:rbac:groups=ltb-backend.ltb,resources=labinstances,verbs=get;list;watch
+//+kubebuilder:rbac:groups=ltb-backend.ltb,resources=labtemplates,verbs=get;list;watch
+
+func (r *PacketCaptureReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+	packetCapture := &ltbv1alpha1.PacketCapture{}
+	err := r.Get(ctx, req.NamespacedName, packetCapture)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			log.Info("PacketCapture resource not found. Ignoring since object must be deleted")
+			return ctrl.Result{}, client.IgnoreNotFound(err)
+		}
+		log.Error(err, "Failed to get PacketCapture")
+		return ctrl.Result{}, err
+	}
+
+	labInstance := &ltbv1alpha1.LabInstance{}
+	err = r.Get(ctx, types.NamespacedName{Namespace: packetCapture.Namespace, Name: packetCapture.Spec.LabInstanceRef}, labInstance)
+	if errors.IsNotFound(err) {
+		_, err = r.updateStatus(ctx, packetCapture, ltbv1alpha1.PacketCapturePending, fmt.Sprintf("Waiting for LabInstance %s", packetCapture.Spec.LabInstanceRef))
+		return ctrl.Result{RequeueAfter: packetCaptureRetryInterval}, err
+	}
+	if err != nil {
+		log.Error(err, "Failed to get LabInstance", "LabInstance", packetCapture.Spec.LabInstanceRef)
+		return ctrl.Result{}, err
+	}
+	// The packet capture is deleted with its lab instance
+	if !hasOwnerReference(packetCapture, labInstance) {
+		if err := controllerutil.SetOwnerReference(labInstance, packetCapture, r.Scheme); err != nil {
+			log.Error(err, "Failed to set owner reference of PacketCapture")
+			return ctrl.Result{}, err
+		}
+		if err := r.Update(ctx, packetCapture); err != nil {
+			log.Error(err, "Failed to update PacketCapture")
+			return ctrl.Result{}, err
+		}
+	}
+	labTemplate := &ltbv1alpha1.LabTemplate{}
+	err = r.Get(ctx, types.NamespacedName{Namespace: labInstance.Namespace, Name: labInstance.Spec.LabTemplateReference}, labTemplate)
+	if errors.IsNotFound(err) {
+		_, err = r.updateStatus(ctx, packetCapture, ltbv1alpha1.PacketCapturePending, fmt.Sprintf("Waiting for LabTemplate %s", labInstance.Spec.LabTemplateReference))
+		return ctrl.Result{RequeueAfter: packetCaptureRetryInterval}, err
+	}
+	if err != nil {
+		log.Error(err, "Failed to get LabTemplate", "LabTemplate", labInstance.Spec.LabTemplateReference)
+		return ctrl.Result{}, err
+	}
+	if err := ValidatePacketCaptureInterface(packetCapture, labTemplate); err != nil {
+		return r.updateStatus(ctx, packetCapture, ltbv1alpha1.PacketCaptureFailed, err.Error())
+	}
+
+	podName := packetCapture.Spec.LabInstanceRef + "-" + packetCapture.Spec.Node
+	pod := &corev1.Pod{}
+	err = r.Get(ctx, types.NamespacedName{Namespace: packetCapture.Namespace, Name: podName}, pod)
+	if errors.IsNotFound(err) {
+		vm := &kubevirtv1.VirtualMachine{}
+		if r.Get(ctx, types.NamespacedName{Namespace: packetCapture.Namespace, Name: podName}, vm) == nil {
+			return r.updateStatus(ctx, packetCapture, ltbv1alpha1.PacketCaptureFailed, "Capturing packets of VMs is not supported")
+		}
+		_, err = r.updateStatus(ctx, packetCapture, ltbv1alpha1.PacketCapturePending, fmt.Sprintf("Waiting for pod %s of the lab node", podName))
+		return ctrl.Result{RequeueAfter: packetCaptureRetryInterval}, err
+	}
+	if err != nil {
+		log.Error(err, "Failed to get Pod", "Pod", podName)
+		return ctrl.Result{}, err
+	}
+
+	if !HasLinkVolume(pod) {
+		return r.updateStatus(ctx, packetCapture, ltbv1alpha1.PacketCaptureFailed, fmt.Sprintf("Pod %s has no %s volume, only lab nodes with interfaces can be captured", podName, labnet.LinkContainerName))
+	}
+	if !hasVolume(pod, labnet.CaptureVolumeName) {
+		return r.updateStatus(ctx, packetCapture, ltbv1alpha1.PacketCaptureFailed, fmt.Sprintf("Pod %s has no %s volume, it has to be recreated to capture packets", podName, labnet.CaptureVolumeName))
+	}
+
+	containerName := labnet.CaptureContainerName(string(packetCapture.UID))
+	packetCapture.Status.PodName = pod.Name
+	packetCapture.Status.ContainerName = containerName
+	packetCapture.Status.File = labnet.CaptureFile(containerName)
+	packetCapture.Status.StreamCommand = labnet.CaptureStreamCommand(pod.Namespace, pod.Name, containerName)
+	packetCapture.Status.DownloadCommand = labnet.CaptureDownloadCommand(pod.Namespace, pod.Name, containerName)
+	if !hasEphemeralContainer(pod, containerName) {
+		pod.Spec.EphemeralContainers = append(pod.Spec.EphemeralContainers, labnet.CaptureContainer(containerName, packetCapture.Spec.Interface, packetCapture.Spec.PacketCount, packetCapture.Spec.Filter))
+		log.Info("Adding capture container", "Pod", pod.Name, "Container", containerName)
+		err = r.SubResource("ephemeralcontainers").Update(ctx, pod)
+		if err != nil {
+			log.Error(err, "Failed to add capture container", "Pod", pod.Name)
+			return r.updateStatus(ctx, packetCapture, ltbv1alpha1.PacketCaptureFailed, "Failed to add capture container: "+err.Error())
+		}
+		return r.updateStatus(ctx, packetCapture, ltbv1alpha1.PacketCapturePending, "Starting capture container")
+	}
+
+	phase, message := GetPacketCapturePhase(pod, containerName)
+	return r.updateStatus(ctx, packetCapture, phase, message)
+}
+
+// GetPacketCapturePhase derives the phase of a capture from the state of its ephemeral container.
+// The container exits with the exit status of tcpdump, so a capture completed, if the container terminated successfully.
+func GetPacketCapturePhase(pod *corev1.Pod, containerName string) (ltbv1alpha1.PacketCapturePhase, string) {
+	for _, containerStatus := range pod.Status.EphemeralContainerStatuses {
+		if containerStatus.Name != containerName {
+			continue
+		}
+		switch {
+		case containerStatus.State.Running != nil:
+			return ltbv1alpha1.PacketCaptureRunning, ""
+		case containerStatus.State.Terminated != nil && containerStatus.State.Terminated.ExitCode == 0:
+			return ltbv1alpha1.PacketCaptureCompleted, fmt.Sprintf("The capture completed, the pcap file can be downloaded from the %s container", labnet.LinkContainerName)
+		case containerStatus.State.Terminated != nil:
+			terminated := containerStatus.State.Terminated
+			message := strings.TrimSpace(terminated.Message)
+			if message == "" {
+				message = terminated.Reason
+			}
+			return ltbv1alpha1.PacketCaptureFailed, fmt.Sprintf("tcpdump exited with %d: %s", terminated.ExitCode, message)
+		case containerStatus.State.Waiting != nil:
+			return ltbv1alpha1.PacketCapturePending, containerStatus.State.Waiting.Reason
+		}
+	}
+	return ltbv1alpha1.PacketCapturePending, "Starting capture container"
+}
+
+// HasLinkVolume reports whether the pod of a lab node has the link volume, which the capture container needs to know when to start and stop.
+func HasLinkVolume(pod *corev1.Pod) bool {
+	return hasVolume(pod, labnet.LinkContainerName)
+}
+
+func hasVolume(pod *corev1.Pod, volumeName string) bool {
+	for _, volume := range pod.Spec.Volumes {
+		if volume.Name == volumeName {
+			return true
+		}
+	}
+	return false
+}
+
+func hasOwnerReference(object client.Object, owner client.Object) bool {
+	for _, ownerReference := range object.GetOwnerReferences() {
+		if ownerReference.UID == owner.GetUID() {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidatePacketCaptureInterface checks that the node of a packet capture is part of the lab template and has the captured interface.
+func ValidatePacketCaptureInterface(packetCapture *ltbv1alpha1.PacketCapture, labTemplate *ltbv1alpha1.LabTemplate) error {
+	for i := range labTemplate.Spec.Nodes {
+		node := &labTemplate.Spec.Nodes[i]
+		if node.Name != packetCapture.Spec.Node {
+			continue
+		}
+		interfaceNames := []string{}
+		for _, nodeInterface := range GetNodeInterfaces(labTemplate, node) {
+			if nodeInterface.Name == packetCapture.Spec.Interface {
+				return nil
+			}
+			interfaceNames = append(interfaceNames, nodeInterface.Name)
+		}
+		return errors.NewBadRequest(fmt.Sprintf("Node %s has no interface %s, its interfaces are %s", node.Name, packetCapture.Spec.Interface, strings.Join(interfaceNames, ", ")))
+	}
+	return errors.NewBadRequest(fmt.Sprintf("Node %s not found in LabTemplate %s", packetCapture.Spec.Node, labTemplate.Name))
+}
+
+func hasEphemeralContainer(pod *corev1.Pod, containerName string) bool {
+	for _, container := range pod.Spec.EphemeralContainers {
+		if container.Name == containerName {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *PacketCaptureReconciler) updateStatus(ctx context.Context, packetCapture *ltbv1alpha1.PacketCapture, phase ltbv1alpha1.PacketCapturePhase, message string) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+	packetCapture.Status.Phase = phase
+	packetCapture.Status.Message = message
+	err := r.Status().Update(ctx, packetCapture)
+	if err != nil {
+		log.Error(err, "Failed to update PacketCapture status")
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil
+}
+
+func (r *PacketCaptureReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&ltbv1alpha1.PacketCapture{}).
+		// The phase of a capture follows the state of its container in the pod of the lab node
+		Watches(&source.Kind{Type: &corev1.Pod{}}, handler.EnqueueRequestsFromMapFunc(r.packetCapturesOfPod)).
+		Complete(r)
+}
+
+func (r *PacketCaptureReconciler) packetCapturesOfPod(object client.Object) []reconcile.Request {
+	packetCaptures := &ltbv1alpha1.PacketCaptureList{}
+	err := r.List(context.Background(), packetCaptures, client.InNamespace(object.GetNamespace()))
+	if err != nil {
+		return nil
+	}
+	requests := []reconcile.Request{}
+	for _, packetCapture := range packetCaptures.Items {
+		if packetCapture.Spec.LabInstanceRef+"-"+packetCapture.Spec.Node == object.GetName() {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&packetCapture)})
+		}
+	}
+	return requests
+}
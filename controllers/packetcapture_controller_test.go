@@ -0,0 +1,165 @@
+package controllers
+
+import (
+	"context"
+
+	ltbv1alpha1 "github.com/Lab-Topology-Builder/LTB-K8s-Backend/api/v1alpha1"
+	"github.com/Lab-Topology-Builder/LTB-K8s-Backend/labnet"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("PacketCapture Controller", func() {
+	var (
+		ctx context.Context
+		req ctrl.Request
+		r   *PacketCaptureReconciler
+	)
+
+	getPacketCapture := func(name string) *ltbv1alpha1.PacketCapture {
+		packetCapture := &ltbv1alpha1.PacketCapture{}
+		err := r.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, packetCapture)
+		Expect(err).To(BeNil())
+		return packetCapture
+	}
+
+	Describe("Reconcile", func() {
+		BeforeEach(func() {
+			ctx = context.Background()
+			req = ctrl.Request{NamespacedName: types.NamespacedName{Namespace: namespace, Name: testPacketCapture.Name}}
+			fakeClient = fake.NewClientBuilder().WithObjects(testPacketCapture.DeepCopy(), testVMPacketCapture.DeepCopy(), testCapturePod.DeepCopy(), testVM.DeepCopy(), testLabInstance.DeepCopy(), testLabTemplateWithRenderedNodeSpec.DeepCopy()).Build()
+			r = &PacketCaptureReconciler{Client: fakeClient, Scheme: scheme.Scheme}
+		})
+		Context("PacketCapture doesn't exist", func() {
+			It("should ignore the request", func() {
+				req.NamespacedName.Name = "test"
+				result, err := r.Reconcile(ctx, req)
+				Expect(result).To(Equal(ctrl.Result{}))
+				Expect(err).To(BeNil())
+			})
+		})
+		Context("LabInstance doesn't exist yet", func() {
+			It("should wait for the lab instance", func() {
+				r.Client = fake.NewClientBuilder().WithObjects(testPacketCapture.DeepCopy()).Build()
+				result, err := r.Reconcile(ctx, req)
+				Expect(result).To(Equal(ctrl.Result{RequeueAfter: packetCaptureRetryInterval}))
+				Expect(err).To(BeNil())
+				packetCapture := getPacketCapture(testPacketCapture.Name)
+				Expect(packetCapture.Status.Phase).To(Equal(ltbv1alpha1.PacketCapturePending))
+				Expect(packetCapture.Status.Message).To(ContainSubstring("LabInstance"))
+			})
+		})
+		Context("Interface isn't part of the lab node", func() {
+			It("should fail", func() {
+				packetCapture := testPacketCapture.DeepCopy()
+				packetCapture.Spec.Interface = "eth9"
+				r.Client = fake.NewClientBuilder().WithObjects(packetCapture, testLabInstance.DeepCopy(), testLabTemplateWithRenderedNodeSpec.DeepCopy(), testCapturePod.DeepCopy()).Build()
+				result, err := r.Reconcile(ctx, req)
+				Expect(result).To(Equal(ctrl.Result{}))
+				Expect(err).To(BeNil())
+				packetCapture = getPacketCapture(testPacketCapture.Name)
+				Expect(packetCapture.Status.Phase).To(Equal(ltbv1alpha1.PacketCaptureFailed))
+				Expect(packetCapture.Status.Message).To(ContainSubstring("has no interface eth9"))
+			})
+		})
+		Context("Pod of the lab node doesn't exist yet", func() {
+			It("should wait for the pod", func() {
+				r.Client = fake.NewClientBuilder().WithObjects(testPacketCapture.DeepCopy(), testLabInstance.DeepCopy(), testLabTemplateWithRenderedNodeSpec.DeepCopy()).Build()
+				result, err := r.Reconcile(ctx, req)
+				Expect(result).To(Equal(ctrl.Result{RequeueAfter: packetCaptureRetryInterval}))
+				Expect(err).To(BeNil())
+				Expect(getPacketCapture(testPacketCapture.Name).Status.Phase).To(Equal(ltbv1alpha1.PacketCapturePending))
+			})
+		})
+		Context("Lab node is a VM", func() {
+			It("should fail", func() {
+				req.NamespacedName.Name = testVMPacketCapture.Name
+				result, err := r.Reconcile(ctx, req)
+				Expect(result).To(Equal(ctrl.Result{}))
+				Expect(err).To(BeNil())
+				packetCapture := getPacketCapture(testVMPacketCapture.Name)
+				Expect(packetCapture.Status.Phase).To(Equal(ltbv1alpha1.PacketCaptureFailed))
+				Expect(packetCapture.Status.Message).To(ContainSubstring("VMs"))
+			})
+		})
+		Context("Pod has no link volume", func() {
+			It("should fail", func() {
+				pod := testCapturePod.DeepCopy()
+				pod.Spec.Volumes = nil
+				r.Client = fake.NewClientBuilder().WithObjects(testPacketCapture.DeepCopy(), testLabInstance.DeepCopy(), testLabTemplateWithRenderedNodeSpec.DeepCopy(), pod).Build()
+				result, err := r.Reconcile(ctx, req)
+				Expect(result).To(Equal(ctrl.Result{}))
+				Expect(err).To(BeNil())
+				Expect(getPacketCapture(testPacketCapture.Name).Status.Phase).To(Equal(ltbv1alpha1.PacketCaptureFailed))
+			})
+		})
+		Context("Pod has a link volume", func() {
+			It("should add the capture container and show how to access the capture", func() {
+				result, err := r.Reconcile(ctx, req)
+				Expect(result).To(Equal(ctrl.Result{}))
+				Expect(err).To(BeNil())
+				pod := &corev1.Pod{}
+				err = r.Get(ctx, types.NamespacedName{Namespace: namespace, Name: testCapturePod.Name}, pod)
+				Expect(err).To(BeNil())
+				Expect(pod.Spec.EphemeralContainers).To(HaveLen(1))
+				Expect(pod.Spec.EphemeralContainers[0].Name).To(Equal("capture-01234567"))
+				packetCapture := getPacketCapture(testPacketCapture.Name)
+				Expect(packetCapture.Status.Phase).To(Equal(ltbv1alpha1.PacketCapturePending))
+				Expect(packetCapture.Status.PodName).To(Equal(testCapturePod.Name))
+				Expect(packetCapture.Status.ContainerName).To(Equal("capture-01234567"))
+				Expect(packetCapture.Status.File).To(Equal(labnet.CaptureFile("capture-01234567")))
+				Expect(packetCapture.Status.StreamCommand).To(ContainSubstring("-c capture-01234567"))
+				Expect(packetCapture.Status.DownloadCommand).To(ContainSubstring("-c " + labnet.LinkContainerName))
+				Expect(packetCapture.OwnerReferences).To(HaveLen(1))
+				Expect(packetCapture.OwnerReferences[0].Name).To(Equal(testLabInstance.Name))
+			})
+			It("should not add the capture container twice", func() {
+				_, err := r.Reconcile(ctx, req)
+				Expect(err).To(BeNil())
+				_, err = r.Reconcile(ctx, req)
+				Expect(err).To(BeNil())
+				pod := &corev1.Pod{}
+				err = r.Get(ctx, types.NamespacedName{Namespace: namespace, Name: testCapturePod.Name}, pod)
+				Expect(err).To(BeNil())
+				Expect(pod.Spec.EphemeralContainers).To(HaveLen(1))
+			})
+		})
+	})
+
+	Describe("GetPacketCapturePhase", func() {
+		var pod *corev1.Pod
+		BeforeEach(func() {
+			pod = testCapturePod.DeepCopy()
+		})
+		It("should be pending without container status", func() {
+			phase, _ := GetPacketCapturePhase(pod, "capture-01234567")
+			Expect(phase).To(Equal(ltbv1alpha1.PacketCapturePending))
+		})
+		It("should be running, while the container runs", func() {
+			pod.Status.EphemeralContainerStatuses = []corev1.ContainerStatus{{Name: "capture-01234567", State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}}}
+			phase, _ := GetPacketCapturePhase(pod, "capture-01234567")
+			Expect(phase).To(Equal(ltbv1alpha1.PacketCaptureRunning))
+		})
+		It("should fail, if tcpdump failed", func() {
+			pod.Status.EphemeralContainerStatuses = []corev1.ContainerStatus{{Name: "capture-01234567", State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 1, Reason: "Error", Message: "tcpdump: eth9: No such device exists\n"}}}}
+			phase, message := GetPacketCapturePhase(pod, "capture-01234567")
+			Expect(phase).To(Equal(ltbv1alpha1.PacketCaptureFailed))
+			Expect(message).To(Equal("tcpdump exited with 1: tcpdump: eth9: No such device exists"))
+		})
+		It("should complete, if tcpdump stopped on its own", func() {
+			pod.Status.EphemeralContainerStatuses = []corev1.ContainerStatus{{Name: "capture-01234567", State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 0, Reason: "Completed"}}}}
+			phase, _ := GetPacketCapturePhase(pod, "capture-01234567")
+			Expect(phase).To(Equal(ltbv1alpha1.PacketCaptureCompleted))
+		})
+		It("should ignore the containers of other captures", func() {
+			pod.Status.EphemeralContainerStatuses = []corev1.ContainerStatus{{Name: "capture-other", State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}}}
+			phase, _ := GetPacketCapturePhase(pod, "capture-01234567")
+			Expect(phase).To(Equal(ltbv1alpha1.PacketCapturePending))
+		})
+	})
+})
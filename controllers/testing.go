@@ -2,6 +2,7 @@ package controllers
 
 import (
 	ltbv1alpha1 "github.com/Lab-Topology-Builder/LTB-K8s-Backend/api/v1alpha1"
+	"github.com/Lab-Topology-Builder/LTB-K8s-Backend/labnet"
 	network "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
 	corev1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
@@ -16,7 +17,7 @@ const namespace = "test-namespace"
 
 var (
 	testLabInstance                                                                                                                                                           *ltbv1alpha1.LabInstance
-	testLabTemplateWithoutRenderedNodeSpec, testLabTemplateWithRenderedNodeSpec, testLabTemplateWithoutRenderedNodeSpec2                                                      *ltbv1alpha1.LabTemplate
+	testLabTemplateWithoutRenderedNodeSpec, testLabTemplateWithRenderedNodeSpec, testLabTemplateWithoutRenderedNodeSpec2, testLabTemplateWithInvalidLink                      *ltbv1alpha1.LabTemplate
 	testNodeVMType, testPodNodeType, failingVMNodeType, failingPodNodeType, invalidKindNodeType, invalidNodeSpecVMNodeType, invalidNodeSpecPodNodeType, renderInvalidNodeType *ltbv1alpha1.NodeType
 	testPodNode, testVMNode, nodeWithUndefinedNodeType, vmNodeYAMLProblem, podNodeYAMLProblem, podRenderSpecProblem                                                           *ltbv1alpha1.LabInstanceNodes
 	fakeClient                                                                                                                                                                client.Client
@@ -27,7 +28,10 @@ var (
 	testRole                                                                                                                                                                  *rbacv1.Role
 	testRoleBinding                                                                                                                                                           *rbacv1.RoleBinding
 	testServiceAccount                                                                                                                                                        *corev1.ServiceAccount
-	testPodNetworkAttachmentDefinition, testVMNetworkAttachmentDefinition                                                                                                     *network.NetworkAttachmentDefinition
+	testPodNetworkAttachmentDefinition, testPodUnlinkedNetworkAttachmentDefinition, testVMNetworkAttachmentDefinition                                                         *network.NetworkAttachmentDefinition
+	testLinkConfigMap                                                                                                                                                         *corev1.ConfigMap
+	testPacketCapture, testVMPacketCapture                                                                                                                                    *ltbv1alpha1.PacketCapture
+	testCapturePod                                                                                                                                                            *corev1.Pod
 )
 
 func initialize() {
@@ -265,6 +269,16 @@ template:
 			Image:   "ubuntu",
 			Version: "20.04",
 		},
+		Interfaces: []ltbv1alpha1.NodeInterface{
+			{
+				Name: "eth2",
+			},
+			{
+				Name: "eth1",
+				IPv4: "10.0.0.1/24",
+				IPv6: "2001:db8::1/64",
+			},
+		},
 		RenderedNodeSpec: `
 containers:
   - name: testnode
@@ -404,6 +418,41 @@ template:
 				*testVMNode,
 				*testPodNode,
 			},
+			Links: []ltbv1alpha1.Link{
+				{
+					Endpoints: []ltbv1alpha1.LinkEndpoint{
+						{Node: testVMNode.Name, Interface: "eth1"},
+						{Node: testPodNode.Name, Interface: "eth1"},
+					},
+					Impairment: &ltbv1alpha1.Impairment{Delay: "10ms", Loss: "1"},
+				},
+			},
+		},
+		Status: ltbv1alpha1.LabTemplateStatus{
+			Nodes: []ltbv1alpha1.RenderedNode{
+				renderedNode(testVMNode, "vm"),
+				renderedNode(testPodNode, "pod"),
+			},
+		},
+	}
+	testLabTemplateWithInvalidLink = &ltbv1alpha1.LabTemplate{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-labtemplate",
+			Namespace: namespace,
+		},
+		Spec: ltbv1alpha1.LabTemplateSpec{
+			Nodes: []ltbv1alpha1.LabInstanceNodes{
+				*testVMNode,
+				*testPodNode,
+			},
+			Links: []ltbv1alpha1.Link{
+				{
+					Endpoints: []ltbv1alpha1.LinkEndpoint{
+						{Node: testVMNode.Name, Interface: "eth1"},
+						{Node: nodeWithUndefinedNodeType.Name, Interface: "eth1"},
+					},
+				},
+			},
 		},
 	}
 
@@ -414,6 +463,7 @@ template:
 		},
 		Spec: ltbv1alpha1.LabInstanceSpec{
 			LabTemplateReference: "test-labtemplate",
+			DNSAddress:           "example.com",
 		},
 		Status: ltbv1alpha1.LabInstanceStatus{
 			Status: "Running",
@@ -428,16 +478,36 @@ template:
 			Name:      testLabInstance.Name + "-" + testPodNode.Name,
 			Namespace: namespace,
 			Annotations: map[string]string{
-				"k8s.v1.cni.cncf.io/networks": testLabInstance.Name + "-pod",
+				"k8s.v1.cni.cncf.io/networks":             `[{"name":"` + testLabInstance.Name + "-" + testPodNode.Name + `-eth2","interface":"eth2"},{"name":"` + testLabInstance.Name + "-" + testPodNode.Name + `-eth1","interface":"eth1"}]`,
+				"kubectl.kubernetes.io/default-container": "testnode",
 			},
 			Labels: map[string]string{
 				"app": testLabInstance.Name + "-" + testPodNode.Name + "-remote-access",
 			},
 		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{
+					Name:    "testnode",
+					Image:   "ubuntu:22.04",
+					Command: []string{"/bin/bash", "-c", "apt update && apt install -y openssh-server && service ssh start && sleep 365d"},
+					Ports: []corev1.ContainerPort{
+						{
+							Name:          "testsshport",
+							ContainerPort: 22,
+							Protocol:      "tcp",
+						},
+					},
+				},
+			},
+		},
 		Status: corev1.PodStatus{
 			Phase: corev1.PodRunning,
 		},
 	}
+	linkContainer, linkVolumes := labnet.LinkSidecar(testLabInstance.Name+"-links", testPodNode.Name)
+	testPod.Spec.Containers = append(testPod.Spec.Containers, linkContainer)
+	testPod.Spec.Volumes = linkVolumes
 
 	testPodUndefinedNode = &corev1.Pod{
 		ObjectMeta: metav1.ObjectMeta{
@@ -536,39 +606,62 @@ template:
 			},
 			Type: corev1.ServiceTypeLoadBalancer,
 		},
+		Status: corev1.ServiceStatus{
+			LoadBalancer: corev1.LoadBalancerStatus{
+				Ingress: []corev1.LoadBalancerIngress{{IP: "192.0.2.10"}},
+			},
+		},
 	}
 
 	// ------------------ 4.4 Test NetworkAttachmentDefinition -----------------
 
 	testPodNetworkAttachmentDefinition = &network.NetworkAttachmentDefinition{}
-	testPodNetworkAttachmentDefinition.Name = testLabInstance.Name + "-pod"
+	testPodNetworkAttachmentDefinition.Name = testLabInstance.Name + "-" + testPodNode.Name + "-eth1"
 	testPodNetworkAttachmentDefinition.Namespace = testLabInstance.Namespace
 	testPodNetworkAttachmentDefinition.Spec.Config = `{
-				"cniVersion": "0.3.1",
-				"name": "mynet",
-				"type": "bridge",
-				"bridge": "mynet0",
-				"ipam": {
-					"type": "host-local",
-					"ranges": [
-						[ {
-							"subnet": "10.10.0.0/24",
-							"rangeStart": "10.10.0.10",
-							"rangeEnd": "10.10.0.250"
-						} ]
-					]
-				}
-			}`
+		"cniVersion": "0.3.1",
+		"name": "ltb6457a0eb1b30",
+		"type": "bridge",
+		"bridge": "ltb4f84fa63049a",
+		"ipam": {
+			"type": "static",
+			"addresses": [
+				{"address": "10.0.0.1/24"},
+				{"address": "2001:db8::1/64"}
+			]
+		}
+	}`
+	testPodUnlinkedNetworkAttachmentDefinition = &network.NetworkAttachmentDefinition{}
+	testPodUnlinkedNetworkAttachmentDefinition.Name = testLabInstance.Name + "-" + testPodNode.Name + "-eth2"
+	testPodUnlinkedNetworkAttachmentDefinition.Namespace = testLabInstance.Namespace
+	testPodUnlinkedNetworkAttachmentDefinition.Spec.Config = `{
+		"cniVersion": "0.3.1",
+		"name": "ltb6457a0eb1b30",
+		"type": "bridge",
+		"bridge": "ltbef56459f8fbe",
+		"ipam": {}
+	}`
 	testVMNetworkAttachmentDefinition = &network.NetworkAttachmentDefinition{}
-	testVMNetworkAttachmentDefinition.Name = testLabInstance.Name + "-vm"
+	testVMNetworkAttachmentDefinition.Name = testLabInstance.Name + "-" + testVMNode.Name + "-eth1"
 	testVMNetworkAttachmentDefinition.Namespace = testLabInstance.Namespace
 	testVMNetworkAttachmentDefinition.Spec.Config = `{
-					"cniVersion": "0.3.1",
-					"name": "mynet",
-					"type": "bridge",
-					"bridge": "mynet0",
-					"ipam": {}
-				}`
+		"cniVersion": "0.3.1",
+		"name": "ltb6457a0eb1b30",
+		"type": "bridge",
+		"bridge": "ltb4f84fa63049a",
+		"ipam": {}
+	}`
+
+	testLinkConfigMap = &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      testLabInstance.Name + "-links",
+			Namespace: namespace,
+		},
+		Data: map[string]string{
+			testVMNode.Name:  "eth1 up delay 10ms loss 1%\n",
+			testPodNode.Name: "eth1 up delay 10ms loss 1%\n",
+		},
+	}
 
 	// ---------------------------- 4.3 Test Ttyd ------------------------------
 	// =========================== 4.3.1 Test Ttyd Pod =========================
@@ -677,4 +770,70 @@ template:
 			APIGroup: "rbac.authorization.k8s.io",
 		},
 	}
+
+	// ___________________________ 5. Test PacketCaptures ______________________
+
+	testPacketCapture = &ltbv1alpha1.PacketCapture{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-capture",
+			Namespace: namespace,
+			UID:       "0123456789abcdef",
+		},
+		Spec: ltbv1alpha1.PacketCaptureSpec{
+			LabInstanceRef: testLabInstance.Name,
+			Node:           testPodNode.Name,
+			Interface:      "eth1",
+			Filter:         "icmp",
+		},
+	}
+
+	testVMPacketCapture = &ltbv1alpha1.PacketCapture{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-vm-capture",
+			Namespace: namespace,
+		},
+		Spec: ltbv1alpha1.PacketCaptureSpec{
+			LabInstanceRef: testLabInstance.Name,
+			Node:           testVMNode.Name,
+			Interface:      "eth1",
+		},
+	}
+
+	testCapturePod = &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      testLabInstance.Name + "-" + testPodNode.Name,
+			Namespace: namespace,
+		},
+		Spec: corev1.PodSpec{
+			Volumes: []corev1.Volume{
+				{
+					Name: "link-control",
+					VolumeSource: corev1.VolumeSource{
+						ConfigMap: &corev1.ConfigMapVolumeSource{
+							LocalObjectReference: corev1.LocalObjectReference{Name: testLabInstance.Name + "-links"},
+						},
+					},
+				},
+				{
+					Name:         "ltb-captures",
+					VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+				},
+			},
+		},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodRunning,
+		},
+	}
+}
+
+// renderedNode returns the successfully rendered status of a test node.
+func renderedNode(node *ltbv1alpha1.LabInstanceNodes, kind string) ltbv1alpha1.RenderedNode {
+	return ltbv1alpha1.RenderedNode{
+		Name:             node.Name,
+		Kind:             kind,
+		RenderedNodeSpec: node.RenderedNodeSpec,
+		Conditions: []metav1.Condition{
+			{Type: ltbv1alpha1.RenderedCondition, Status: metav1.ConditionTrue, Reason: RenderedReason},
+		},
+	}
 }
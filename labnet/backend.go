@@ -0,0 +1,125 @@
+package labnet
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	Bridge = "bridge"
+	VXLAN  = "vxlan"
+	OVNK8s = "ovn-k8s"
+	OVS    = "ovs"
+)
+
+// Backend generates the CNI configuration of the NetworkAttachmentDefinitions, which attach the interfaces of lab nodes to the layer 2 segments of their links.
+type Backend interface {
+	// NetworkConfig returns the CNI configuration to attach an interface to its segment.
+	NetworkConfig(attachment *Attachment) (string, error)
+	// NeedsSegmentID reports whether the segments share the same infrastructure and need an ID, which is unique in the cluster, to be isolated from each other.
+	NeedsSegmentID() bool
+}
+
+// Attachment describes how an interface of a lab node is attached to a segment.
+type Attachment struct {
+	// Namespace of the NetworkAttachmentDefinition.
+	Namespace string
+	// Name of the NetworkAttachmentDefinition.
+	Name string
+	// Name of the network shared by all attachments of a lab instance.
+	NetworkName string
+	// Name of the layer 2 segment, which is shared by both interfaces of a link.
+	// It is at most 15 characters long, so it can be used as name of a linux interface.
+	Segment string
+	// ID of the segment, if the backend needs one.
+	SegmentID int32
+	// Static addresses of the interface in CIDR notation.
+	Addresses []string
+	// Subnet from which the interface gets an address, if it has no static addresses.
+	Subnet string
+}
+
+// Config contains the settings of the backends, which depend on the infrastructure of the cluster.
+type Config struct {
+	// Name of the linux bridge, which is connected to the other Kubernetes nodes with a VXLAN interface.
+	VXLANBridge string
+	// Name of the Open vSwitch bridge, which is connected to the other Kubernetes nodes.
+	OVSBridge string
+}
+
+// DefaultConfig returns the settings, which are used if a setting is not configured.
+func DefaultConfig() Config {
+	return Config{
+		VXLANBridge: "ltb-vxlan0",
+		OVSBridge:   "br-ltb",
+	}
+}
+
+// NewBackend returns the backend with the given name, an empty name selects the bridge backend.
+func NewBackend(name string, config Config) (Backend, error) {
+	defaultConfig := DefaultConfig()
+	if config.VXLANBridge == "" {
+		config.VXLANBridge = defaultConfig.VXLANBridge
+	}
+	if config.OVSBridge == "" {
+		config.OVSBridge = defaultConfig.OVSBridge
+	}
+	switch name {
+	case "", Bridge:
+		return &bridgeBackend{}, nil
+	case VXLAN:
+		return &vxlanBackend{bridge: config.VXLANBridge}, nil
+	case OVNK8s:
+		return &ovnK8sBackend{}, nil
+	case OVS:
+		return &ovsBackend{bridge: config.OVSBridge}, nil
+	default:
+		return nil, fmt.Errorf("NewBackend: Unknown network backend %s", name)
+	}
+}
+
+type ipamConfig struct {
+	Type      string        `json:"type,omitempty"`
+	Addresses []ipamAddress `json:"addresses,omitempty"`
+	Ranges    [][]ipamRange `json:"ranges,omitempty"`
+	Range     string        `json:"range,omitempty"`
+}
+
+type ipamAddress struct {
+	Address string `json:"address"`
+}
+
+type ipamRange struct {
+	Subnet string `json:"subnet"`
+}
+
+// newIPAMConfig returns static IPAM for static addresses and the given dynamic IPAM type for the subnet otherwise.
+// host-local only guarantees unique addresses on the same host, whereabouts is needed for segments spanning multiple hosts.
+func newIPAMConfig(attachment *Attachment, dynamicType string) ipamConfig {
+	config := ipamConfig{}
+	if len(attachment.Addresses) > 0 {
+		config.Type = "static"
+		for _, address := range attachment.Addresses {
+			config.Addresses = append(config.Addresses, ipamAddress{Address: address})
+		}
+		return config
+	}
+	if attachment.Subnet == "" {
+		return config
+	}
+	config.Type = dynamicType
+	if dynamicType == "host-local" {
+		config.Ranges = [][]ipamRange{{{Subnet: attachment.Subnet}}}
+	} else {
+		config.Range = attachment.Subnet
+	}
+	return config
+}
+
+func marshalConfig(config interface{}) (string, error) {
+	configJSON, err := json.Marshal(config)
+	if err != nil {
+		return "", fmt.Errorf("marshalConfig: Failed to marshal network config\nErr:%s", err)
+	}
+	return string(configJSON), nil
+}
@@ -0,0 +1,26 @@
+package labnet_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/Lab-Topology-Builder/LTB-K8s-Backend/labnet"
+)
+
+var _ = Describe("NewBackend", func() {
+	It("should return the bridge backend for an empty name", func() {
+		backend, err := labnet.NewBackend("", labnet.Config{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(backend.NeedsSegmentID()).To(BeFalse())
+	})
+	It("should return every known backend", func() {
+		for _, name := range []string{labnet.Bridge, labnet.VXLAN, labnet.OVNK8s, labnet.OVS} {
+			_, err := labnet.NewBackend(name, labnet.Config{})
+			Expect(err).NotTo(HaveOccurred())
+		}
+	})
+	It("should fail for an unknown backend", func() {
+		_, err := labnet.NewBackend("unknown", labnet.Config{})
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,29 @@
+package labnet
+
+// bridgeBackend creates a linux bridge per segment, therefore all lab nodes have to run on the same host.
+type bridgeBackend struct{}
+
+type bridgeNetworkConfig struct {
+	CNIVersion string     `json:"cniVersion"`
+	Name       string     `json:"name"`
+	Type       string     `json:"type"`
+	Bridge     string     `json:"bridge"`
+	VLAN       int32      `json:"vlan,omitempty"`
+	IPAM       ipamConfig `json:"ipam"`
+}
+
+func (b *bridgeBackend) NetworkConfig(attachment *Attachment) (string, error) {
+	// Don't change mode to "passthru" as it will takeover the kubernetes node interface and cause a network outage
+	// All attachments of a lab instance share the network name, so host-local IPAM doesn't hand out an address twice on the same host
+	return marshalConfig(bridgeNetworkConfig{
+		CNIVersion: "0.3.1",
+		Name:       attachment.NetworkName,
+		Type:       "bridge",
+		Bridge:     attachment.Segment,
+		IPAM:       newIPAMConfig(attachment, "host-local"),
+	})
+}
+
+func (b *bridgeBackend) NeedsSegmentID() bool {
+	return false
+}
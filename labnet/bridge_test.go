@@ -0,0 +1,42 @@
+package labnet_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/Lab-Topology-Builder/LTB-K8s-Backend/labnet"
+)
+
+var _ = Describe("Bridge backend", func() {
+	var backend labnet.Backend
+	var attachment *labnet.Attachment
+
+	BeforeEach(func() {
+		backend, _ = labnet.NewBackend(labnet.Bridge, labnet.Config{})
+		attachment = &labnet.Attachment{Namespace: "test-namespace", Name: "test-lab-node-eth1", NetworkName: "ltbnetwork", Segment: "ltbsegment"}
+	})
+
+	It("should not need segment IDs", func() {
+		Expect(backend.NeedsSegmentID()).To(BeFalse())
+	})
+	It("should attach the interface to the bridge of the segment", func() {
+		config, err := backend.NetworkConfig(attachment)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(config).To(MatchJSON(`{"cniVersion": "0.3.1", "name": "ltbnetwork", "type": "bridge", "bridge": "ltbsegment", "ipam": {}}`))
+	})
+	It("should configure static addresses", func() {
+		attachment.Addresses = []string{"10.0.0.1/24", "2001:db8::1/64"}
+		attachment.Subnet = "10.10.0.0/24"
+		config, err := backend.NetworkConfig(attachment)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(config).To(MatchJSON(`{"cniVersion": "0.3.1", "name": "ltbnetwork", "type": "bridge", "bridge": "ltbsegment",
+			"ipam": {"type": "static", "addresses": [{"address": "10.0.0.1/24"}, {"address": "2001:db8::1/64"}]}}`))
+	})
+	It("should configure host-local addresses from the subnet", func() {
+		attachment.Subnet = "10.10.0.0/24"
+		config, err := backend.NetworkConfig(attachment)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(config).To(MatchJSON(`{"cniVersion": "0.3.1", "name": "ltbnetwork", "type": "bridge", "bridge": "ltbsegment",
+			"ipam": {"type": "host-local", "ranges": [[{"subnet": "10.10.0.0/24"}]]}}`))
+	})
+})
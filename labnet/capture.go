@@ -0,0 +1,86 @@
+package labnet
+
+import (
+	"fmt"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// Name of the volume, which is shared by the capture containers and the link sidecar of a pod and holds the pcap files.
+	CaptureVolumeName = "ltb-captures"
+	captureMountPath  = "/captures"
+	// Name of the file in the link volume, which lists the capture containers, that are allowed to run.
+	captureListFile = "captures"
+)
+
+// captureScript waits until its capture is listed in the captures file, then runs tcpdump until tcpdump stops on its own or the capture is no longer listed.
+// It exits with the exit status of tcpdump, so the state of the container shows whether the capture completed or failed.
+// The arguments are passed as positional parameters, so they are never interpreted by the shell.
+const captureScript = `name="$1"; iface="$2"; count="$3"; filter="$4"; file="$5"
+listed() { grep -qx "$name" ` + linkMountPath + `/` + captureListFile + ` 2>/dev/null; }
+until listed; do sleep 2; done
+set -- -i "$iface" -U -w "$file"
+[ "$count" -gt 0 ] && set -- "$@" -c "$count"
+[ -n "$filter" ] && set -- "$@" "$filter"
+tcpdump "$@" &
+pid=$!
+while listed && kill -0 "$pid" 2>/dev/null; do sleep 2; done
+if kill -0 "$pid" 2>/dev/null; then
+  kill "$pid"
+  wait "$pid"
+  exit 0
+fi
+wait "$pid"
+`
+
+// CaptureFile returns the path of the pcap file of a capture container, in the capture container and in the link sidecar.
+func CaptureFile(containerName string) string {
+	return captureMountPath + "/" + containerName + ".pcap"
+}
+
+// CaptureContainerName returns the name of the ephemeral container of a capture.
+// Ephemeral containers can't be removed from a pod, so the name is derived from the UID to be unique for every capture.
+func CaptureContainerName(captureUID string) string {
+	if len(captureUID) > 8 {
+		captureUID = captureUID[:8]
+	}
+	return "capture-" + captureUID
+}
+
+// CaptureListKey returns the key of the link ConfigMap, which lists the capture containers of a lab node, that are allowed to run.
+func CaptureListKey(nodeName string) string {
+	return nodeName + ".captures"
+}
+
+// CaptureContainer returns the ephemeral container, which captures the packets of an interface with tcpdump.
+// It mounts the link volume of the pod to know when to start and stop, and writes the pcap file into the capture volume.
+// The errors of tcpdump become the termination message of the container.
+func CaptureContainer(name string, interfaceName string, packetCount int32, filter string) corev1.EphemeralContainer {
+	return corev1.EphemeralContainer{
+		EphemeralContainerCommon: corev1.EphemeralContainerCommon{
+			Name:                     name,
+			Image:                    linkImage,
+			Command:                  []string{"/bin/sh", "-c", captureScript, "capture", name, interfaceName, strconv.Itoa(int(packetCount)), filter, CaptureFile(name)},
+			TerminationMessagePolicy: corev1.TerminationMessageFallbackToLogsOnError,
+			SecurityContext: &corev1.SecurityContext{
+				Capabilities: &corev1.Capabilities{Add: []corev1.Capability{"NET_ADMIN", "NET_RAW"}},
+			},
+			VolumeMounts: []corev1.VolumeMount{
+				{Name: LinkContainerName, MountPath: linkMountPath, ReadOnly: true},
+				{Name: CaptureVolumeName, MountPath: captureMountPath},
+			},
+		},
+	}
+}
+
+// CaptureStreamCommand returns the command to stream a running capture into Wireshark.
+func CaptureStreamCommand(namespace string, podName string, containerName string) string {
+	return fmt.Sprintf("kubectl exec -n %s %s -c %s -- tail -c +1 -f %s | wireshark -k -i -", namespace, podName, containerName, CaptureFile(containerName))
+}
+
+// CaptureDownloadCommand returns the command to download the pcap file of a capture from the link sidecar, which works after the capture container terminated.
+func CaptureDownloadCommand(namespace string, podName string, containerName string) string {
+	return fmt.Sprintf("kubectl cp -n %s -c %s %s:%s %s.pcap", namespace, LinkContainerName, podName, CaptureFile(containerName), containerName)
+}
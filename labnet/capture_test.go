@@ -0,0 +1,37 @@
+package labnet_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/Lab-Topology-Builder/LTB-K8s-Backend/labnet"
+)
+
+var _ = Describe("CaptureContainerName", func() {
+	It("should be derived from the UID", func() {
+		Expect(labnet.CaptureContainerName("0123456789abcdef")).To(Equal("capture-01234567"))
+		Expect(labnet.CaptureContainerName("0123")).To(Equal("capture-0123"))
+	})
+})
+
+var _ = Describe("CaptureContainer", func() {
+	It("should pass the capture parameters as arguments", func() {
+		container := labnet.CaptureContainer("capture-01234567", "eth1", 100, "icmp or arp")
+		Expect(container.Name).To(Equal("capture-01234567"))
+		Expect(container.Command[len(container.Command)-5:]).To(Equal([]string{"capture-01234567", "eth1", "100", "icmp or arp", "/captures/capture-01234567.pcap"}))
+		Expect(container.SecurityContext.Capabilities.Add).To(ContainElement(BeEquivalentTo("NET_RAW")))
+		Expect(container.VolumeMounts[0].Name).To(Equal(labnet.LinkContainerName))
+		Expect(container.VolumeMounts[1].Name).To(Equal(labnet.CaptureVolumeName))
+	})
+	It("should be listed in the link volume", func() {
+		_, volumes := labnet.LinkSidecar("test-labinstance-links", "test-node")
+		Expect(volumes[0].ConfigMap.Items[1].Key).To(Equal(labnet.CaptureListKey("test-node")))
+	})
+	It("should write the pcap file into the capture volume of the link sidecar", func() {
+		container, volumes := labnet.LinkSidecar("test-labinstance-links", "test-node")
+		Expect(volumes[1].Name).To(Equal(labnet.CaptureVolumeName))
+		Expect(volumes[1].EmptyDir).NotTo(BeNil())
+		Expect(container.VolumeMounts[1].Name).To(Equal(labnet.CaptureVolumeName))
+		Expect(labnet.CaptureDownloadCommand("test", "test-pod", "capture-01234567")).To(Equal("kubectl cp -n test -c link-control test-pod:/captures/capture-01234567.pcap capture-01234567.pcap"))
+	})
+})
@@ -0,0 +1,57 @@
+package labnet
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	ltbv1alpha1 "github.com/Lab-Topology-Builder/LTB-K8s-Backend/api/v1alpha1"
+)
+
+var (
+	durationPattern   = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?(us|ms|s)$`)
+	percentagePattern = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?$`)
+	ratePattern       = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?(bit|kbit|mbit|gbit|tbit|bps|kbps|mbps|gbps|tbps)$`)
+)
+
+// NetemArguments converts an impairment into the arguments of tc netem, an empty string means no impairment.
+// The values are validated strictly, because they are passed to a shell.
+func NetemArguments(impairment *ltbv1alpha1.Impairment) (string, error) {
+	if impairment == nil {
+		return "", nil
+	}
+	arguments := []string{}
+	if impairment.Jitter != "" && impairment.Delay == "" {
+		return "", fmt.Errorf("NetemArguments: Jitter %s requires a delay", impairment.Jitter)
+	}
+	if impairment.Delay != "" {
+		if !durationPattern.MatchString(impairment.Delay) {
+			return "", fmt.Errorf("NetemArguments: Invalid delay %s", impairment.Delay)
+		}
+		arguments = append(arguments, "delay", impairment.Delay)
+		if impairment.Jitter != "" {
+			if !durationPattern.MatchString(impairment.Jitter) {
+				return "", fmt.Errorf("NetemArguments: Invalid jitter %s", impairment.Jitter)
+			}
+			arguments = append(arguments, impairment.Jitter)
+		}
+	}
+	for _, percentage := range []struct{ name, value string }{{"loss", impairment.Loss}, {"corrupt", impairment.Corruption}} {
+		if percentage.value == "" {
+			continue
+		}
+		value, err := strconv.ParseFloat(percentage.value, 64)
+		if !percentagePattern.MatchString(percentage.value) || err != nil || value > 100 {
+			return "", fmt.Errorf("NetemArguments: Invalid %s percentage %s", percentage.name, percentage.value)
+		}
+		arguments = append(arguments, percentage.name, percentage.value+"%")
+	}
+	if impairment.Rate != "" {
+		if !ratePattern.MatchString(impairment.Rate) {
+			return "", fmt.Errorf("NetemArguments: Invalid rate %s", impairment.Rate)
+		}
+		arguments = append(arguments, "rate", impairment.Rate)
+	}
+	return strings.Join(arguments, " "), nil
+}
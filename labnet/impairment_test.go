@@ -0,0 +1,35 @@
+package labnet_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	ltbv1alpha1 "github.com/Lab-Topology-Builder/LTB-K8s-Backend/api/v1alpha1"
+	"github.com/Lab-Topology-Builder/LTB-K8s-Backend/labnet"
+)
+
+var _ = Describe("NetemArguments", func() {
+	It("should return no arguments without impairment", func() {
+		Expect(labnet.NetemArguments(nil)).To(BeEmpty())
+		Expect(labnet.NetemArguments(&ltbv1alpha1.Impairment{})).To(BeEmpty())
+	})
+	It("should convert all impairments", func() {
+		arguments, err := labnet.NetemArguments(&ltbv1alpha1.Impairment{Delay: "100ms", Jitter: "10ms", Loss: "0.5", Rate: "10mbit", Corruption: "0.1"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(arguments).To(Equal("delay 100ms 10ms loss 0.5% corrupt 0.1% rate 10mbit"))
+	})
+	It("should fail for jitter without delay", func() {
+		_, err := labnet.NetemArguments(&ltbv1alpha1.Impairment{Jitter: "10ms"})
+		Expect(err).To(HaveOccurred())
+	})
+	It("should fail for a percentage above 100", func() {
+		_, err := labnet.NetemArguments(&ltbv1alpha1.Impairment{Loss: "101"})
+		Expect(err).To(HaveOccurred())
+	})
+	It("should fail for values, which are not valid netem arguments", func() {
+		for _, impairment := range []ltbv1alpha1.Impairment{{Delay: "10ms; reboot"}, {Delay: "10ms", Jitter: "$(id)"}, {Rate: "fast"}, {Corruption: "1%"}} {
+			_, err := labnet.NetemArguments(&impairment)
+			Expect(err).To(HaveOccurred())
+		}
+	})
+})
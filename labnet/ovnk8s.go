@@ -0,0 +1,35 @@
+package labnet
+
+import "fmt"
+
+// ovnK8sBackend creates an OVN-Kubernetes secondary network with layer 2 topology per segment, which spans all hosts of the cluster.
+type ovnK8sBackend struct{}
+
+type ovnK8sNetworkConfig struct {
+	CNIVersion       string `json:"cniVersion"`
+	Name             string `json:"name"`
+	Type             string `json:"type"`
+	Topology         string `json:"topology"`
+	NetAttachDefName string `json:"netAttachDefName"`
+	Subnets          string `json:"subnets,omitempty"`
+}
+
+func (b *ovnK8sBackend) NetworkConfig(attachment *Attachment) (string, error) {
+	// OVN-Kubernetes manages the addresses of a network by itself and doesn't support an IPAM section
+	if len(attachment.Addresses) > 0 {
+		return "", fmt.Errorf("NetworkConfig: Static addresses are not supported by the %s network backend, they have to be configured inside the lab node", OVNK8s)
+	}
+	// Both attachments of a segment have to use the same network name and configuration to be part of the same network
+	return marshalConfig(ovnK8sNetworkConfig{
+		CNIVersion:       "0.3.1",
+		Name:             attachment.Segment,
+		Type:             "ovn-k8s-cni-overlay",
+		Topology:         "layer2",
+		NetAttachDefName: attachment.Namespace + "/" + attachment.Name,
+		Subnets:          attachment.Subnet,
+	})
+}
+
+func (b *ovnK8sBackend) NeedsSegmentID() bool {
+	return false
+}
@@ -0,0 +1,39 @@
+package labnet_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/Lab-Topology-Builder/LTB-K8s-Backend/labnet"
+)
+
+var _ = Describe("OVN-Kubernetes backend", func() {
+	var backend labnet.Backend
+	var attachment *labnet.Attachment
+
+	BeforeEach(func() {
+		backend, _ = labnet.NewBackend(labnet.OVNK8s, labnet.Config{})
+		attachment = &labnet.Attachment{Namespace: "test-namespace", Name: "test-lab-node-eth1", NetworkName: "ltbnetwork", Segment: "ltbsegment"}
+	})
+
+	It("should not need segment IDs", func() {
+		Expect(backend.NeedsSegmentID()).To(BeFalse())
+	})
+	It("should create a layer 2 network per segment", func() {
+		config, err := backend.NetworkConfig(attachment)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(config).To(MatchJSON(`{"cniVersion": "0.3.1", "name": "ltbsegment", "type": "ovn-k8s-cni-overlay", "topology": "layer2",
+			"netAttachDefName": "test-namespace/test-lab-node-eth1"}`))
+	})
+	It("should let OVN-Kubernetes assign addresses from the subnet", func() {
+		attachment.Subnet = "10.10.0.0/24"
+		config, err := backend.NetworkConfig(attachment)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(config).To(ContainSubstring(`"subnets":"10.10.0.0/24"`))
+	})
+	It("should fail for static addresses", func() {
+		attachment.Addresses = []string{"10.0.0.1/24"}
+		_, err := backend.NetworkConfig(attachment)
+		Expect(err).To(HaveOccurred())
+	})
+})
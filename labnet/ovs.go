@@ -0,0 +1,36 @@
+package labnet
+
+import "fmt"
+
+// ovsBackend attaches all interfaces to a shared Open vSwitch bridge with OVS-CNI.
+// The segments are isolated from each other by VLAN tags, the bridge has to be connected to the other hosts, e.g. with VXLAN or Geneve tunnels.
+type ovsBackend struct {
+	bridge string
+}
+
+type ovsNetworkConfig struct {
+	CNIVersion string     `json:"cniVersion"`
+	Name       string     `json:"name"`
+	Type       string     `json:"type"`
+	Bridge     string     `json:"bridge"`
+	VLAN       int32      `json:"vlan"`
+	IPAM       ipamConfig `json:"ipam"`
+}
+
+func (b *ovsBackend) NetworkConfig(attachment *Attachment) (string, error) {
+	if attachment.SegmentID < 1 || attachment.SegmentID > 4094 {
+		return "", fmt.Errorf("NetworkConfig: Segment %s has invalid VLAN ID %d", attachment.Segment, attachment.SegmentID)
+	}
+	return marshalConfig(ovsNetworkConfig{
+		CNIVersion: "0.4.0",
+		Name:       attachment.NetworkName,
+		Type:       "ovs",
+		Bridge:     b.bridge,
+		VLAN:       attachment.SegmentID,
+		IPAM:       newIPAMConfig(attachment, "whereabouts"),
+	})
+}
+
+func (b *ovsBackend) NeedsSegmentID() bool {
+	return true
+}
@@ -0,0 +1,34 @@
+package labnet_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/Lab-Topology-Builder/LTB-K8s-Backend/labnet"
+)
+
+var _ = Describe("OVS backend", func() {
+	var backend labnet.Backend
+	var attachment *labnet.Attachment
+
+	BeforeEach(func() {
+		backend, _ = labnet.NewBackend(labnet.OVS, labnet.Config{OVSBridge: "br1"})
+		attachment = &labnet.Attachment{Namespace: "test-namespace", Name: "test-lab-node-eth1", NetworkName: "ltbnetwork", Segment: "ltbsegment", SegmentID: 42}
+	})
+
+	It("should need segment IDs", func() {
+		Expect(backend.NeedsSegmentID()).To(BeTrue())
+	})
+	It("should attach the interface to the OVS bridge with the VLAN of the segment", func() {
+		attachment.Addresses = []string{"10.0.0.1/24"}
+		config, err := backend.NetworkConfig(attachment)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(config).To(MatchJSON(`{"cniVersion": "0.4.0", "name": "ltbnetwork", "type": "ovs", "bridge": "br1", "vlan": 42,
+			"ipam": {"type": "static", "addresses": [{"address": "10.0.0.1/24"}]}}`))
+	})
+	It("should fail for an invalid VLAN ID", func() {
+		attachment.SegmentID = 4095
+		_, err := backend.NetworkConfig(attachment)
+		Expect(err).To(HaveOccurred())
+	})
+})
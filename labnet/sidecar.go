@@ -0,0 +1,85 @@
+package labnet
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// Name of the sidecar container, which controls the state and the impairment of the link interfaces of a pod.
+	LinkContainerName = "link-control"
+	linkImage         = "docker.io/nicolaka/netshoot:latest"
+	linkMountPath     = "/etc/ltb/links"
+	linkFile          = "links"
+)
+
+// linkScript applies the link configuration file, which has a line "<interface> <up|down> [netem arguments]" per link interface.
+// The file is a projected ConfigMap key, so changes are picked up without restarting the pod.
+const linkScript = `applied=""
+while true; do
+  current="$(cat ` + linkMountPath + `/` + linkFile + ` 2>/dev/null)"
+  if [ "$current" != "$applied" ]; then
+    failed=""
+    while read -r iface state args; do
+      [ -n "$iface" ] || continue
+      ip link set dev "$iface" "$state" || failed=1
+      if [ -n "$args" ]; then
+        tc qdisc replace dev "$iface" root netem $args || failed=1
+      else
+        tc qdisc del dev "$iface" root 2>/dev/null
+      fi
+    done <<EOF
+$current
+EOF
+    [ -z "$failed" ] && applied="$current"
+  fi
+  sleep 5
+done
+`
+
+// LinkConfigLine returns the line of the link configuration file for an interface.
+// The netem arguments have to be validated with NetemArguments before.
+func LinkConfigLine(interfaceName string, up bool, netemArguments string) string {
+	state := "up"
+	if !up {
+		state = "down"
+	}
+	line := interfaceName + " " + state
+	if netemArguments != "" {
+		line += " " + netemArguments
+	}
+	return line + "\n"
+}
+
+// LinkSidecar returns the sidecar container and its volumes, which apply the link configuration stored under the given key of the ConfigMap to the interfaces of a pod.
+// The link volume also contains the list of capture containers of the pod, which mount it as well.
+// The sidecar mounts the capture volume, so the pcap files can still be downloaded after their capture containers terminated.
+func LinkSidecar(configMapName string, key string) (corev1.Container, []corev1.Volume) {
+	optional := true
+	container := corev1.Container{
+		Name:    LinkContainerName,
+		Image:   linkImage,
+		Command: []string{"/bin/sh", "-c", linkScript},
+		SecurityContext: &corev1.SecurityContext{
+			Capabilities: &corev1.Capabilities{Add: []corev1.Capability{"NET_ADMIN"}},
+		},
+		VolumeMounts: []corev1.VolumeMount{
+			{Name: LinkContainerName, MountPath: linkMountPath, ReadOnly: true},
+			{Name: CaptureVolumeName, MountPath: captureMountPath},
+		},
+	}
+	volume := corev1.Volume{
+		Name: LinkContainerName,
+		VolumeSource: corev1.VolumeSource{
+			ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: configMapName},
+				Items:                []corev1.KeyToPath{{Key: key, Path: linkFile}, {Key: CaptureListKey(key), Path: captureListFile}},
+				Optional:             &optional,
+			},
+		},
+	}
+	captureVolume := corev1.Volume{
+		Name:         CaptureVolumeName,
+		VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+	}
+	return container, []corev1.Volume{volume, captureVolume}
+}
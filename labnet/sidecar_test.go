@@ -0,0 +1,31 @@
+package labnet_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/Lab-Topology-Builder/LTB-K8s-Backend/labnet"
+)
+
+var _ = Describe("LinkConfigLine", func() {
+	It("should list the state of the interface", func() {
+		Expect(labnet.LinkConfigLine("eth1", true, "")).To(Equal("eth1 up\n"))
+		Expect(labnet.LinkConfigLine("eth1", false, "")).To(Equal("eth1 down\n"))
+	})
+	It("should append the netem arguments", func() {
+		Expect(labnet.LinkConfigLine("eth1", true, "delay 10ms")).To(Equal("eth1 up delay 10ms\n"))
+	})
+})
+
+var _ = Describe("LinkSidecar", func() {
+	It("should mount the key of the node from the ConfigMap", func() {
+		container, volumes := labnet.LinkSidecar("test-labinstance-links", "test-node")
+		volume := volumes[0]
+		Expect(container.Name).To(Equal(labnet.LinkContainerName))
+		Expect(container.SecurityContext.Capabilities.Add).To(ContainElement(BeEquivalentTo("NET_ADMIN")))
+		Expect(container.VolumeMounts[0].Name).To(Equal(volume.Name))
+		Expect(volume.ConfigMap.Name).To(Equal("test-labinstance-links"))
+		Expect(volume.ConfigMap.Items[0].Key).To(Equal("test-node"))
+		Expect(*volume.ConfigMap.Optional).To(BeTrue())
+	})
+})
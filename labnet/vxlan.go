@@ -0,0 +1,27 @@
+package labnet
+
+import "fmt"
+
+// vxlanBackend attaches all interfaces to a shared linux bridge, which is connected to the other hosts with a VXLAN interface.
+// The segments are isolated from each other by VLAN tags, which the VXLAN interface carries between the hosts.
+type vxlanBackend struct {
+	bridge string
+}
+
+func (b *vxlanBackend) NetworkConfig(attachment *Attachment) (string, error) {
+	if attachment.SegmentID < 1 || attachment.SegmentID > 4094 {
+		return "", fmt.Errorf("NetworkConfig: Segment %s has invalid VLAN ID %d", attachment.Segment, attachment.SegmentID)
+	}
+	return marshalConfig(bridgeNetworkConfig{
+		CNIVersion: "0.3.1",
+		Name:       attachment.NetworkName,
+		Type:       "bridge",
+		Bridge:     b.bridge,
+		VLAN:       attachment.SegmentID,
+		IPAM:       newIPAMConfig(attachment, "whereabouts"),
+	})
+}
+
+func (b *vxlanBackend) NeedsSegmentID() bool {
+	return true
+}
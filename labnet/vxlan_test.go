@@ -0,0 +1,45 @@
+package labnet_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/Lab-Topology-Builder/LTB-K8s-Backend/labnet"
+)
+
+var _ = Describe("VXLAN backend", func() {
+	var backend labnet.Backend
+	var attachment *labnet.Attachment
+
+	BeforeEach(func() {
+		backend, _ = labnet.NewBackend(labnet.VXLAN, labnet.Config{VXLANBridge: "vxlanbr0"})
+		attachment = &labnet.Attachment{Namespace: "test-namespace", Name: "test-lab-node-eth1", NetworkName: "ltbnetwork", Segment: "ltbsegment", SegmentID: 42}
+	})
+
+	It("should need segment IDs", func() {
+		Expect(backend.NeedsSegmentID()).To(BeTrue())
+	})
+	It("should attach the interface to the shared bridge with the VLAN of the segment", func() {
+		config, err := backend.NetworkConfig(attachment)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(config).To(MatchJSON(`{"cniVersion": "0.3.1", "name": "ltbnetwork", "type": "bridge", "bridge": "vxlanbr0", "vlan": 42, "ipam": {}}`))
+	})
+	It("should use the default bridge if none is configured", func() {
+		backend, _ = labnet.NewBackend(labnet.VXLAN, labnet.Config{})
+		config, err := backend.NetworkConfig(attachment)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(config).To(ContainSubstring(`"bridge":"` + labnet.DefaultConfig().VXLANBridge + `"`))
+	})
+	It("should configure whereabouts addresses from the subnet", func() {
+		attachment.Subnet = "10.10.0.0/24"
+		config, err := backend.NetworkConfig(attachment)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(config).To(MatchJSON(`{"cniVersion": "0.3.1", "name": "ltbnetwork", "type": "bridge", "bridge": "vxlanbr0", "vlan": 42,
+			"ipam": {"type": "whereabouts", "range": "10.10.0.0/24"}}`))
+	})
+	It("should fail for an invalid VLAN ID", func() {
+		attachment.SegmentID = 0
+		_, err := backend.NetworkConfig(attachment)
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -18,6 +18,7 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"os"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
@@ -32,7 +33,10 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
 	ltbbackendv1alpha1 "github.com/Lab-Topology-Builder/LTB-K8s-Backend/api/v1alpha1"
+	ltbbackendv1beta1 "github.com/Lab-Topology-Builder/LTB-K8s-Backend/api/v1beta1"
 	"github.com/Lab-Topology-Builder/LTB-K8s-Backend/controllers"
+	"github.com/Lab-Topology-Builder/LTB-K8s-Backend/labnet"
+	"github.com/Lab-Topology-Builder/LTB-K8s-Backend/util"
 
 	//+kubebuilder:scaffold:imports
 
@@ -50,6 +54,7 @@ func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 
 	utilruntime.Must(ltbbackendv1alpha1.AddToScheme(scheme))
+	utilruntime.Must(ltbbackendv1beta1.AddToScheme(scheme))
 	//+kubebuilder:scaffold:scheme
 
 	// Add kubevirt scheme
@@ -63,11 +68,22 @@ func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var labNetworkPool string
+	var labSubnetPrefixLength int
+	var labSegmentIDRange string
+	var defaultDNSAddress string
+	networkConfig := labnet.DefaultConfig()
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.StringVar(&labNetworkPool, "lab-network-pool", "10.10.0.0/16", "The IPv4 network from which every lab instance gets a subnet of its own.")
+	flag.IntVar(&labSubnetPrefixLength, "lab-subnet-prefix-length", 24, "The prefix length of the subnet allocated for every lab instance.")
+	flag.StringVar(&labSegmentIDRange, "lab-segment-id-range", "1-4094", "The range of VLAN IDs allocated for the links of lab instances using the vxlan or ovs network backend.")
+	flag.StringVar(&defaultDNSAddress, "default-dns-address", "", "The DNS address of lab instances, which don't define one.")
+	flag.StringVar(&networkConfig.VXLANBridge, "vxlan-bridge", networkConfig.VXLANBridge, "The linux bridge used by the vxlan network backend, it has to be connected to the other nodes with a VXLAN interface.")
+	flag.StringVar(&networkConfig.OVSBridge, "ovs-bridge", networkConfig.OVSBridge, "The Open vSwitch bridge used by the ovs network backend.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -100,27 +116,80 @@ func main() {
 		os.Exit(1)
 	}
 
+	subnetAllocator, err := util.NewSubnetAllocator(labNetworkPool, labSubnetPrefixLength)
+	if err != nil {
+		setupLog.Error(err, "unable to create subnet allocator")
+		os.Exit(1)
+	}
+	var firstSegmentID, lastSegmentID int32
+	_, err = fmt.Sscanf(labSegmentIDRange, "%d-%d", &firstSegmentID, &lastSegmentID)
+	if err != nil {
+		setupLog.Error(err, "invalid segment ID range", "range", labSegmentIDRange)
+		os.Exit(1)
+	}
+	segmentAllocator, err := util.NewSegmentAllocator(firstSegmentID, lastSegmentID)
+	if err != nil {
+		setupLog.Error(err, "unable to create segment allocator")
+		os.Exit(1)
+	}
 	if err = (&controllers.LabInstanceReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:           mgr.GetClient(),
+		Scheme:           mgr.GetScheme(),
+		SubnetAllocator:  subnetAllocator,
+		SegmentAllocator: segmentAllocator,
+		NetworkConfig:    networkConfig,
+		Recorder:         mgr.GetEventRecorderFor("labinstance-controller"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "LabInstance")
 		os.Exit(1)
 	}
 	if err = (&controllers.LabTemplateReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("labtemplate-controller"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "LabTemplate")
 		os.Exit(1)
 	}
 	if err = (&controllers.NodeTypeReconciler{
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("nodetype-controller"),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "NodeType")
+		os.Exit(1)
+	}
+	if err = (&controllers.PacketCaptureReconciler{
 		Client: mgr.GetClient(),
 		Scheme: mgr.GetScheme(),
 	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "NodeType")
+		setupLog.Error(err, "unable to create controller", "controller", "PacketCapture")
 		os.Exit(1)
 	}
+	// The webhooks need a serving certificate, they can be disabled to run the operator locally.
+	// They also serve the conversion between the v1alpha1 and v1beta1 API, as both versions are in the scheme.
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		if err = (&controllers.NodeTypeValidator{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "NodeType")
+			os.Exit(1)
+		}
+		if err = (&controllers.LabTemplateDefaulter{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "LabTemplate")
+			os.Exit(1)
+		}
+		if err = (&controllers.LabTemplateValidator{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "LabTemplate")
+			os.Exit(1)
+		}
+		if err = (&controllers.LabInstanceDefaulter{DefaultDNSAddress: defaultDNSAddress}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "LabInstance")
+			os.Exit(1)
+		}
+		if err = (&controllers.LabInstanceValidator{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "LabInstance")
+			os.Exit(1)
+		}
+	}
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -2,16 +2,18 @@ package util
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 	"text/template"
+	"text/template/parse"
 
 	"sigs.k8s.io/controller-runtime/pkg/log"
-
-	ltbv1alpha1 "github.com/Lab-Topology-Builder/LTB-K8s-Backend/api/v1alpha1"
 )
 
-func ParseAndRenderTemplate(nodetype *ltbv1alpha1.NodeType, renderedNodeSpec *strings.Builder, data ltbv1alpha1.LabInstanceNodes) error {
-	tmplt, err := template.New("nodeTemplate").Parse(nodetype.Spec.NodeSpec)
+// ParseAndRenderTemplate renders the node spec of a node type with the template context of a lab node and the template functions.
+// Missing keys fail the rendering, instead of rendering "<no value>" into the node spec.
+func ParseAndRenderTemplate(nodeSpec string, renderedNodeSpec *strings.Builder, data TemplateContext) error {
+	tmplt, err := template.New("nodeTemplate").Option("missingkey=error").Funcs(TemplateFuncs()).Parse(nodeSpec)
 
 	if err != nil {
 		return fmt.Errorf("ParseAndRenderTemplate: Failed to parse template\nErr:%s", err)
@@ -20,6 +22,92 @@ func ParseAndRenderTemplate(nodetype *ltbv1alpha1.NodeType, renderedNodeSpec *st
 	if err != nil {
 		return fmt.Errorf("ParseAndRenderTemplate: Failed to render template\nErr:%s", err)
 	}
-	log.Log.Info(fmt.Sprintf("Rendered Template: %s", renderedNodeSpec.String()))
+	log.Log.V(1).Info("Rendered template", "Node", data.Name)
 	return nil
 }
+
+// ReferencedParameters returns the sorted names of the parameters, which a node spec references as .Params.<name> or $.Params.<name>.
+func ReferencedParameters(nodeSpec string) ([]string, error) {
+	parameters, err := referencedKeys(nodeSpec, "Params")
+	if err != nil {
+		return nil, fmt.Errorf("ReferencedParameters: %w", err)
+	}
+	return parameters, nil
+}
+
+// ReferencedSecrets returns the sorted names of the secret references, which a node spec uses as .Secrets.<name> or $.Secrets.<name>.
+func ReferencedSecrets(nodeSpec string) ([]string, error) {
+	secrets, err := referencedKeys(nodeSpec, "Secrets")
+	if err != nil {
+		return nil, fmt.Errorf("ReferencedSecrets: %w", err)
+	}
+	return secrets, nil
+}
+
+// referencedKeys returns the sorted keys of a map field of the template context, which a node spec references as .<field>.<key> or $.<field>.<key>.
+func referencedKeys(nodeSpec string, field string) ([]string, error) {
+	tmplt, err := template.New("nodeTemplate").Funcs(TemplateFuncs()).Parse(nodeSpec)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse template\nErr:%s", err)
+	}
+	keys := map[string]bool{}
+	for _, definedTemplate := range tmplt.Templates() {
+		if definedTemplate.Tree != nil {
+			collectKeys(definedTemplate.Tree.Root, field, keys)
+		}
+	}
+	names := []string{}
+	for name := range keys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// collectKeys walks the parse tree of a template and adds the keys of the map field, which are referenced.
+func collectKeys(node parse.Node, field string, keys map[string]bool) {
+	switch node := node.(type) {
+	case *parse.ListNode:
+		if node == nil {
+			return
+		}
+		for _, child := range node.Nodes {
+			collectKeys(child, field, keys)
+		}
+	case *parse.ActionNode:
+		collectKeys(node.Pipe, field, keys)
+	case *parse.PipeNode:
+		if node == nil {
+			return
+		}
+		for _, command := range node.Cmds {
+			collectKeys(command, field, keys)
+		}
+	case *parse.CommandNode:
+		for _, argument := range node.Args {
+			collectKeys(argument, field, keys)
+		}
+	case *parse.FieldNode:
+		if len(node.Ident) > 1 && node.Ident[0] == field {
+			keys[node.Ident[1]] = true
+		}
+	case *parse.VariableNode:
+		if len(node.Ident) > 2 && node.Ident[0] == "$" && node.Ident[1] == field {
+			keys[node.Ident[2]] = true
+		}
+	case *parse.ChainNode:
+		collectKeys(node.Node, field, keys)
+	case *parse.IfNode:
+		collectKeys(&node.BranchNode, field, keys)
+	case *parse.RangeNode:
+		collectKeys(&node.BranchNode, field, keys)
+	case *parse.WithNode:
+		collectKeys(&node.BranchNode, field, keys)
+	case *parse.BranchNode:
+		collectKeys(node.Pipe, field, keys)
+		collectKeys(node.List, field, keys)
+		collectKeys(node.ElseList, field, keys)
+	case *parse.TemplateNode:
+		collectKeys(node.Pipe, field, keys)
+	}
+}
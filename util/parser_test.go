@@ -70,12 +70,12 @@ chpasswd: { expire: False }`,
 			}
 		})
 		It("should return nil", func() {
-			err := util.ParseAndRenderTemplate(nodeType, &strings.Builder{}, data)
+			err := util.ParseAndRenderTemplate(nodeType.Spec.NodeSpec, &strings.Builder{}, util.TemplateContext{LabInstanceNodes: data})
 			Expect(err).To(BeNil())
 		})
 		It("should parse and render the template correctly", func() {
 			var sb strings.Builder
-			err := util.ParseAndRenderTemplate(nodeType, &sb, data)
+			err := util.ParseAndRenderTemplate(nodeType.Spec.NodeSpec, &sb, util.TemplateContext{LabInstanceNodes: data})
 			Expect(err).To(BeNil())
 			Expect(sb.String()).To(MatchYAML(`
 containers:
@@ -106,7 +106,7 @@ containers:
 			data = ltbv1alpha1.LabInstanceNodes{}
 		})
 		It("should return an error", func() {
-			err := util.ParseAndRenderTemplate(nodeType, &strings.Builder{}, data)
+			err := util.ParseAndRenderTemplate(nodeType.Spec.NodeSpec, &strings.Builder{}, util.TemplateContext{LabInstanceNodes: data})
 			Expect(err).ToNot(BeNil())
 			Expect(err.Error()).To(ContainSubstring("Failed to parse template"))
 		})
@@ -133,9 +133,45 @@ containers:
 			data = ltbv1alpha1.LabInstanceNodes{}
 		})
 		It("should not return an error", func() {
-			err := util.ParseAndRenderTemplate(nodeType, &strings.Builder{}, data)
+			err := util.ParseAndRenderTemplate(nodeType.Spec.NodeSpec, &strings.Builder{}, util.TemplateContext{LabInstanceNodes: data})
 			Expect(err).To(BeNil())
 		})
 	})
+	Context("When listing the referenced parameters", func() {
+		It("should find the parameters in actions, pipelines and blocks", func() {
+			parameters, err := util.ReferencedParameters(`
+name: {{ .Params.name }}
+{{- if .Params.enabled }}
+address: {{ cidrhost .Params.subnet 1 | printf "%s" }}
+{{- end }}
+{{- range .Ports }}
+port: {{ $.Params.port }}
+{{- end }}
+name: {{ .Params.name }}`)
+			Expect(err).To(BeNil())
+			Expect(parameters).To(Equal([]string{"enabled", "name", "port", "subnet"}))
+		})
+		It("should return no parameters for a node spec without parameters", func() {
+			parameters, err := util.ReferencedParameters(`name: {{ .Name }}`)
+			Expect(err).To(BeNil())
+			Expect(parameters).To(BeEmpty())
+		})
+		It("should return an error for an invalid node spec", func() {
+			_, err := util.ReferencedParameters(`name: {{ .Params.name`)
+			Expect(err).ToNot(BeNil())
+		})
+	})
+	Context("When listing the referenced secrets", func() {
+		It("should find the secrets, but not the parameters", func() {
+			secrets, err := util.ReferencedSecrets(`
+name: {{ .Params.name }}
+secretName: {{ .Secrets.admin }}
+{{- range .Ports }}
+license: {{ $.Secrets.license }}
+{{- end }}`)
+			Expect(err).To(BeNil())
+			Expect(secrets).To(Equal([]string{"admin", "license"}))
+		})
+	})
 
 })
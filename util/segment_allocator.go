@@ -0,0 +1,94 @@
+package util
+
+import (
+	"fmt"
+	"sync"
+)
+
+// SegmentAllocator hands out cluster-wide unique IDs, e.g. VLAN IDs, from a fixed range to the segments of lab instances.
+// Each ID is owned by exactly one segment of one owner, e.g. the namespaced name of a lab instance.
+type SegmentAllocator struct {
+	first    int32
+	last     int32
+	mutex    sync.Mutex
+	owners   map[int32]string
+	segments map[string]map[string]int32
+}
+
+func NewSegmentAllocator(first int32, last int32) (*SegmentAllocator, error) {
+	if first < 1 || last < first {
+		return nil, fmt.Errorf("NewSegmentAllocator: Invalid range %d-%d", first, last)
+	}
+	return &SegmentAllocator{
+		first:    first,
+		last:     last,
+		owners:   map[int32]string{},
+		segments: map[string]map[string]int32{},
+	}, nil
+}
+
+// Allocate returns the ID of the segment of the owner, or allocates the first free ID of the range.
+func (a *SegmentAllocator) Allocate(owner string, segment string) (int32, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if id, ok := a.segments[owner][segment]; ok {
+		return id, nil
+	}
+	for id := a.first; id <= a.last; id++ {
+		if _, used := a.owners[id]; !used {
+			a.add(owner, segment, id)
+			return id, nil
+		}
+	}
+	return 0, fmt.Errorf("Allocate: No free segment ID left in range %d-%d", a.first, a.last)
+}
+
+// Reserve records an existing allocation, e.g. after a restart of the operator.
+func (a *SegmentAllocator) Reserve(owner string, segment string, id int32) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if id < a.first || id > a.last {
+		return fmt.Errorf("Reserve: Segment ID %d is not part of range %d-%d", id, a.first, a.last)
+	}
+	if currentOwner, used := a.owners[id]; used {
+		if currentOwner == owner && a.segments[owner][segment] == id {
+			return nil
+		}
+		return fmt.Errorf("Reserve: Segment ID %d is already allocated to %s", id, currentOwner)
+	}
+	if currentID, ok := a.segments[owner][segment]; ok {
+		delete(a.owners, currentID)
+	}
+	a.add(owner, segment, id)
+	return nil
+}
+
+// Release frees all segment IDs of the owner, so they can be allocated again.
+func (a *SegmentAllocator) Release(owner string) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	for _, id := range a.segments[owner] {
+		delete(a.owners, id)
+	}
+	delete(a.segments, owner)
+}
+
+// ReleaseSegment frees the ID of a single segment of the owner, e.g. of a removed link.
+func (a *SegmentAllocator) ReleaseSegment(owner string, segment string) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	id, ok := a.segments[owner][segment]
+	if !ok {
+		return
+	}
+	delete(a.owners, id)
+	delete(a.segments[owner], segment)
+}
+
+func (a *SegmentAllocator) add(owner string, segment string, id int32) {
+	if a.segments[owner] == nil {
+		a.segments[owner] = map[string]int32{}
+	}
+	a.segments[owner][segment] = id
+	a.owners[id] = owner
+}
@@ -0,0 +1,93 @@
+package util_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/Lab-Topology-Builder/LTB-K8s-Backend/util"
+)
+
+var _ = Describe("SegmentAllocator", func() {
+	var allocator *util.SegmentAllocator
+
+	Context("When creating an allocator", func() {
+		It("should fail for an empty range", func() {
+			_, err := util.NewSegmentAllocator(10, 9)
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail for a range starting at 0", func() {
+			_, err := util.NewSegmentAllocator(0, 10)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("When allocating segment IDs", func() {
+		BeforeEach(func() {
+			var err error
+			allocator, err = util.NewSegmentAllocator(100, 101)
+			Expect(err).NotTo(HaveOccurred())
+		})
+		It("should allocate unique IDs", func() {
+			id1, err := allocator.Allocate("ns/lab1", "segment1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(id1).To(Equal(int32(100)))
+			id2, err := allocator.Allocate("ns/lab2", "segment1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(id2).To(Equal(int32(101)))
+		})
+		It("should return the same ID for the same segment", func() {
+			id1, _ := allocator.Allocate("ns/lab1", "segment1")
+			id2, _ := allocator.Allocate("ns/lab1", "segment1")
+			Expect(id2).To(Equal(id1))
+		})
+		It("should fail when the range is exhausted", func() {
+			_, _ = allocator.Allocate("ns/lab1", "segment1")
+			_, _ = allocator.Allocate("ns/lab1", "segment2")
+			_, err := allocator.Allocate("ns/lab1", "segment3")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should reuse the IDs of a released owner", func() {
+			_, _ = allocator.Allocate("ns/lab1", "segment1")
+			_, _ = allocator.Allocate("ns/lab1", "segment2")
+			allocator.Release("ns/lab1")
+			id, err := allocator.Allocate("ns/lab2", "segment1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(id).To(Equal(int32(100)))
+		})
+		It("should reuse the ID of a released segment", func() {
+			_, _ = allocator.Allocate("ns/lab1", "segment1")
+			_, _ = allocator.Allocate("ns/lab1", "segment2")
+			allocator.ReleaseSegment("ns/lab1", "segment1")
+			id, err := allocator.Allocate("ns/lab2", "segment1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(id).To(Equal(int32(100)))
+			id, _ = allocator.Allocate("ns/lab1", "segment2")
+			Expect(id).To(Equal(int32(101)))
+		})
+	})
+
+	Context("When reserving segment IDs", func() {
+		BeforeEach(func() {
+			var err error
+			allocator, err = util.NewSegmentAllocator(100, 101)
+			Expect(err).NotTo(HaveOccurred())
+		})
+		It("should skip reserved IDs while allocating", func() {
+			Expect(allocator.Reserve("ns/lab1", "segment1", 100)).To(Succeed())
+			id, err := allocator.Allocate("ns/lab2", "segment1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(id).To(Equal(int32(101)))
+		})
+		It("should succeed for an ID already reserved by the same segment", func() {
+			Expect(allocator.Reserve("ns/lab1", "segment1", 100)).To(Succeed())
+			Expect(allocator.Reserve("ns/lab1", "segment1", 100)).To(Succeed())
+		})
+		It("should fail for an ID of another segment", func() {
+			Expect(allocator.Reserve("ns/lab1", "segment1", 100)).To(Succeed())
+			Expect(allocator.Reserve("ns/lab1", "segment2", 100)).NotTo(Succeed())
+		})
+		It("should fail for an ID outside of the range", func() {
+			Expect(allocator.Reserve("ns/lab1", "segment1", 4095)).NotTo(Succeed())
+		})
+	})
+})
@@ -0,0 +1,96 @@
+package util
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"sync"
+)
+
+// SubnetAllocator hands out non-overlapping subnets of a fixed prefix length from a cluster-wide pool.
+// Each subnet is owned by exactly one owner, e.g. the namespaced name of a lab instance.
+type SubnetAllocator struct {
+	pool         *net.IPNet
+	prefixLength int
+	mutex        sync.Mutex
+	owners       map[string]string
+	subnets      map[string]string
+}
+
+func NewSubnetAllocator(pool string, prefixLength int) (*SubnetAllocator, error) {
+	_, poolNet, err := net.ParseCIDR(pool)
+	if err != nil {
+		return nil, fmt.Errorf("NewSubnetAllocator: Invalid pool %s\nErr:%s", pool, err)
+	}
+	if poolNet.IP.To4() == nil {
+		return nil, fmt.Errorf("NewSubnetAllocator: Pool %s is not an IPv4 network", pool)
+	}
+	poolPrefixLength, _ := poolNet.Mask.Size()
+	if prefixLength < poolPrefixLength || prefixLength > 30 {
+		return nil, fmt.Errorf("NewSubnetAllocator: Prefix length %d must be between %d and 30", prefixLength, poolPrefixLength)
+	}
+	return &SubnetAllocator{
+		pool:         poolNet,
+		prefixLength: prefixLength,
+		owners:       map[string]string{},
+		subnets:      map[string]string{},
+	}, nil
+}
+
+// Allocate returns the subnet of the owner, or allocates the first free subnet of the pool.
+func (a *SubnetAllocator) Allocate(owner string) (string, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if subnet, ok := a.subnets[owner]; ok {
+		return subnet, nil
+	}
+	poolPrefixLength, _ := a.pool.Mask.Size()
+	numSubnets := uint32(1) << uint(a.prefixLength-poolPrefixLength)
+	subnetSize := uint32(1) << uint(32-a.prefixLength)
+	base := binary.BigEndian.Uint32(a.pool.IP.To4())
+	for i := uint32(0); i < numSubnets; i++ {
+		ip := make(net.IP, 4)
+		binary.BigEndian.PutUint32(ip, base+i*subnetSize)
+		subnet := (&net.IPNet{IP: ip, Mask: net.CIDRMask(a.prefixLength, 32)}).String()
+		if _, used := a.owners[subnet]; !used {
+			a.owners[subnet] = owner
+			a.subnets[owner] = subnet
+			return subnet, nil
+		}
+	}
+	return "", fmt.Errorf("Allocate: No free subnet left in pool %s", a.pool.String())
+}
+
+// Reserve records an existing allocation, e.g. after a restart of the operator.
+func (a *SubnetAllocator) Reserve(owner string, subnet string) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	_, subnetNet, err := net.ParseCIDR(subnet)
+	if err != nil {
+		return fmt.Errorf("Reserve: Invalid subnet %s\nErr:%s", subnet, err)
+	}
+	subnetPrefixLength, _ := subnetNet.Mask.Size()
+	if !a.pool.Contains(subnetNet.IP) || subnetPrefixLength != a.prefixLength {
+		return fmt.Errorf("Reserve: Subnet %s is not part of pool %s with prefix length %d", subnet, a.pool.String(), a.prefixLength)
+	}
+	subnet = subnetNet.String()
+	if currentOwner, used := a.owners[subnet]; used && currentOwner != owner {
+		return fmt.Errorf("Reserve: Subnet %s is already allocated to %s", subnet, currentOwner)
+	}
+	if currentSubnet, ok := a.subnets[owner]; ok && currentSubnet != subnet {
+		delete(a.owners, currentSubnet)
+	}
+	a.owners[subnet] = owner
+	a.subnets[owner] = subnet
+	return nil
+}
+
+// Release frees the subnet of the owner, so it can be allocated again.
+func (a *SubnetAllocator) Release(owner string) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if subnet, ok := a.subnets[owner]; ok {
+		delete(a.owners, subnet)
+		delete(a.subnets, owner)
+	}
+}
@@ -0,0 +1,86 @@
+package util_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/Lab-Topology-Builder/LTB-K8s-Backend/util"
+)
+
+var _ = Describe("SubnetAllocator", func() {
+	var allocator *util.SubnetAllocator
+
+	Context("When creating an allocator", func() {
+		It("should fail for an invalid pool", func() {
+			_, err := util.NewSubnetAllocator("10.10.0.0", 24)
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail for an IPv6 pool", func() {
+			_, err := util.NewSubnetAllocator("2001:db8::/48", 64)
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail for a prefix length shorter than the pool", func() {
+			_, err := util.NewSubnetAllocator("10.10.0.0/16", 8)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("When allocating subnets", func() {
+		BeforeEach(func() {
+			var err error
+			allocator, err = util.NewSubnetAllocator("10.10.0.0/23", 24)
+			Expect(err).NotTo(HaveOccurred())
+		})
+		It("should allocate non-overlapping subnets", func() {
+			subnet1, err := allocator.Allocate("ns/lab1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(subnet1).To(Equal("10.10.0.0/24"))
+			subnet2, err := allocator.Allocate("ns/lab2")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(subnet2).To(Equal("10.10.1.0/24"))
+		})
+		It("should return the same subnet for the same owner", func() {
+			subnet1, _ := allocator.Allocate("ns/lab1")
+			subnet2, _ := allocator.Allocate("ns/lab1")
+			Expect(subnet2).To(Equal(subnet1))
+		})
+		It("should fail when the pool is exhausted", func() {
+			_, _ = allocator.Allocate("ns/lab1")
+			_, _ = allocator.Allocate("ns/lab2")
+			_, err := allocator.Allocate("ns/lab3")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should reuse a released subnet", func() {
+			_, _ = allocator.Allocate("ns/lab1")
+			_, _ = allocator.Allocate("ns/lab2")
+			allocator.Release("ns/lab1")
+			subnet, err := allocator.Allocate("ns/lab3")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(subnet).To(Equal("10.10.0.0/24"))
+		})
+	})
+
+	Context("When reserving subnets", func() {
+		BeforeEach(func() {
+			var err error
+			allocator, err = util.NewSubnetAllocator("10.10.0.0/23", 24)
+			Expect(err).NotTo(HaveOccurred())
+		})
+		It("should skip reserved subnets while allocating", func() {
+			Expect(allocator.Reserve("ns/lab1", "10.10.0.0/24")).To(Succeed())
+			subnet, err := allocator.Allocate("ns/lab2")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(subnet).To(Equal("10.10.1.0/24"))
+		})
+		It("should fail for a subnet of another owner", func() {
+			Expect(allocator.Reserve("ns/lab1", "10.10.0.0/24")).To(Succeed())
+			Expect(allocator.Reserve("ns/lab2", "10.10.0.0/24")).NotTo(Succeed())
+		})
+		It("should fail for a subnet outside of the pool", func() {
+			Expect(allocator.Reserve("ns/lab1", "10.20.0.0/24")).NotTo(Succeed())
+		})
+		It("should fail for a subnet with another prefix length", func() {
+			Expect(allocator.Reserve("ns/lab1", "10.10.0.0/25")).NotTo(Succeed())
+		})
+	})
+})
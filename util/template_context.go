@@ -0,0 +1,64 @@
+package util
+
+import (
+	ltbv1alpha1 "github.com/Lab-Topology-Builder/LTB-K8s-Backend/api/v1alpha1"
+)
+
+// TemplateContext is the data, which is available in the node spec of a node type, when a lab node is rendered.
+// The fields of the rendered lab node are available directly as well, e.g. {{ .Name }}, so node specs written for the lab node alone keep working.
+type TemplateContext struct {
+	ltbv1alpha1.LabInstanceNodes
+	// The rendered lab node.
+	Node NodeContext
+	// The lab template and all its lab nodes.
+	Lab LabContext
+	// The lab instance, which the lab node is rendered for.
+	// Its fields are empty, when the lab node is rendered into the status of the lab template.
+	Instance InstanceContext
+	// The links of the rendered lab node in the order of the links of the lab template.
+	Links []LinkContext
+	// The lab nodes at the other end of the links of the rendered lab node, every lab node only once.
+	Peers []NodeContext
+	// The values of the parameters of the lab template by name, converted to their types.
+	Params map[string]interface{}
+	// The names of the Secrets of the rendered lab node by the names of their references, e.g. to use them in a secretKeyRef.
+	// The values of the Secrets are never rendered into the node spec.
+	Secrets map[string]string
+}
+
+// NodeContext is a lab node of the lab template. Its interfaces include the interfaces, which are only referenced by a link.
+type NodeContext struct {
+	ltbv1alpha1.LabInstanceNodes
+	// The position of the lab node in the lab template, starting at 0.
+	Index int
+}
+
+// LabContext is the lab template of the rendered lab node.
+type LabContext struct {
+	// The name of the lab template.
+	Name string
+	// All lab nodes of the lab template in their order.
+	Nodes []NodeContext
+}
+
+// InstanceContext is the lab instance of the rendered lab node.
+type InstanceContext struct {
+	// The name of the lab instance.
+	Name string
+	// The namespace of the lab instance, which the lab node runs in.
+	Namespace string
+	// The DNS address, which is used to expose the lab instance.
+	DNSAddress string
+}
+
+// LinkContext is a link of the rendered lab node, seen from the rendered lab node.
+type LinkContext struct {
+	// The name of the link.
+	Name string
+	// The interface of the rendered lab node.
+	Interface ltbv1alpha1.NodeInterface
+	// The name of the lab node at the other end of the link.
+	Peer string
+	// The interface of the lab node at the other end of the link, e.g. to configure its address as neighbor.
+	PeerInterface ltbv1alpha1.NodeInterface
+}
@@ -0,0 +1,150 @@
+package util
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"net"
+	"reflect"
+	"strings"
+	"text/template"
+
+	"sigs.k8s.io/yaml"
+)
+
+// TemplateFuncs returns the functions, which are available in the node spec of a node type, in addition to the builtin functions of text/template.
+// The names and the order of the arguments follow the Sprig library used by Helm, so the functions can be piped, e.g. {{ .Config | nindent 8 }}.
+func TemplateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"indent":       indent,
+		"nindent":      nindent,
+		"toYaml":       toYaml,
+		"b64enc":       b64enc,
+		"default":      defaultValue,
+		"required":     required,
+		"cidrhost":     cidrhost,
+		"ipAdd":        ipAdd,
+		"macFromSeed":  macFromSeed,
+		"uuidFromSeed": uuidFromSeed,
+	}
+}
+
+// indent indents every line of the text by the given number of spaces.
+func indent(spaces int, text string) string {
+	padding := strings.Repeat(" ", spaces)
+	return padding + strings.ReplaceAll(text, "\n", "\n"+padding)
+}
+
+// nindent indents every line of the text by the given number of spaces and starts it on a new line.
+func nindent(spaces int, text string) string {
+	return "\n" + indent(spaces, text)
+}
+
+// toYaml encodes a value as YAML without the trailing newline.
+func toYaml(value interface{}) (string, error) {
+	data, err := yaml.Marshal(value)
+	if err != nil {
+		return "", fmt.Errorf("toYaml: %w", err)
+	}
+	return strings.TrimSuffix(string(data), "\n"), nil
+}
+
+// b64enc encodes the text with standard base64.
+func b64enc(text string) string {
+	return base64.StdEncoding.EncodeToString([]byte(text))
+}
+
+// defaultValue returns the given value, or the default value, if the given value is empty.
+func defaultValue(defaultValue interface{}, value interface{}) interface{} {
+	if isEmpty(value) {
+		return defaultValue
+	}
+	return value
+}
+
+// required returns the value, or fails the rendering with the message, if the value is empty.
+func required(message string, value interface{}) (interface{}, error) {
+	if isEmpty(value) {
+		return nil, fmt.Errorf("required: %s", message)
+	}
+	return value, nil
+}
+
+// isEmpty reports whether a value is nil, the zero value of its type, or an empty string, slice or map.
+func isEmpty(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+	reflectValue := reflect.ValueOf(value)
+	switch reflectValue.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return reflectValue.Len() == 0
+	default:
+		return reflectValue.IsZero()
+	}
+}
+
+// cidrhost returns the address of the host with the given number in the network of a prefix, e.g. 10.0.0.5 for 10.0.0.0/24 and 5.
+// Negative numbers count back from the end of the network, -1 is the last address.
+func cidrhost(prefix string, hostNumber int) (string, error) {
+	_, network, err := net.ParseCIDR(prefix)
+	if err != nil {
+		return "", fmt.Errorf("cidrhost: invalid prefix %s", prefix)
+	}
+	ones, bits := network.Mask.Size()
+	size := new(big.Int).Lsh(big.NewInt(1), uint(bits-ones))
+	offset := big.NewInt(int64(hostNumber))
+	if hostNumber < 0 {
+		offset.Add(offset, size)
+	}
+	if offset.Sign() < 0 || offset.Cmp(size) >= 0 {
+		return "", fmt.Errorf("cidrhost: prefix %s has no host number %d", prefix, hostNumber)
+	}
+	return addToIP(network.IP, offset).String(), nil
+}
+
+// ipAdd adds a number to an address, which may have a prefix length, e.g. 10.0.0.2/24 for 10.0.0.1/24 and 1.
+func ipAdd(address string, number int) (string, error) {
+	ipText, prefixLength, hasPrefix := strings.Cut(address, "/")
+	ip := net.ParseIP(ipText)
+	if ip == nil {
+		return "", fmt.Errorf("ipAdd: invalid address %s", address)
+	}
+	if ipv4 := ip.To4(); ipv4 != nil {
+		ip = ipv4
+	}
+	sum := addToIP(ip, big.NewInt(int64(number)))
+	if sum == nil {
+		return "", fmt.Errorf("ipAdd: %s + %d is out of range", address, number)
+	}
+	if hasPrefix {
+		return sum.String() + "/" + prefixLength, nil
+	}
+	return sum.String(), nil
+}
+
+// addToIP adds an offset to an address and returns nil, if the result doesn't fit into the address family.
+func addToIP(ip net.IP, offset *big.Int) net.IP {
+	sum := new(big.Int).Add(new(big.Int).SetBytes(ip), offset)
+	if sum.Sign() < 0 || sum.BitLen() > len(ip)*8 {
+		return nil
+	}
+	return net.IP(sum.FillBytes(make([]byte, len(ip))))
+}
+
+// macFromSeed derives a locally administered unicast MAC address from a seed, e.g. the name of the lab instance and the node.
+func macFromSeed(seed string) string {
+	hash := sha256.Sum256([]byte(seed))
+	hash[0] = hash[0]&0xfc | 0x02
+	return net.HardwareAddr(hash[:6]).String()
+}
+
+// uuidFromSeed derives a UUID of version 5 from the SHA-1 hash of a seed, so the same seed always gets the same UUID.
+func uuidFromSeed(seed string) string {
+	hash := sha1.Sum([]byte(seed))
+	hash[6] = hash[6]&0x0f | 0x50
+	hash[8] = hash[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", hash[0:4], hash[4:6], hash[6:8], hash[8:10], hash[10:16])
+}
@@ -0,0 +1,96 @@
+package util_test
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	ltbv1alpha1 "github.com/Lab-Topology-Builder/LTB-K8s-Backend/api/v1alpha1"
+
+	"github.com/Lab-Topology-Builder/LTB-K8s-Backend/util"
+)
+
+var _ = Describe("TemplateFuncs", func() {
+	var data util.TemplateContext
+
+	render := func(nodeSpec string) (string, error) {
+		var sb strings.Builder
+		err := util.ParseAndRenderTemplate(nodeSpec, &sb, data)
+		return sb.String(), err
+	}
+
+	BeforeEach(func() {
+		data = util.TemplateContext{
+			LabInstanceNodes: ltbv1alpha1.LabInstanceNodes{
+				Name: "test",
+				Interfaces: []ltbv1alpha1.NodeInterface{
+					{Name: "eth1", IPv4: "10.0.0.1/24"},
+				},
+				Config: "#cloud-config\npassword: ubuntu",
+			},
+			Instance: util.InstanceContext{Name: "test-labinstance", Namespace: "test-namespace"},
+		}
+	})
+
+	It("should indent the config into a block", func() {
+		renderedNodeSpec, err := render("userData: |{{ .Config | nindent 2 }}\nuserDataBase64: {{ .Config | b64enc }}")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(renderedNodeSpec).To(MatchYAML(`
+userData: |
+  #cloud-config
+  password: ubuntu
+userDataBase64: I2Nsb3VkLWNvbmZpZwpwYXNzd29yZDogdWJ1bnR1
+`))
+		renderedNodeSpec, err = render("{{ indent 4 \"a\\nb\" }}")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(renderedNodeSpec).To(Equal("    a\n    b"))
+	})
+	It("should encode values as YAML", func() {
+		renderedNodeSpec, err := render("interfaces:{{ .Interfaces | toYaml | nindent 2 }}")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(renderedNodeSpec).To(MatchYAML(`
+interfaces:
+  - name: eth1
+    ipv4: 10.0.0.1/24
+`))
+	})
+	It("should fall back to defaults and fail on missing required values", func() {
+		renderedNodeSpec, err := render(`{{ .NodeTypeRef.Image | default "ubuntu" }}:{{ .Name | default "other" }}`)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(renderedNodeSpec).To(Equal("ubuntu:test"))
+		_, err = render(`{{ required "the lab node needs an image" .NodeTypeRef.Image }}`)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("the lab node needs an image"))
+	})
+	It("should compute addresses", func() {
+		renderedNodeSpec, err := render(`{{ cidrhost "10.0.0.0/24" 5 }} {{ cidrhost "10.0.0.0/24" -2 }} {{ cidrhost "2001:db8::/64" 1 }} {{ ipAdd (index .Interfaces 0).IPv4 1 }} {{ ipAdd "10.0.0.255" 1 }}`)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(renderedNodeSpec).To(Equal("10.0.0.5 10.0.0.254 2001:db8::1 10.0.0.2/24 10.0.1.0"))
+		_, err = render(`{{ cidrhost "10.0.0.0/30" 4 }}`)
+		Expect(err).To(HaveOccurred())
+		_, err = render(`{{ ipAdd "255.255.255.255" 1 }}`)
+		Expect(err).To(HaveOccurred())
+		_, err = render(`{{ ipAdd "invalid" 1 }}`)
+		Expect(err).To(HaveOccurred())
+	})
+	It("should derive the same addresses and UUIDs from the same seed", func() {
+		renderedNodeSpec, err := render(`{{ macFromSeed .Name }} {{ uuidFromSeed .Name }}`)
+		Expect(err).NotTo(HaveOccurred())
+		fields := strings.Fields(renderedNodeSpec)
+		Expect(fields).To(HaveLen(2))
+		Expect(fields[0]).To(MatchRegexp(`^[0-9a-f][26ae](:[0-9a-f]{2}){5}$`))
+		Expect(fields[1]).To(MatchRegexp(`^[0-9a-f]{8}-[0-9a-f]{4}-5[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`))
+		renderedAgain, err := render(`{{ macFromSeed .Name }} {{ uuidFromSeed .Name }}`)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(renderedAgain).To(Equal(renderedNodeSpec))
+		data.Name = "other"
+		renderedOther, err := render(`{{ macFromSeed .Name }} {{ uuidFromSeed .Name }}`)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(renderedOther).NotTo(Equal(renderedNodeSpec))
+	})
+	It("should fail on missing keys", func() {
+		_, err := render(`{{ .Image }}`)
+		Expect(err).To(HaveOccurred())
+	})
+})